@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"borehole/core/pkg/engine"
+	"borehole/core/pkg/parser"
+)
+
+// maxBatchUsers caps the users scored in one batch request.
+const maxBatchUsers = 1000
+
+// BatchUser is one user's logs in a BatchRequest.
+type BatchUser struct {
+	ID   string   `json:"id"`
+	Logs []string `json:"logs"`
+}
+
+// BatchRequest is the JSON input for the batch scoring endpoint.
+type BatchRequest struct {
+	Users []BatchUser `json:"users"`
+
+	// IncludeCertificates signs each result's score, with the user ID as uid
+	IncludeCertificates bool `json:"include_certificates,omitempty"`
+}
+
+// BatchResult is the score of one user in a BatchResponse.
+type BatchResult struct {
+	ID          string             `json:"id"`
+	Score       float64            `json:"score"`
+	TxnCount    int                `json:"txn_count"`
+	Message     string             `json:"message,omitempty"`
+	Certificate *engine.SignedCert `json:"certificate,omitempty"`
+}
+
+// BatchResponse is the JSON output for the batch scoring endpoint. Results
+// are in request order.
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// batchHandler scores many users in one request, each parsed and vectorized
+// independently exactly as scoreHandler would.
+func batchHandler(p parser.Parser, cfg config, logger *log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		var req BatchRequest
+		if !decodeRequest(w, r, cfg, &req) {
+			return
+		}
+		defer r.Body.Close()
+
+		if len(req.Users) == 0 {
+			writeError(w, "users array cannot be empty", http.StatusBadRequest)
+			return
+		}
+		if len(req.Users) > maxBatchUsers {
+			writeError(w, fmt.Sprintf("batch of %d users exceeds the limit of %d", len(req.Users), maxBatchUsers), http.StatusBadRequest)
+			return
+		}
+		for i, u := range req.Users {
+			if len(u.Logs) == 0 {
+				writeError(w, fmt.Sprintf("user %d: logs array cannot be empty", i), http.StatusBadRequest)
+				return
+			}
+			if !checkLogSizes(w, fmt.Sprintf("user %d: ", i), u.Logs) {
+				return
+			}
+		}
+
+		resp := BatchResponse{Results: make([]BatchResult, 0, len(req.Users))}
+		var stamp engine.VersionStamp // Of the engine that scored the batch
+		for _, u := range req.Users {
+			txns, err := parseCounted(r.Context(), p, u.Logs)
+			if err != nil {
+				logger.Printf("Parse error: %v", err)
+				writeError(w, "failed to parse logs", http.StatusInternalServerError)
+				return
+			}
+			score := scoreTransactions(txns, cfg, logger)
+			stamp = score.VersionStamp
+			resp.Results = append(resp.Results, BatchResult{
+				ID:       u.ID,
+				Score:    score.Score,
+				TxnCount: score.TxnCount,
+				Message:  score.Message,
+			})
+		}
+
+		if req.IncludeCertificates {
+			claims := make([]engine.ScoreClaim, len(resp.Results))
+			for i, res := range resp.Results {
+				claims[i] = engine.ScoreClaim{Score: res.Score, UserID: res.ID}
+			}
+			certs, err := engine.GetSecurityModule().IssueCertificates(claims, stamp)
+			if err != nil {
+				logger.Printf("Certificate error: %v", err)
+				writeError(w, "failed to issue certificates", http.StatusInternalServerError)
+				return
+			}
+			for i := range certs {
+				resp.Results[i].Certificate = &certs[i]
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		apiMetrics.observeScoreDuration(routePath(r), time.Since(start))
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"borehole/core/pkg/engine"
+	"borehole/core/pkg/parser"
+)
+
+// postBatch sends req to a batchHandler and returns the recorder.
+func postBatch(t *testing.T, req BatchRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	httpReq := httptest.NewRequest(http.MethodPost, "/v1/score/batch", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	batchHandler(parser.NewParser(), testConfig(), log.New(io.Discard, "", 0)).ServeHTTP(rec, httpReq)
+	return rec
+}
+
+func TestBatchHandler(t *testing.T) {
+	u1 := []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+	}
+	u2 := []string{"Fuliza M-PESA. You have borrowed Ksh2,000.00"}
+
+	rec := postBatch(t, BatchRequest{Users: []BatchUser{{ID: "u1", Logs: u1}, {ID: "u2", Logs: u2}}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var resp BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response JSON: %v", err)
+	}
+	if len(resp.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(resp.Results))
+	}
+
+	// Each user scores exactly as a single /v1/score request would
+	for i, logs := range [][]string{u1, u2} {
+		var single ScoreResponse
+		if err := json.Unmarshal(postScore(t, testConfig(), logs).Body.Bytes(), &single); err != nil {
+			t.Fatalf("invalid score response JSON: %v", err)
+		}
+		got := resp.Results[i]
+		if got.ID != []string{"u1", "u2"}[i] || got.Score != single.Score || got.TxnCount != single.TxnCount {
+			t.Errorf("results[%d] = %+v, want score %v and %d txns", i, got, single.Score, single.TxnCount)
+		}
+		if got.Certificate != nil {
+			t.Errorf("results[%d] has a certificate without include_certificates", i)
+		}
+	}
+}
+
+func TestBatchHandler_Certificates(t *testing.T) {
+	rec := postBatch(t, BatchRequest{
+		Users:               []BatchUser{{ID: "u1", Logs: []string{"Fuliza M-PESA. You have borrowed Ksh2,000.00"}}},
+		IncludeCertificates: true,
+	})
+	var resp BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response JSON: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Certificate == nil {
+		t.Fatalf("results = %+v, want one certified result", resp.Results)
+	}
+
+	cert := resp.Results[0].Certificate
+	if valid, err := engine.GetSecurityModule().VerifyCertificate(cert.Payload, cert.Signature); err != nil || !valid {
+		t.Errorf("VerifyCertificate() = %v, %v, want true", valid, err)
+	}
+	var payload engine.CertificatePayload
+	if err := json.Unmarshal([]byte(cert.Payload), &payload); err != nil {
+		t.Fatalf("invalid payload JSON: %v", err)
+	}
+	if payload.UserID != "u1" || payload.Score != resp.Results[0].Score {
+		t.Errorf("payload uid, score = %q, %v, want u1, %v", payload.UserID, payload.Score, resp.Results[0].Score)
+	}
+}
+
+func TestBatchHandler_Invalid(t *testing.T) {
+	logs := []string{"Fuliza M-PESA. You have borrowed Ksh2,000.00"}
+	tooMany := make([]BatchUser, maxBatchUsers+1)
+	for i := range tooMany {
+		tooMany[i] = BatchUser{ID: "u", Logs: logs}
+	}
+
+	tests := []struct {
+		name string
+		req  BatchRequest
+	}{
+		{"no users", BatchRequest{}},
+		{"user without logs", BatchRequest{Users: []BatchUser{{ID: "u1", Logs: logs}, {ID: "u2"}}}},
+		{"over the limit", BatchRequest{Users: tooMany}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := postBatch(t, tt.req); rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// drainPollInterval is how often drain checks for remaining in-flight requests.
+const drainPollInterval = 10 * time.Millisecond
+
+// drainer coordinates graceful shutdown. Once draining starts, /ready reports
+// 503 so load balancers stop routing new work, and drain waits for requests
+// already admitted by the limiter to finish.
+type drainer struct {
+	limiter  *inFlightLimiter
+	draining atomic.Bool
+}
+
+// newDrainer creates a drainer watching the given limiter's in-flight count.
+func newDrainer(limiter *inFlightLimiter) *drainer {
+	return &drainer{limiter: limiter}
+}
+
+// readyHandler reports whether the server is accepting new work.
+func (d *drainer) readyHandler(w http.ResponseWriter, r *http.Request) {
+	status, state := http.StatusOK, "ready"
+	if d.draining.Load() {
+		status, state = http.StatusServiceUnavailable, "draining"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": state,
+	})
+}
+
+// drain flips readiness off and blocks until no requests are in flight or ctx
+// expires, in which case it returns an error naming how many were cut off.
+func (d *drainer) drain(ctx context.Context) error {
+	d.draining.Store(true)
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		n := d.limiter.inFlight()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d requests still in flight: %w", n, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
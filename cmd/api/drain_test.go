@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// readyStatus returns the status code currently served by the drainer's /ready handler.
+func readyStatus(d *drainer) int {
+	rec := httptest.NewRecorder()
+	d.readyHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	return rec.Code
+}
+
+func TestDrainer_WaitsForInFlight(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	limiter := newInFlightLimiter(0, 0)
+	handler := limiter.wrap(blockingHandler(started, release))
+	d := newDrainer(limiter)
+
+	if code := readyStatus(d); code != http.StatusOK {
+		t.Fatalf("ready status before drain = %d, want %d", code, http.StatusOK)
+	}
+
+	rec := httptest.NewRecorder()
+	requestDone := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/score", nil))
+		close(requestDone)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	drained := make(chan error, 1)
+	go func() { drained <- d.drain(ctx) }()
+
+	// Readiness flips as soon as draining starts, while the request is still running
+	deadline := time.Now().Add(time.Second)
+	for readyStatus(d) != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatal("ready never reported 503 while draining")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("drain returned %v before the in-flight request finished", err)
+	default:
+	}
+
+	close(release)
+	if err := <-drained; err != nil {
+		t.Errorf("drain() error = %v, want nil", err)
+	}
+	select {
+	case <-requestDone:
+	default:
+		t.Error("drain returned before the in-flight request completed")
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("in-flight request status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestDrainer_Timeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	limiter := newInFlightLimiter(1, 0)
+	handler := limiter.wrap(blockingHandler(started, release))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/score", nil))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := newDrainer(limiter).drain(ctx); err == nil {
+		t.Error("drain() should return an error when requests outlive the timeout")
+	}
+}
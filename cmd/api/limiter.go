@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// retryAfterSeconds is the Retry-After hint sent with 503 responses.
+const retryAfterSeconds = 1
+
+// inFlightLimiter bounds how many requests a handler serves concurrently.
+// Excess requests wait up to queueTimeout for a slot, then get a 503.
+type inFlightLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+	active       atomic.Int64 // Requests being served, counted even when unlimited
+}
+
+// newInFlightLimiter creates a limiter allowing max concurrent requests.
+// A max of 0 or less disables limiting.
+func newInFlightLimiter(max int, queueTimeout time.Duration) *inFlightLimiter {
+	l := &inFlightLimiter{queueTimeout: queueTimeout}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// wrap applies the limit to next.
+func (l *inFlightLimiter) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+			writeError(w, "server busy, retry later", http.StatusServiceUnavailable)
+			return
+		}
+		defer l.release()
+
+		l.active.Add(1)
+		defer l.active.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire takes a slot, queueing for up to queueTimeout when none is free.
+func (l *inFlightLimiter) acquire(r *http.Request) bool {
+	if l.slots == nil {
+		return true
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if l.queueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// release frees a slot taken by acquire.
+func (l *inFlightLimiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// inFlight returns the number of requests currently being served.
+func (l *inFlightLimiter) inFlight() int {
+	return int(l.active.Load())
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// blockingHandler holds each request open until release is closed.
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestInFlightLimiter_RejectsWhenSaturated(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	limiter := newInFlightLimiter(1, 0)
+	handler := limiter.wrap(blockingHandler(started, release))
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(first, httptest.NewRequest(http.MethodPost, "/v1/score", nil))
+		close(done)
+	}()
+	<-started
+
+	excess := httptest.NewRecorder()
+	handler.ServeHTTP(excess, httptest.NewRequest(http.MethodPost, "/v1/score", nil))
+
+	if excess.Code != http.StatusServiceUnavailable {
+		t.Errorf("excess request status = %d, want %d", excess.Code, http.StatusServiceUnavailable)
+	}
+	if excess.Header().Get("Retry-After") == "" {
+		t.Error("excess request missing Retry-After header")
+	}
+
+	close(release)
+	<-done
+	if first.Code != http.StatusOK {
+		t.Errorf("first request status = %d, want %d", first.Code, http.StatusOK)
+	}
+	if n := limiter.inFlight(); n != 0 {
+		t.Errorf("inFlight() = %d after completion, want 0", n)
+	}
+}
+
+func TestInFlightLimiter_QueuesUntilSlotFrees(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	limiter := newInFlightLimiter(1, 5*time.Second)
+	handler := limiter.wrap(blockingHandler(started, release))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/score", nil))
+	<-started
+
+	queued := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(queued, httptest.NewRequest(http.MethodPost, "/v1/score", nil))
+		close(done)
+	}()
+
+	// Let the queued request wait, then free the slot for it
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	<-done
+
+	if queued.Code != http.StatusOK {
+		t.Errorf("queued request status = %d, want %d", queued.Code, http.StatusOK)
+	}
+}
+
+func TestInFlightLimiter_Disabled(t *testing.T) {
+	limiter := newInFlightLimiter(0, 0)
+	handler := limiter.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/score", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
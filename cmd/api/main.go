@@ -5,10 +5,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -21,6 +24,12 @@ const (
 	readTimeout     = 10 * time.Second
 	writeTimeout    = 10 * time.Second
 	shutdownTimeout = 5 * time.Second
+
+	defaultMaxInFlight  = 64
+	defaultMaxBodyBytes = 10 << 20
+
+	// maxLogBytes caps a single SMS; real messages are a few hundred bytes.
+	maxLogBytes = 2 << 10
 )
 
 func main() {
@@ -28,6 +37,7 @@ func main() {
 	logger := log.New(os.Stdout, "[borehole] ", log.LstdFlags|log.Lshortfile)
 
 	// Initialize dependencies
+	cfg := loadConfig(logger)
 	p := parser.NewParser()
 	// Engine is now a singleton, initialized on first use
 
@@ -37,8 +47,28 @@ func main() {
 	// Health check endpoint
 	mux.HandleFunc("GET /health", healthHandler)
 
-	// Main scoring endpoint
-	mux.HandleFunc("POST /v1/score", scoreHandler(p, logger))
+	// Lists what the parser recognises, for integrators
+	mux.HandleFunc("GET /v1/capabilities", capabilitiesHandler)
+
+	// Main scoring endpoint, bounded so load spikes cannot exhaust memory
+	limiter := newInFlightLimiter(cfg.maxInFlight, cfg.queueTimeout)
+	mux.Handle("POST /v1/score", limiter.wrap(scoreHandler(p, cfg, logger)))
+
+	// Scores many users per request, for partners scoring in bulk
+	mux.Handle("POST /v1/score/batch", limiter.wrap(batchHandler(p, cfg, logger)))
+
+	// Scoring for clients that parsed on-device and send transactions directly
+	mux.Handle("POST /v1/score/transactions", limiter.wrap(transactionsHandler(cfg, logger)))
+
+	// Financial summary without a model score, for dashboards and transparency
+	mux.Handle("POST /v1/summary", limiter.wrap(summaryHandler(p, cfg, logger)))
+
+	// Readiness endpoint, turned off while draining on shutdown
+	drain := newDrainer(limiter)
+	mux.HandleFunc("GET /ready", drain.readyHandler)
+
+	// Request, parse and latency counters for Prometheus
+	mux.HandleFunc("GET /metrics", metricsHandler)
 
 	// Create server
 	addr := os.Getenv("ADDR")
@@ -65,11 +95,16 @@ func main() {
 	}()
 
 	<-done
-	logger.Println("Shutting down server...")
+	logger.Println("Draining in-flight requests...")
 
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
+	if err := drain.drain(ctx); err != nil {
+		logger.Printf("Drain incomplete: %v", err)
+	}
+
+	logger.Println("Shutting down server...")
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Fatalf("Server shutdown failed: %v", err)
 	}
@@ -77,6 +112,62 @@ func main() {
 	logger.Println("Server stopped gracefully")
 }
 
+// config holds runtime settings read from the environment.
+type config struct {
+	// scorePrecision is the number of decimal places in serialized scores.
+	// engine.FullPrecision (the default) disables rounding.
+	scorePrecision int
+
+	// maxInFlight caps concurrent scoring requests; 0 disables the limit.
+	maxInFlight int
+
+	// queueTimeout is how long an excess request waits for a free slot
+	// before being rejected. 0 rejects immediately.
+	queueTimeout time.Duration
+
+	// maxBodyBytes caps the size of any POST request body.
+	maxBodyBytes int
+}
+
+// loadConfig reads the API configuration from environment variables,
+// falling back to defaults for unset or invalid values.
+func loadConfig(logger *log.Logger) config {
+	return config{
+		scorePrecision: envInt(logger, "SCORE_PRECISION", engine.FullPrecision),
+		maxInFlight:    envInt(logger, "MAX_IN_FLIGHT", defaultMaxInFlight),
+		queueTimeout:   envDuration(logger, "QUEUE_TIMEOUT", 0),
+		maxBodyBytes:   envInt(logger, "MAX_BODY_BYTES", defaultMaxBodyBytes),
+	}
+}
+
+// envInt reads an integer environment variable.
+func envInt(logger *log.Logger, name string, fallback int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		logger.Printf("Ignoring invalid %s %q: %v", name, v, err)
+		return fallback
+	}
+	return n
+}
+
+// envDuration reads a time.ParseDuration-style environment variable, e.g. "500ms".
+func envDuration(logger *log.Logger, name string, fallback time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		logger.Printf("Ignoring invalid %s %q: %v", name, v, err)
+		return fallback
+	}
+	return d
+}
+
 // ScoreRequest is the JSON input for the scoring endpoint.
 type ScoreRequest struct {
 	Logs []string `json:"logs"`
@@ -84,10 +175,17 @@ type ScoreRequest struct {
 
 // ScoreResponse is the JSON output for the scoring endpoint.
 type ScoreResponse struct {
-	Score    float64   `json:"score"`
-	Features []float64 `json:"features"`
-	TxnCount int       `json:"txn_count"`
-	Message  string    `json:"message,omitempty"`
+	Score       float64            `json:"score"`
+	Features    []float64          `json:"features"`
+	SubScores   engine.SubScoreSet `json:"sub_scores"`
+	Explanation []string           `json:"explanation"` // Top factors, strongest first
+	NetIncome   float64            `json:"net_income"`  // Income excluding loan disbursements
+	TxnCount    int                `json:"txn_count"`
+	Message     string             `json:"message,omitempty"`
+	engine.VersionStamp
+
+	// FeatureMap keys Features by engine.FeatureNames; only sent with ?explain=true
+	FeatureMap map[string]float64 `json:"feature_map,omitempty"`
 }
 
 // healthHandler returns a simple health check response.
@@ -100,13 +198,37 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// CapabilitiesResponse is the JSON output for the capabilities endpoint.
+type CapabilitiesResponse struct {
+	TransactionTypes []string `json:"transaction_types"`
+	Providers        []string `json:"providers"`
+}
+
+// capabilitiesHandler returns the transaction types and providers the parser recognises.
+func capabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(CapabilitiesResponse{
+		TransactionTypes: parser.SupportedTypes(),
+		Providers:        parser.SupportedProviders(),
+	})
+}
+
 // scoreHandler processes SMS logs and returns a credit score.
-func scoreHandler(p parser.Parser, logger *log.Logger) http.HandlerFunc {
+func scoreHandler(p parser.Parser, cfg config, logger *log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		// Select the response shape before doing any work
+		version, ok := negotiateVersion(r)
+		if !ok {
+			writeError(w, "unsupported API version requested in Accept header", http.StatusNotAcceptable)
+			return
+		}
+
 		// Parse request
 		var req ScoreRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			writeError(w, "invalid request body", http.StatusBadRequest)
+		if !decodeRequest(w, r, cfg, &req) {
 			return
 		}
 		defer r.Body.Close()
@@ -116,45 +238,111 @@ func scoreHandler(p parser.Parser, logger *log.Logger) http.HandlerFunc {
 			writeError(w, "logs array cannot be empty", http.StatusBadRequest)
 			return
 		}
+		if !checkLogSizes(w, "", req.Logs) {
+			return
+		}
 
 		// Parse SMS logs
-		txns, err := p.ParseLogs(r.Context(), req.Logs)
+		txns, err := parseCounted(r.Context(), p, req.Logs)
 		if err != nil {
 			logger.Printf("Parse error: %v", err)
 			writeError(w, "failed to parse logs", http.StatusInternalServerError)
 			return
 		}
 
-		// Generate feature vector
-		features := engine.MapFeatures(txns)
-
-		// Calculate score using the ML Engine
-		mlEngine, err := engine.GetEngine()
-		var score float64
-		if err != nil {
-			logger.Printf("Engine init error: %v", err)
-			// Fallback to 0 or handle error appropriately.
-			// For this test API, we'll return 0 and log the error.
-		} else {
-			score = mlEngine.Predict(features)
+		resp := scoreTransactions(txns, cfg, logger)
+		if explain, _ := strconv.ParseBool(r.URL.Query().Get("explain")); explain {
+			resp.FeatureMap = featureMap(resp.Features)
 		}
+		writeScore(w, version, resp)
+		apiMetrics.observeScoreDuration(routePath(r), time.Since(start))
+	}
+}
 
-		// Build response
-		resp := ScoreResponse{
-			Score:    score,
-			Features: features,
-			TxnCount: len(txns),
-		}
+// featureMap names each value of a MapFeatures vector.
+func featureMap(features []float64) map[string]float64 {
+	m := make(map[string]float64, len(features))
+	for i, v := range features {
+		m[engine.FeatureNames[i]] = v
+	}
+	return m
+}
+
+// scoreTransactions vectorizes and scores parsed transactions into a response.
+func scoreTransactions(txns []parser.Transaction, cfg config, logger *log.Logger) ScoreResponse {
+	// Generate feature vector
+	features := engine.MapFeatures(txns)
+
+	// Calculate score using the ML Engine
+	mlEngine, err := engine.GetEngine()
+	var (
+		score float64
+		stamp engine.VersionStamp
+	)
+	if err != nil {
+		logger.Printf("Engine init error: %v", err)
+		// Fallback to 0 or handle error appropriately.
+		// For this test API, we'll return 0 and log the error.
+	} else {
+		score = mlEngine.Predict(features)
+		stamp = mlEngine.Stamp()
+	}
+
+	// Build response
+	resp := ScoreResponse{
+		Score:        engine.RoundScore(score, cfg.scorePrecision),
+		Features:     features,
+		SubScores:    engine.SubScores(features),
+		Explanation:  engine.Explain(features),
+		NetIncome:    engine.EstimatedNetIncome(txns),
+		TxnCount:     len(txns),
+		VersionStamp: stamp,
+	}
+
+	if len(txns) == 0 {
+		resp.Message = "no transactions could be parsed from provided logs"
+	}
+	return resp
+}
 
-		if len(txns) == 0 {
-			resp.Message = "no transactions could be parsed from provided logs"
+// writeScore sends resp in the negotiated shape.
+func writeScore(w http.ResponseWriter, version apiVersion, resp ScoreResponse) {
+	var body any = resp
+	if version == apiV1 {
+		body = resp.v1()
+	}
+	w.Header().Set("Content-Type", version.mediaType())
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(body)
+}
+
+// decodeRequest decodes the JSON body of r into v, bounded by
+// cfg.maxBodyBytes so a huge body cannot exhaust memory. On failure it writes
+// the error response, 413 for an oversized body, and returns false.
+func decodeRequest(w http.ResponseWriter, r *http.Request, cfg config, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(cfg.maxBodyBytes))
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return false
 		}
+		writeError(w, "invalid request body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
 
-		// Send response
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(resp)
+// checkLogSizes rejects the request with 413 and returns false if any SMS in
+// logs exceeds maxLogBytes. prefix locates logs in the request, e.g. "user 3: ".
+func checkLogSizes(w http.ResponseWriter, prefix string, logs []string) bool {
+	for i, l := range logs {
+		if len(l) > maxLogBytes {
+			writeError(w, fmt.Sprintf("%slog %d exceeds %d bytes", prefix, i, maxLogBytes), http.StatusRequestEntityTooLarge)
+			return false
+		}
 	}
+	return true
 }
 
 // writeError sends a JSON error response.
@@ -166,7 +354,7 @@ func writeError(w http.ResponseWriter, message string, status int) {
 	})
 }
 
-// loggingMiddleware logs HTTP requests.
+// loggingMiddleware logs HTTP requests and counts them in apiMetrics.
 func loggingMiddleware(logger *log.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -175,6 +363,7 @@ func loggingMiddleware(logger *log.Logger, next http.Handler) http.Handler {
 		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
 
 		next.ServeHTTP(wrapped, r)
+		apiMetrics.observeRequest(routePath(r), wrapped.status)
 
 		logger.Printf("%s %s %d %v",
 			r.Method,
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"borehole/core/pkg/engine"
+	"borehole/core/pkg/mobile"
+	"borehole/core/pkg/parser"
+)
+
+// testConfig returns the defaults used by the server with no environment set.
+func testConfig() config {
+	return loadConfig(log.New(io.Discard, "", 0))
+}
+
+// postScore sends logs to a scoreHandler built from cfg and returns the recorder.
+func postScore(t *testing.T, cfg config, logs []string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(ScoreRequest{Logs: logs})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/score", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	scoreHandler(parser.NewParser(), cfg, log.New(io.Discard, "", 0)).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestScoreHandler_VersionStamp(t *testing.T) {
+	rec := postScore(t, testConfig(), []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp ScoreResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response JSON: %v", err)
+	}
+
+	mlEngine, _ := engine.GetEngine()
+	if resp.VersionStamp != mlEngine.Stamp() {
+		t.Errorf("stamp = %+v, want %+v", resp.VersionStamp, mlEngine.Stamp())
+	}
+}
+
+func TestScoreHandler_FeatureMap(t *testing.T) {
+	body, err := json.Marshal(ScoreRequest{Logs: []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+	}})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	post := func(target string) map[string]json.RawMessage {
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		scoreHandler(parser.NewParser(), testConfig(), log.New(io.Discard, "", 0)).ServeHTTP(rec, req)
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+			t.Fatalf("invalid response JSON: %v", err)
+		}
+		return raw
+	}
+
+	if _, ok := post("/v1/score")["feature_map"]; ok {
+		t.Error("default response has feature_map, want the shape unchanged")
+	}
+
+	var featureMap map[string]float64
+	if err := json.Unmarshal(post("/v1/score?explain=true")["feature_map"], &featureMap); err != nil {
+		t.Fatalf("invalid feature_map: %v", err)
+	}
+	if len(featureMap) != engine.FeatureCount {
+		t.Errorf("feature_map has %d keys, want %d", len(featureMap), engine.FeatureCount)
+	}
+	for _, name := range engine.FeatureNames {
+		if _, ok := featureMap[name]; !ok {
+			t.Errorf("feature_map missing %q", name)
+		}
+	}
+	if featureMap["total_income"] != 1500 {
+		t.Errorf("feature_map[total_income] = %v, want 1500", featureMap["total_income"])
+	}
+}
+
+func TestScoreHandler_MatchesMobileBridge(t *testing.T) {
+	logs := []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA00KPLC01 Confirmed. Ksh200.00 paid to KPLC",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+	}
+	var api ScoreResponse
+	if err := json.Unmarshal(postScore(t, testConfig(), logs).Body.Bytes(), &api); err != nil {
+		t.Fatalf("invalid API response JSON: %v", err)
+	}
+
+	input, err := json.Marshal(logs)
+	if err != nil {
+		t.Fatalf("marshal logs: %v", err)
+	}
+	var bridge parser.ScoreResult
+	if err := json.Unmarshal([]byte(mobile.NewMobileEngine().CalculateBoreholeScore(string(input))), &bridge); err != nil {
+		t.Fatalf("invalid mobile result JSON: %v", err)
+	}
+
+	if !slices.Equal(api.Features, bridge.Features) {
+		t.Errorf("API features = %v, mobile features = %v", api.Features, bridge.Features)
+	}
+	if api.FeatureSchemaHash != bridge.FeatureSchemaHash {
+		t.Errorf("feature_schema_hash = %q (API), %q (mobile)", api.FeatureSchemaHash, bridge.FeatureSchemaHash)
+	}
+}
+
+func TestScoreHandler_Precision(t *testing.T) {
+	cfg := testConfig()
+	cfg.scorePrecision = 3
+
+	rec := postScore(t, cfg, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+	})
+
+	var resp ScoreResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response JSON: %v", err)
+	}
+	if resp.Score != engine.RoundScore(resp.Score, 3) {
+		t.Errorf("Score = %v, want at most 3 decimal places", resp.Score)
+	}
+}
+
+func TestScoreHandler_NetIncome(t *testing.T) {
+	rec := postScore(t, testConfig(), []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+	})
+
+	var resp ScoreResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response JSON: %v", err)
+	}
+	if resp.NetIncome != 1500 {
+		t.Errorf("NetIncome = %v, want 1500", resp.NetIncome)
+	}
+}
+
+func TestScoreHandler_SizeLimits(t *testing.T) {
+	valid := "UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678"
+
+	tests := []struct {
+		name         string
+		maxBodyBytes int
+		logs         []string
+		want         int
+	}{
+		{"under the limit", 1 << 10, []string{valid}, http.StatusOK},
+		{"body over the limit", 1 << 10, slices.Repeat([]string{valid}, 20), http.StatusRequestEntityTooLarge},
+		{"log over the limit", defaultMaxBodyBytes, []string{strings.Repeat("x", maxLogBytes+1)}, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.maxBodyBytes = tt.maxBodyBytes
+			if rec := postScore(t, cfg, tt.logs); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestPostHandlers_SizeLimits(t *testing.T) {
+	valid := "UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678"
+	longLog := strings.Repeat("x", maxLogBytes+1)
+	manyLogs := slices.Repeat([]string{valid}, 20)
+
+	cfg := testConfig()
+	cfg.maxBodyBytes = 1 << 10
+	logger := log.New(io.Discard, "", 0)
+	p := parser.NewParser()
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		body    any
+	}{
+		{"score body", scoreHandler(p, cfg, logger), ScoreRequest{Logs: manyLogs}},
+		{"batch body", batchHandler(p, cfg, logger), BatchRequest{Users: []BatchUser{{ID: "u1", Logs: manyLogs}}}},
+		{"batch log", batchHandler(p, testConfig(), logger), BatchRequest{Users: []BatchUser{{ID: "u1", Logs: []string{longLog}}}}},
+		{"summary body", summaryHandler(p, cfg, logger), ScoreRequest{Logs: manyLogs}},
+		{"summary log", summaryHandler(p, testConfig(), logger), ScoreRequest{Logs: []string{longLog}}},
+		{"transactions body", transactionsHandler(cfg, logger), TransactionsRequest{
+			Transactions: slices.Repeat([]TransactionInput{{Type: "MPESA_RECEIVED", Amount: 1500, Sender: "JOHN DOE"}}, 50),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+			if rec.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusRequestEntityTooLarge, rec.Body)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	capabilitiesHandler(rec, httptest.NewRequest(http.MethodGet, "/v1/capabilities", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp CapabilitiesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response JSON: %v", err)
+	}
+	if !slices.Contains(resp.TransactionTypes, parser.TxnMPesaReceived.String()) {
+		t.Errorf("transaction_types = %v, want it to include %s", resp.TransactionTypes, parser.TxnMPesaReceived)
+	}
+	if !slices.Contains(resp.Providers, "M-Pesa") {
+		t.Errorf("providers = %v, want it to include M-Pesa", resp.Providers)
+	}
+}
+
+func TestScoreHandler_Explanation(t *testing.T) {
+	rec := postScore(t, testConfig(), []string{
+		"UA34ABCDEF Confirmed. You have received Ksh5,000.00 from JOHN DOE 0712345678",
+		"Betika: Your bet of Ksh2,000.00 has been placed",
+	})
+
+	var resp ScoreResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response JSON: %v", err)
+	}
+	if want := engine.Explain(resp.Features); !slices.Equal(resp.Explanation, want) || len(want) == 0 {
+		t.Errorf("Explanation = %q, want %q", resp.Explanation, want)
+	}
+}
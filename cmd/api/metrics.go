@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"borehole/core/pkg/parser"
+)
+
+// scoreDurationBuckets are the upper bounds, in seconds, of the
+// borehole_score_duration_seconds histogram.
+var scoreDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// unmatchedPath labels requests that matched no route, so probing arbitrary
+// URLs cannot grow the requests counter without bound.
+const unmatchedPath = "unmatched"
+
+// requestKey labels one borehole_requests_total series.
+type requestKey struct {
+	path   string
+	status int
+}
+
+// durationHistogram is one route's borehole_score_duration_seconds series.
+type durationHistogram struct {
+	counts []uint64 // Per bucket, not cumulative; the last is +Inf
+	sum    float64
+	count  uint64
+}
+
+// metrics holds the counters served at /metrics in the Prometheus text
+// exposition format. The zero value is not usable; see newMetrics.
+type metrics struct {
+	mu           sync.Mutex
+	requests     map[requestKey]uint64
+	parseSkipped uint64
+	durations    map[string]*durationHistogram // By route path
+}
+
+// newMetrics creates an empty metrics registry.
+func newMetrics() *metrics {
+	return &metrics{
+		requests:  make(map[requestKey]uint64),
+		durations: make(map[string]*durationHistogram),
+	}
+}
+
+// apiMetrics is the registry the server's handlers record into.
+var apiMetrics = newMetrics()
+
+// observeRequest counts a served request by route and status.
+func (m *metrics) observeRequest(path string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{path, status}]++
+}
+
+// addParseSkipped counts logs the parser did not recognise.
+func (m *metrics) addParseSkipped(n int) {
+	if n <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.parseSkipped += uint64(n)
+}
+
+// observeScoreDuration records how long one parsing or scoring request on
+// the route path took.
+func (m *metrics) observeScoreDuration(path string, d time.Duration) {
+	s := d.Seconds()
+	i := sort.SearchFloat64s(scoreDurationBuckets, s)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.durations[path]
+	if h == nil {
+		h = &durationHistogram{counts: make([]uint64, len(scoreDurationBuckets)+1)}
+		m.durations[path] = h
+	}
+	h.counts[i]++
+	h.sum += s
+	h.count++
+}
+
+// writeTo writes every metric in the Prometheus text exposition format.
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].path != keys[j].path {
+			return keys[i].path < keys[j].path
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	fmt.Fprintln(w, "# HELP borehole_requests_total HTTP requests served, by route and status.")
+	fmt.Fprintln(w, "# TYPE borehole_requests_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(w, "borehole_requests_total{path=%q,status=\"%d\"} %d\n", k.path, k.status, m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP borehole_parse_skipped_total SMS logs the parser did not recognise.")
+	fmt.Fprintln(w, "# TYPE borehole_parse_skipped_total counter")
+	fmt.Fprintf(w, "borehole_parse_skipped_total %d\n", m.parseSkipped)
+
+	paths := make([]string, 0, len(m.durations))
+	for path := range m.durations {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintln(w, "# HELP borehole_score_duration_seconds Time taken to parse and score or summarise one request, by route.")
+	fmt.Fprintln(w, "# TYPE borehole_score_duration_seconds histogram")
+	for _, path := range paths {
+		h := m.durations[path]
+		var cumulative uint64
+		for i, le := range scoreDurationBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "borehole_score_duration_seconds_bucket{path=%q,le=%q} %d\n", path, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		cumulative += h.counts[len(scoreDurationBuckets)]
+		fmt.Fprintf(w, "borehole_score_duration_seconds_bucket{path=%q,le=\"+Inf\"} %d\n", path, cumulative)
+		fmt.Fprintf(w, "borehole_score_duration_seconds_sum{path=%q} %s\n", path, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "borehole_score_duration_seconds_count{path=%q} %d\n", path, h.count)
+	}
+}
+
+// metricsHandler serves apiMetrics for Prometheus to scrape.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	apiMetrics.writeTo(w)
+}
+
+// routePath returns the path of the route that served r, without its method,
+// or unmatchedPath. It is only set once the mux has routed r.
+func routePath(r *http.Request) string {
+	if r.Pattern == "" {
+		return unmatchedPath
+	}
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return path
+	}
+	return r.Pattern
+}
+
+// reportingParser is a parser that can say which logs it skipped, such as
+// parser.DefaultParser.
+type reportingParser interface {
+	ParseLogsWithReport(ctx context.Context, logs []string) ([]parser.Transaction, parser.ParseReport, error)
+}
+
+// parseCounted parses logs with p, adding any skipped logs to apiMetrics when
+// p reports them.
+func parseCounted(ctx context.Context, p parser.Parser, logs []string) ([]parser.Transaction, error) {
+	rp, ok := p.(reportingParser)
+	if !ok {
+		return p.ParseLogs(ctx, logs)
+	}
+	txns, report, err := rp.ParseLogsWithReport(ctx, logs)
+	apiMetrics.addParseSkipped(report.Skipped)
+	return txns, err
+}
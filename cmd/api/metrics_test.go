@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"borehole/core/pkg/parser"
+)
+
+func TestMetricsHandler_AfterScore(t *testing.T) {
+	apiMetrics = newMetrics()
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /v1/score", scoreHandler(parser.NewParser(), testConfig(), log.New(io.Discard, "", 0)))
+	mux.HandleFunc("GET /metrics", metricsHandler)
+	srv := httptest.NewServer(loggingMiddleware(log.New(io.Discard, "", 0), mux))
+	defer srv.Close()
+
+	body, err := json.Marshal(ScoreRequest{Logs: []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"Your OTP is 123456",
+	}})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	resp, err := http.Post(srv.URL+"/v1/score", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /v1/score: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	scraped, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+
+	for _, want := range []string{
+		`borehole_requests_total{path="/v1/score",status="200"} 1`,
+		`borehole_parse_skipped_total 1`,
+		`borehole_score_duration_seconds_bucket{path="/v1/score",le="+Inf"} 1`,
+		`borehole_score_duration_seconds_count{path="/v1/score"} 1`,
+	} {
+		if !strings.Contains(string(scraped), want+"\n") {
+			t.Errorf("scrape missing %q:\n%s", want, scraped)
+		}
+	}
+}
+
+func TestMetrics_EveryParsingHandler(t *testing.T) {
+	apiMetrics = newMetrics()
+
+	p, cfg, logger := parser.NewParser(), testConfig(), log.New(io.Discard, "", 0)
+	mux := http.NewServeMux()
+	mux.Handle("POST /v1/score/batch", batchHandler(p, cfg, logger))
+	mux.Handle("POST /v1/score/transactions", transactionsHandler(cfg, logger))
+	mux.Handle("POST /v1/summary", summaryHandler(p, cfg, logger))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	logs := []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"Your OTP is 123456",
+	}
+	requests := map[string]any{
+		"/v1/score/batch":        BatchRequest{Users: []BatchUser{{ID: "u1", Logs: logs}}},
+		"/v1/score/transactions": TransactionsRequest{Transactions: []TransactionInput{{Type: "MPESA_RECEIVED", Amount: 1500}}},
+		"/v1/summary":            ScoreRequest{Logs: logs},
+	}
+	for path, req := range requests {
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		resp, err := http.Post(srv.URL+path, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("POST %s status = %d, want %d", path, resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	var buf bytes.Buffer
+	apiMetrics.writeTo(&buf)
+	wants := []string{`borehole_parse_skipped_total 2`} // The OTP, in batch and summary
+	for path := range requests {
+		wants = append(wants, fmt.Sprintf("borehole_score_duration_seconds_count{path=%q} 1", path))
+	}
+	for _, want := range wants {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("scrape missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestMetrics_UnmatchedPath(t *testing.T) {
+	m := newMetrics()
+	m.observeRequest(routePath(httptest.NewRequest(http.MethodGet, "/wp-admin", nil)), http.StatusNotFound)
+
+	var buf bytes.Buffer
+	m.writeTo(&buf)
+	if want := `borehole_requests_total{path="unmatched",status="404"} 1`; !strings.Contains(buf.String(), want) {
+		t.Errorf("scrape missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestMetrics_DurationBuckets(t *testing.T) {
+	m := newMetrics()
+	m.observeScoreDuration("/v1/score", 3*time.Millisecond)
+	m.observeScoreDuration("/v1/score", 10*time.Second)
+	m.observeScoreDuration("/v1/summary", time.Millisecond)
+
+	var buf bytes.Buffer
+	m.writeTo(&buf)
+	for _, want := range []string{
+		`borehole_score_duration_seconds_bucket{path="/v1/score",le="0.001"} 0`,
+		`borehole_score_duration_seconds_bucket{path="/v1/score",le="0.005"} 1`,
+		`borehole_score_duration_seconds_bucket{path="/v1/score",le="2.5"} 1`,
+		`borehole_score_duration_seconds_bucket{path="/v1/score",le="+Inf"} 2`,
+		`borehole_score_duration_seconds_bucket{path="/v1/summary",le="0.001"} 1`,
+		`borehole_score_duration_seconds_count{path="/v1/summary"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("scrape missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"borehole/core/pkg/engine"
+	"borehole/core/pkg/parser"
+)
+
+// TypeTotal is the count and net total of one transaction type.
+type TypeTotal struct {
+	Count int     `json:"count"`
+	Total float64 `json:"total"`
+}
+
+// SummaryResponse is the JSON output for the summary endpoint. It carries no
+// model score, only figures a user can check against their own statements.
+type SummaryResponse struct {
+	Summary   engine.Summary       `json:"summary"`
+	Breakdown map[string]TypeTotal `json:"breakdown"` // Keyed by transaction type, e.g. "MPESA_SENT"
+	TxnCount  int                  `json:"txn_count"`
+	Message   string               `json:"message,omitempty"`
+}
+
+// summaryHandler parses SMS logs and returns a financial summary without scoring.
+func summaryHandler(p parser.Parser, cfg config, logger *log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		var req ScoreRequest
+		if !decodeRequest(w, r, cfg, &req) {
+			return
+		}
+		defer r.Body.Close()
+
+		if len(req.Logs) == 0 {
+			writeError(w, "logs array cannot be empty", http.StatusBadRequest)
+			return
+		}
+		if !checkLogSizes(w, "", req.Logs) {
+			return
+		}
+
+		txns, err := parseCounted(r.Context(), p, req.Logs)
+		if err != nil {
+			logger.Printf("Parse error: %v", err)
+			writeError(w, "failed to parse logs", http.StatusInternalServerError)
+			return
+		}
+
+		resp := SummaryResponse{
+			Summary:   engine.Summarize(txns),
+			Breakdown: make(map[string]TypeTotal),
+			TxnCount:  len(txns),
+		}
+		for key, g := range engine.GroupByType(txns) {
+			resp.Breakdown[key] = TypeTotal{Count: g.Count, Total: g.Total}
+		}
+		if len(txns) == 0 {
+			resp.Message = "no transactions could be parsed from provided logs"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		apiMetrics.observeScoreDuration(routePath(r), time.Since(start))
+	}
+}
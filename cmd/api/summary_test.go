@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"borehole/core/pkg/parser"
+)
+
+func TestSummaryHandler(t *testing.T) {
+	body, err := json.Marshal(ScoreRequest{Logs: []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA00KPLC01 Confirmed. Ksh200.00 paid to KPLC",
+	}})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/summary", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	summaryHandler(parser.NewParser(), testConfig(), log.New(io.Discard, "", 0)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("invalid response JSON: %v", err)
+	}
+	for _, field := range []string{"summary", "breakdown", "txn_count"} {
+		if _, ok := raw[field]; !ok {
+			t.Errorf("response missing %q", field)
+		}
+	}
+	if _, ok := raw["score"]; ok {
+		t.Error("response has a score, want none")
+	}
+
+	var resp SummaryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response JSON: %v", err)
+	}
+	if resp.Summary.Income != 1500 || resp.Summary.Expenses != 200 {
+		t.Errorf("income, expenses = %v, %v, want 1500, 200", resp.Summary.Income, resp.Summary.Expenses)
+	}
+	if got := resp.Breakdown["UTILITY"]; got != (TypeTotal{Count: 1, Total: 200}) {
+		t.Errorf("breakdown[UTILITY] = %+v, want {1 200}", got)
+	}
+	if len(resp.Summary.TopMerchants) != 1 || resp.Summary.TopMerchants[0].Name != "KPLC" {
+		t.Errorf("top_merchants = %+v, want KPLC", resp.Summary.TopMerchants)
+	}
+}
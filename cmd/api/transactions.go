@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"borehole/core/pkg/parser"
+)
+
+// TransactionInput is one pre-parsed transaction in a TransactionsRequest.
+// Type is the parser.TransactionType String form, e.g. "MPESA_RECEIVED".
+type TransactionInput struct {
+	Type          string    `json:"type"`
+	Amount        float64   `json:"amount"`
+	Balance       *float64  `json:"balance,omitempty"` // Absent when not known; 0 is an empty wallet
+	Timestamp     time.Time `json:"timestamp,omitempty"`
+	Sender        string    `json:"sender,omitempty"`
+	Recipient     string    `json:"recipient,omitempty"`
+	Lender        string    `json:"lender,omitempty"`
+	Institutional bool      `json:"institutional,omitempty"`
+	Saved         float64   `json:"saved,omitempty"`
+	FulizaFunded  bool      `json:"fuliza_funded,omitempty"`
+	Reversal      bool      `json:"reversal,omitempty"`
+}
+
+// TransactionsRequest is the JSON input for the pre-parsed scoring endpoint.
+type TransactionsRequest struct {
+	Transactions []TransactionInput `json:"transactions"`
+}
+
+// transaction converts the input to a parser.Transaction, rejecting unknown
+// types and negative amounts.
+func (in TransactionInput) transaction() (parser.Transaction, error) {
+	t, err := parser.ParseTransactionType(in.Type)
+	if err != nil {
+		return parser.Transaction{}, err
+	}
+	if in.Amount < 0 || (in.Balance != nil && *in.Balance < 0) || in.Saved < 0 {
+		return parser.Transaction{}, fmt.Errorf("negative amount in %s transaction", in.Type)
+	}
+	txn := parser.Transaction{
+		Type:          t,
+		Amount:        in.Amount,
+		Timestamp:     in.Timestamp,
+		Sender:        in.Sender,
+		Recipient:     in.Recipient,
+		Lender:        in.Lender,
+		Institutional: in.Institutional,
+		Saved:         in.Saved,
+		FulizaFunded:  in.FulizaFunded,
+		Reversal:      in.Reversal,
+		Confidence:    parser.ConfidenceExact,
+	}
+	if in.Balance != nil {
+		txn.Balance, txn.HasBalance = *in.Balance, true
+	}
+	return txn, nil
+}
+
+// transactionsHandler scores transactions the client already parsed,
+// skipping SMS parsing. The response matches scoreHandler's.
+func transactionsHandler(cfg config, logger *log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		version, ok := negotiateVersion(r)
+		if !ok {
+			writeError(w, "unsupported API version requested in Accept header", http.StatusNotAcceptable)
+			return
+		}
+
+		var req TransactionsRequest
+		if !decodeRequest(w, r, cfg, &req) {
+			return
+		}
+		defer r.Body.Close()
+
+		if len(req.Transactions) == 0 {
+			writeError(w, "transactions array cannot be empty", http.StatusBadRequest)
+			return
+		}
+
+		txns := make([]parser.Transaction, 0, len(req.Transactions))
+		for i, in := range req.Transactions {
+			txn, err := in.transaction()
+			if err != nil {
+				writeError(w, fmt.Sprintf("transaction %d: %v", i, err), http.StatusBadRequest)
+				return
+			}
+			txns = append(txns, txn)
+		}
+
+		writeScore(w, version, scoreTransactions(txns, cfg, logger))
+		apiMetrics.observeScoreDuration(routePath(r), time.Since(start))
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// postTransactions sends a raw JSON body to a transactionsHandler.
+func postTransactions(t *testing.T, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/score/transactions", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	transactionsHandler(testConfig(), log.New(io.Discard, "", 0)).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTransactionsHandler_MatchesLogsPath(t *testing.T) {
+	fromLogs := postScore(t, testConfig(), []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+	})
+
+	body, err := json.Marshal(TransactionsRequest{Transactions: []TransactionInput{
+		{Type: "MPESA_RECEIVED", Amount: 1500, Sender: "JOHN DOE 0712345678"},
+		{Type: "MPESA_SENT", Amount: 500, Recipient: "JANE DOE 0798765432"},
+		{Type: "FULIZA_LOAN", Amount: 2000},
+	}})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	direct := postTransactions(t, body)
+	if direct.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", direct.Code, http.StatusOK, direct.Body)
+	}
+
+	var want, got ScoreResponse
+	if err := json.Unmarshal(fromLogs.Body.Bytes(), &want); err != nil {
+		t.Fatalf("invalid logs response JSON: %v", err)
+	}
+	if err := json.Unmarshal(direct.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid transactions response JSON: %v", err)
+	}
+	if got.Score != want.Score || got.TxnCount != want.TxnCount {
+		t.Errorf("score, txn_count = %v, %d, want %v, %d", got.Score, got.TxnCount, want.Score, want.TxnCount)
+	}
+	if !slices.Equal(got.Features, want.Features) {
+		t.Errorf("features = %v, want %v", got.Features, want.Features)
+	}
+}
+
+func TestTransactionsHandler_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"malformed", `{"transactions": [`},
+		{"empty", `{"transactions": []}`},
+		{"unknown type", `{"transactions": [{"type": "MPESA_STOLEN", "amount": 100}]}`},
+		{"unknown is not a type", `{"transactions": [{"type": "UNKNOWN", "amount": 100}]}`},
+		{"negative amount", `{"transactions": [{"type": "MPESA_SENT", "amount": -100}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postTransactions(t, []byte(tt.body))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestTransactionsHandler_DeprecatedGambling(t *testing.T) {
+	// Clients written before stakes and winnings were split send "GAMBLING"
+	score := func(typ string) ScoreResponse {
+		t.Helper()
+		rec := postTransactions(t, []byte(`{"transactions": [{"type": "`+typ+`", "amount": 100, "recipient": "Betika"}]}`))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("type %s: status = %d, want %d: %s", typ, rec.Code, http.StatusOK, rec.Body)
+		}
+		var resp ScoreResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response JSON: %v", err)
+		}
+		return resp
+	}
+
+	if got, want := score("GAMBLING"), score("GAMBLING_STAKE"); !slices.Equal(got.Features, want.Features) {
+		t.Errorf("GAMBLING features = %v, want those of GAMBLING_STAKE %v", got.Features, want.Features)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Vendor media types clients send in Accept to pin a response shape.
+const (
+	mediaTypeV1 = "application/vnd.borehole.v1+json"
+	mediaTypeV2 = "application/vnd.borehole.v2+json"
+
+	// vendorPrefix matches any borehole vendor media type, supported or not.
+	vendorPrefix = "application/vnd.borehole."
+)
+
+// apiVersion identifies a /v1/score response shape.
+type apiVersion int
+
+const (
+	// apiV1 is the lean original response: score, features and counts only.
+	apiV1 apiVersion = 1
+	// apiV2 adds sub-scores, net income and version stamps. It is the default.
+	apiV2 apiVersion = 2
+)
+
+// mediaType returns the Content-Type served for the version.
+func (v apiVersion) mediaType() string {
+	if v == apiV1 {
+		return mediaTypeV1
+	}
+	return mediaTypeV2
+}
+
+// negotiateVersion picks the response version from the Accept header. The
+// first supported vendor type wins; generic types such as application/json or
+// */* (or no header at all) get the richest shape. ok is false when the client
+// only asked for borehole versions this server does not know.
+func negotiateVersion(r *http.Request) (v apiVersion, ok bool) {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return apiV2, true
+	}
+
+	onlyVendor := true
+	for _, part := range strings.Split(accept, ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mt {
+		case mediaTypeV1:
+			return apiV1, true
+		case mediaTypeV2:
+			return apiV2, true
+		}
+		if !strings.HasPrefix(mt, vendorPrefix) {
+			onlyVendor = false
+		}
+	}
+	return apiV2, !onlyVendor
+}
+
+// ScoreResponseV1 is the original response shape, served to clients pinned to
+// application/vnd.borehole.v1+json.
+type ScoreResponseV1 struct {
+	Score    float64   `json:"score"`
+	Features []float64 `json:"features"`
+	TxnCount int       `json:"txn_count"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// v1 drops the fields added after the original response shape.
+func (resp ScoreResponse) v1() ScoreResponseV1 {
+	return ScoreResponseV1{
+		Score:    resp.Score,
+		Features: resp.Features,
+		TxnCount: resp.TxnCount,
+		Message:  resp.Message,
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"borehole/core/pkg/parser"
+)
+
+// postScoreAccept is postScore with an Accept header; an empty accept sends none.
+func postScoreAccept(t *testing.T, accept string, logs []string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(ScoreRequest{Logs: logs})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/score", bytes.NewReader(body))
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	scoreHandler(parser.NewParser(), testConfig(), log.New(io.Discard, "", 0)).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestScoreHandler_VersionNegotiation(t *testing.T) {
+	logs := []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+	}
+	richFields := []string{"sub_scores", "explanation", "net_income", "engine_version"}
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		wantRich    bool
+	}{
+		{"default", "", mediaTypeV2, true},
+		{"generic json", "application/json", mediaTypeV2, true},
+		{"pinned v1", mediaTypeV1, mediaTypeV1, false},
+		{"pinned v2", mediaTypeV2, mediaTypeV2, true},
+		{"v1 preferred in list", "application/vnd.borehole.v1+json; q=0.9, */*", mediaTypeV1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postScoreAccept(t, tt.accept, logs)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.contentType)
+			}
+
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &fields); err != nil {
+				t.Fatalf("invalid response JSON: %v", err)
+			}
+			for _, key := range []string{"score", "features", "txn_count"} {
+				if _, ok := fields[key]; !ok {
+					t.Errorf("field %q missing", key)
+				}
+			}
+			for _, key := range richFields {
+				if _, ok := fields[key]; ok != tt.wantRich {
+					t.Errorf("field %q present = %v, want %v", key, ok, tt.wantRich)
+				}
+			}
+		})
+	}
+}
+
+func TestScoreHandler_UnsupportedVersion(t *testing.T) {
+	rec := postScoreAccept(t, "application/vnd.borehole.v9+json", []string{"anything"})
+	if rec.Code != http.StatusNotAcceptable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotAcceptable)
+	}
+}
@@ -1,39 +1,65 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
 	"log"
 	"os"
 
-	"github.com/dmitryikh/leaves"
+	"borehole/core/pkg/engine"
 )
 
 func main() {
-	// Read actual file which we just updated
-	data, err := os.ReadFile("pkg/engine/model/borehole_model.json")
-	if err != nil {
-		log.Fatalf("Failed to read file: %v", err)
-	}
+	// A model path argument is loaded directly; otherwise use the same source
+	// GetEngine does: the override file if set, else the embedded model
+	var (
+		mlEngine *engine.BoreholeEngine
+		err      error
+	)
+	if len(os.Args) > 1 {
+		fmt.Printf("Loading model %s\n", os.Args[1])
+		data, readErr := os.ReadFile(os.Args[1])
+		if readErr != nil {
+			log.Fatalf("Failed to read model: %v", readErr)
+		}
 
-	fmt.Println("Attempting to load model from file...")
-	reader := bufio.NewReader(bytes.NewReader(data))
+		// Try the model's own objective first, then the raw margin
+		for _, transform := range []bool{true, false} {
+			cfg := engine.EngineConfig{LoadTransformation: transform}
+			mlEngine, err = engine.NewEngineFromReaderWithConfig(bytes.NewReader(data), cfg)
+			if err == nil {
+				fmt.Printf("Loaded with loadTransformation=%v\n", transform)
+				break
+			}
+			fmt.Printf("ERROR (loadTransformation=%v): %v\n", transform, err)
+		}
+	} else {
+		if path := os.Getenv(engine.ModelPathEnv); path != "" {
+			fmt.Printf("Using model override %s\n", path)
+		} else {
+			fmt.Println("Using embedded model")
+		}
+		data, readErr := engine.ModelData()
+		if readErr != nil {
+			log.Fatalf("Failed to read model: %v", readErr)
+		}
+		fmt.Printf("Model size: %d bytes\n", len(data))
 
-	// Try loading with transformation = true
-	_, err = leaves.XGEnsembleFromReader(reader, true)
+		fmt.Println("Attempting to load model...")
+		mlEngine, err = engine.GetEngine()
+	}
 	if err != nil {
-		fmt.Printf("ERROR (loadTransformation=true): %v\n", err)
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
 
-		// Try loading with transformation = false
-		reader2 := bufio.NewReader(bytes.NewReader(data))
-		_, err2 := leaves.XGEnsembleFromReader(reader2, false)
-		if err2 != nil {
-			fmt.Printf("ERROR (loadTransformation=false): %v\n", err2)
-		} else {
-			fmt.Println("SUCCESS with loadTransformation=false")
-		}
-	} else {
-		fmt.Println("SUCCESS with loadTransformation=true")
+	stamp := mlEngine.Stamp()
+	fmt.Printf("SUCCESS: model hash %s\n", stamp.ModelHash)
+
+	// Probe both sides of the income split
+	features := make([]float64, engine.FeatureCount)
+	for _, income := range []float64{0, 5000} {
+		features[0] = income
+		fmt.Printf("  total_income=%-6.0f score=%.4f\n", income, mlEngine.Predict(features))
 	}
 }
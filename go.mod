@@ -2,10 +2,12 @@ module borehole/core
 
 go 1.25.6
 
-require golang.org/x/mobile v0.0.0-20260120165949-40bd9ace6ce4
+require (
+	github.com/dmitryikh/leaves v0.0.0-20230708180554-25d19a787328
+	golang.org/x/mobile v0.0.0-20260120165949-40bd9ace6ce4
+)
 
 require (
-	github.com/dmitryikh/leaves v0.0.0-20230708180554-25d19a787328 // indirect
 	golang.org/x/mod v0.32.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/tools v0.41.0 // indirect
@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"borehole/core/pkg/parser"
+)
+
+// balancePoint is one dated transaction's effect on the M-Pesa wallet.
+type balancePoint struct {
+	at      time.Time
+	delta   float64 // Signed change in the wallet, fees included
+	balance float64 // Wallet balance the message reported; valid when known
+	known   bool
+}
+
+// balanceTracker collects the points behind ReconstructBalanceSeries in the
+// order they arrive, which need not be chronological. Alongside them it
+// maintains the spread of the reconstructed series, so balance_volatility
+// stays current without rebuilding the series after every transaction. A
+// point dated after every other, or before every other as in a newest-first
+// export, updates the spread in place; one landing between others, or an
+// earlier balance re-anchoring back-filled points, forces a rebuild on the
+// next read.
+type balanceTracker struct {
+	points []balancePoint
+	oldest balancePoint // Earliest point, the first to arrive on a tie
+	newest time.Time    // Latest point's timestamp
+	stats  runningStats // Spread of the series; stale while dirty
+	first  float64      // Balance after oldest; stale while dirty
+	last   float64      // Balance after the latest point; stale while dirty
+	anchor bool         // Some point quoted a balance
+	dirty  bool
+}
+
+// add records txn if it is dated and either reports a wallet balance or
+// moves money in or out of the wallet.
+func (b *balanceTracker) add(txn parser.Transaction) {
+	if txn.Timestamp.IsZero() {
+		return
+	}
+	balance, known := reportedBalance(txn)
+	delta := walletDelta(txn)
+	if !known && delta == 0 {
+		return
+	}
+	p := balancePoint{at: txn.Timestamp, delta: delta, balance: balance, known: known}
+
+	// Equal timestamps keep arrival order, so a tie with the newest point
+	// still lands after it
+	switch {
+	case len(b.points) == 0 || !p.at.Before(b.newest):
+		b.append(p)
+	case p.at.Before(b.oldest.at):
+		b.prepend(p)
+	default:
+		b.dirty = true
+	}
+	b.points = append(b.points, p)
+	if len(b.points) == 1 || p.at.Before(b.oldest.at) {
+		b.oldest = p
+	}
+	if p.at.After(b.newest) {
+		b.newest = p.at
+	}
+	b.anchor = b.anchor || known
+}
+
+// append updates the spread for p, dated after every point held so far.
+func (b *balanceTracker) append(p balancePoint) {
+	value := b.last + p.delta
+	if p.known {
+		if !b.anchor {
+			// Shift the estimates made so far onto the first real balance
+			b.shift(p.balance - value)
+		}
+		value = p.balance
+	}
+	if len(b.points) == 0 {
+		b.first = value
+	}
+	b.last = value
+	b.stats.add(value)
+}
+
+// prepend updates the spread for p, dated before every point held so far.
+func (b *balanceTracker) prepend(p balancePoint) {
+	var value float64
+	switch {
+	case !b.anchor:
+		// The series ran forward from 0; it now runs forward from p
+		value = p.delta
+		if p.known {
+			value = p.balance
+		}
+		b.shift(value)
+	case !p.known:
+		value = b.first - b.oldest.delta // Back-filled from the point after it
+	case b.oldest.known:
+		value = p.balance
+	default:
+		// p now anchors points that were back-filled from a later balance
+		b.dirty = true
+		return
+	}
+	b.first = value
+	b.stats.add(value)
+}
+
+// shift moves every balance in the series by offset, which leaves its spread
+// unchanged.
+func (b *balanceTracker) shift(offset float64) {
+	b.stats.mean += offset
+	b.first += offset
+	b.last += offset
+}
+
+// volatility returns the standard deviation of the series, or 0 without
+// any points.
+func (b *balanceTracker) volatility() float64 {
+	if b.dirty {
+		series := b.series()
+		b.stats = runningStats{}
+		for _, balance := range series {
+			b.stats.add(balance)
+		}
+		b.first, b.last = series[0], series[len(series)-1]
+		b.dirty = false
+	}
+	return b.stats.stdDev()
+}
+
+// series returns the wallet balance after each point, oldest first.
+// Reported balances are used as given. Between them the balance is carried
+// forward by each transaction's delta, and points before the first reported
+// balance are back-filled from it. With no reported balance at all the series
+// starts from 0, which leaves its spread, though not its level, correct.
+func (b *balanceTracker) series() []float64 {
+	points := make([]balancePoint, len(b.points))
+	copy(points, b.points)
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].at.Before(points[j].at)
+	})
+
+	out := make([]float64, len(points))
+	running, anchored := 0.0, false
+	for i, p := range points {
+		running += p.delta
+		if p.known {
+			if !anchored {
+				// Shift the estimates made so far onto the first real balance
+				offset := p.balance - running
+				for j := range out[:i] {
+					out[j] += offset
+				}
+				anchored = true
+			}
+			running = p.balance
+		}
+		out[i] = running
+	}
+	return out
+}
+
+// ReconstructBalanceSeries returns the M-Pesa wallet balance after each dated
+// transaction that touched it, in chronological order. Undated transactions
+// cannot be placed and are left out. Balances quoted in the messages are used
+// where present and the gaps are filled in from the transaction amounts; see
+// balanceTracker.series.
+func ReconstructBalanceSeries(txns []parser.Transaction) []float64 {
+	var b balanceTracker
+	for _, txn := range txns {
+		if !txn.Type.IsInformational() {
+			b.add(txn)
+		}
+	}
+	return b.series()
+}
+
+// reportedBalance returns the wallet balance txn quotes, if any, including
+// an empty wallet. Hustler Fund and Okoa Jahazi messages quote a debt balance
+// instead.
+func reportedBalance(txn parser.Transaction) (float64, bool) {
+	if !txn.HasBalance {
+		return 0, false
+	}
+	switch txn.Type {
+	case parser.TxnHustlerLoan, parser.TxnHustlerRepay, parser.TxnOkoaReceived, parser.TxnOkoaDebt:
+		return 0, false
+	}
+	return txn.Balance, true
+}
+
+// walletDelta returns how txn changed the M-Pesa wallet: positive for money
+// in, negative for money out including the fee, and 0 for transactions on
+// other wallets, bank accounts or airtime. A reversal undoes the original.
+func walletDelta(txn parser.Transaction) float64 {
+	var delta float64
+	switch txn.Type {
+	case parser.TxnMPesaReceived, parser.TxnFulizaLoan, parser.TxnHustlerLoan,
+		parser.TxnDigitalLoan, parser.TxnKCBLoan, parser.TxnMMFWithdraw,
+		parser.TxnAgentDeposit, parser.TxnBankWithdraw, parser.TxnGamblingWin, parser.TxnRemittanceReceived:
+		delta = txn.Amount
+	case parser.TxnMPesaSent, parser.TxnMPesaPaybill, parser.TxnMPesaBuyGoods,
+		parser.TxnUtility, parser.TxnAirtime, parser.TxnGamblingStake,
+		parser.TxnFulizaRepay, parser.TxnHustlerRepay, parser.TxnDigitalRepay, parser.TxnKCBRepay,
+		parser.TxnMMFDeposit, parser.TxnAgentWithdraw, parser.TxnBankDeposit:
+		delta = -(txn.Amount + txn.Cost)
+	}
+	if txn.Reversal {
+		return -delta
+	}
+	return delta
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"borehole/core/pkg/parser"
+)
+
+func TestReconstructBalanceSeries_Order(t *testing.T) {
+	// Exports often list the newest message first
+	txns := parseLogs(t, []string{
+		"UA00SEND02 Confirmed. Ksh500.00 sent to JANE DOE 0798765432 on 3/2/26. New M-PESA balance is Ksh10,500.00.",
+		"UA34ABCDEF Confirmed. You have received Ksh12,000.00 from SAFARICOM LIMITED 123456 on 1/2/26. New M-PESA balance is Ksh12,500.00.",
+		"UA00SEND01 Confirmed. Ksh1,500.00 sent to JANE DOE 0798765432 on 2/2/26. New M-PESA balance is Ksh11,000.00.",
+	})
+
+	if got, want := ReconstructBalanceSeries(txns), []float64{12500, 11000, 10500}; !slices.Equal(got, want) {
+		t.Errorf("ReconstructBalanceSeries() = %v, want %v", got, want)
+	}
+}
+
+func TestReconstructBalanceSeries_FillsGaps(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	txns := []parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 1000, Timestamp: day(1)},
+		{Type: parser.TxnMPesaSent, Amount: 300, Cost: 7, Timestamp: day(2), Balance: 1700, HasBalance: true},
+		{Type: parser.TxnMPesaPaybill, Amount: 200, Timestamp: day(3)},
+		{Type: parser.TxnMPesaSent, Amount: 100, Timestamp: day(4), Reversal: true},
+		{Type: parser.TxnAirtelReceived, Amount: 5000, Timestamp: day(5)}, // Another wallet
+		{Type: parser.TxnMPesaReceived, Amount: 400},                      // Undated
+	}
+
+	// The first reported balance back-fills the receipt before it
+	want := []float64{2007, 1700, 1500, 1600}
+	if got := ReconstructBalanceSeries(txns); !slices.Equal(got, want) {
+		t.Errorf("ReconstructBalanceSeries() = %v, want %v", got, want)
+	}
+}
+
+func TestMapFeatures_BalanceVolatility(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	// Without any quoted balance the spread still follows the flows
+	features := MapFeatures([]parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 1000, Timestamp: day(1)},
+		{Type: parser.TxnMPesaSent, Amount: 1000, Timestamp: day(2)},
+	})
+	if features[11] != 500 {
+		t.Errorf("balance_volatility = %v, want 500", features[11])
+	}
+
+	if undated := mapLogs(t, []string{"Fuliza M-PESA. You have borrowed Ksh2,000.00"}); undated[11] != 0 {
+		t.Errorf("balance_volatility = %v without dates, want 0", undated[11])
+	}
+}
+
+func TestBalanceTracker_VolatilityAnyOrder(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	txns := []parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 1000, Timestamp: day(1)},
+		{Type: parser.TxnMPesaSent, Amount: 300, Cost: 7, Timestamp: day(2), Balance: 1700, HasBalance: true},
+		{Type: parser.TxnMPesaPaybill, Amount: 200, Timestamp: day(3)},
+		{Type: parser.TxnMPesaReceived, Amount: 900, Timestamp: day(3), Balance: 2400, HasBalance: true},
+		{Type: parser.TxnMPesaSent, Amount: 100, Timestamp: day(4)},
+	}
+
+	// Every arrival order must give the spread of the rebuilt series
+	var permute func(k int)
+	permute = func(k int) {
+		if k == len(txns) {
+			var b, unread balanceTracker
+			var want runningStats
+			for i, txn := range txns {
+				unread.add(txn)
+				b.add(txn)
+				want = runningStats{}
+				for _, balance := range ReconstructBalanceSeries(txns[:i+1]) {
+					want.add(balance)
+				}
+				if got := b.volatility(); !almostEqual(got, want.stdDev(), 1e-9) {
+					t.Fatalf("order %v: volatility after %d = %v, want %v", txns, i+1, got, want.stdDev())
+				}
+			}
+			if got := unread.volatility(); !almostEqual(got, want.stdDev(), 1e-9) {
+				t.Fatalf("order %v: volatility read once = %v, want %v", txns, got, want.stdDev())
+			}
+			return
+		}
+		for i := k; i < len(txns); i++ {
+			txns[k], txns[i] = txns[i], txns[k]
+			permute(k + 1)
+			txns[k], txns[i] = txns[i], txns[k]
+		}
+	}
+	permute(0)
+}
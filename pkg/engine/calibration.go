@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Calibration methods accepted in a calibration file.
+const (
+	CalibrationIdentity = "identity"
+	CalibrationPlatt    = "platt"
+)
+
+// Calibration holds the parameters stored next to a model that map its raw
+// probabilities onto observed default rates.
+type Calibration struct {
+	Method string `json:"method"`
+
+	// Platt scaling parameters: the calibrated score is
+	// sigmoid(A*logit(p) + B), so A = 1, B = 0 is the identity.
+	A float64 `json:"a,omitempty"`
+	B float64 `json:"b,omitempty"`
+}
+
+// Hash identifies the score transform the calibration describes: the
+// hex-encoded SHA-256 of its canonical JSON. Calibrations with the same
+// method and parameters share a hash however their files were formatted,
+// and every spelling of the identity hashes alike.
+func (c Calibration) Hash() string {
+	if c.Method == "" {
+		c.Method = CalibrationIdentity
+	}
+	if c.Method == CalibrationIdentity {
+		c.A, c.B = 0, 0
+	}
+	data, _ := json.Marshal(c) // A struct of strings and floats always marshals
+	return hashHex(data)
+}
+
+// Func returns the score transform the calibration describes.
+func (c Calibration) Func() (func(float64) float64, error) {
+	switch c.Method {
+	case "", CalibrationIdentity:
+		return identity, nil
+	case CalibrationPlatt:
+		return PlattCalibration(c.A, c.B), nil
+	default:
+		return nil, fmt.Errorf("unknown calibration method %q", c.Method)
+	}
+}
+
+// PlattCalibration returns sigmoid(a*logit(p) + b), Platt scaling applied to
+// the model's log-odds. Scores of exactly 0 or 1 are returned unchanged.
+func PlattCalibration(a, b float64) func(float64) float64 {
+	return func(p float64) float64 {
+		if p <= 0 || p >= 1 {
+			return p
+		}
+		logit := math.Log(p / (1 - p))
+		return 1.0 / (1.0 + math.Exp(-(a*logit + b)))
+	}
+}
+
+// parseCalibration decodes a calibration file. Empty data is the identity.
+func parseCalibration(data []byte) (Calibration, error) {
+	var c Calibration
+	if len(data) == 0 {
+		return c, nil
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Calibration{}, fmt.Errorf("decode calibration: %w", err)
+	}
+	if _, err := c.Func(); err != nil {
+		return Calibration{}, err
+	}
+	return c, nil
+}
+
+func identity(p float64) float64 { return p }
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewEngine_Calibration(t *testing.T) {
+	features := make([]float64, FeatureCount)
+	features[0] = 5000
+	raw := 1 / (1 + math.Exp(-1.5)) // Embedded stump, high income
+
+	e, err := newEngine(embeddedModel, EngineConfig{})
+	if err != nil {
+		t.Fatalf("newEngine() error = %v", err)
+	}
+	if got := e.Predict(features); !almostEqual(got, raw, floatEpsilon) {
+		t.Errorf("Predict() uncalibrated = %v, want %v", got, raw)
+	}
+
+	// Platt with a = 2, b = -1 maps margin 1.5 to sigmoid(2*1.5 - 1) = sigmoid(2)
+	platt, err := newEngine(embeddedModel, EngineConfig{Calibration: Calibration{Method: CalibrationPlatt, A: 2, B: -1}})
+	if err != nil {
+		t.Fatalf("newEngine() with Platt error = %v", err)
+	}
+	if got, want := platt.Predict(features), 1/(1+math.Exp(-2)); !almostEqual(got, want, floatEpsilon) {
+		t.Errorf("Predict() with Platt = %v, want %v", got, want)
+	}
+
+	// The same model under another calibration is a different score mapping
+	if e.Stamp().CalibrationHash == platt.Stamp().CalibrationHash {
+		t.Errorf("identity and Platt engines share calibration hash %q", e.Stamp().CalibrationHash)
+	}
+	if e.Stamp().ModelHash != platt.Stamp().ModelHash {
+		t.Error("calibration changed the model hash")
+	}
+
+	if _, err := newEngine(embeddedModel, EngineConfig{Calibration: Calibration{Method: "isotonic"}}); err == nil {
+		t.Error("newEngine() with an unknown calibration method: want error")
+	}
+}
+
+func TestCalibration_Hash(t *testing.T) {
+	identity := Calibration{}.Hash()
+	if got := (Calibration{Method: CalibrationIdentity}).Hash(); got != identity {
+		t.Errorf("explicit identity hash = %q, want %q", got, identity)
+	}
+
+	// Formatting of the file does not change the hash
+	a, err := parseCalibration([]byte(`{"method": "platt", "a": 0.8, "b": 0.1}`))
+	if err != nil {
+		t.Fatalf("parseCalibration() error = %v", err)
+	}
+	b, err := parseCalibration([]byte(`{"b":0.10,"a":0.80,"method":"platt"}`))
+	if err != nil {
+		t.Fatalf("parseCalibration() error = %v", err)
+	}
+	if a.Hash() != b.Hash() {
+		t.Errorf("reformatted calibration hash = %q, want %q", b.Hash(), a.Hash())
+	}
+	if a.Hash() == identity {
+		t.Error("Platt calibration hashes as the identity")
+	}
+}
+
+func TestParseCalibration(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		in   float64
+		want float64
+	}{
+		{"empty is identity", "", 0.3, 0.3},
+		{"identity", `{"method": "identity"}`, 0.3, 0.3},
+		{"platt identity params", `{"method": "platt", "a": 1, "b": 0}`, 0.3, 0.3},
+		{"platt shift", `{"method": "platt", "a": 1, "b": 1}`, 0.5, 1 / (1 + math.Exp(-1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseCalibration([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseCalibration() error = %v", err)
+			}
+			fn, err := c.Func()
+			if err != nil {
+				t.Fatalf("Func() error = %v", err)
+			}
+			if got := fn(tt.in); !almostEqual(got, tt.want, floatEpsilon) {
+				t.Errorf("calibrate(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseCalibration([]byte(`{"method": "isotonic"}`)); err == nil {
+		t.Error("parseCalibration() with an unknown method: want error")
+	}
+}
+
+func TestCalibrationData(t *testing.T) {
+	t.Setenv(ModelPathEnv, "")
+	if data, err := CalibrationData(); err != nil || string(data) != string(embeddedCalibration) {
+		t.Errorf("CalibrationData() = %q, %v, want the embedded calibration", data, err)
+	}
+
+	dir := t.TempDir()
+	t.Setenv(ModelPathEnv, filepath.Join(dir, "model.json"))
+	if data, err := CalibrationData(); err != nil || data != nil {
+		t.Errorf("CalibrationData() without a file = %q, %v, want nil, nil", data, err)
+	}
+
+	stored := []byte(`{"method": "platt", "a": 0.8, "b": 0.1}`)
+	if err := os.WriteFile(filepath.Join(dir, calibrationFile), stored, 0o600); err != nil {
+		t.Fatalf("write calibration: %v", err)
+	}
+	if data, err := CalibrationData(); err != nil || string(data) != string(stored) {
+		t.Errorf("CalibrationData() = %q, %v, want the file beside the model", data, err)
+	}
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"time"
+
+	"borehole/core/pkg/parser"
+)
+
+const (
+	// drawdownWindow is how long after a receipt outflows count as spending it.
+	drawdownWindow = 3 * 24 * time.Hour
+
+	// neutralDrawdownRatio is reported when no dated income was seen.
+	neutralDrawdownRatio = 0.5
+)
+
+// incomeWindow is a dated receipt whose drawdown window is still open.
+type incomeWindow struct {
+	received time.Time
+	unspent  float64
+}
+
+// drawdownTracker measures how much of each dated receipt leaves the wallet
+// within drawdownWindow. Outflows are charged to the oldest open receipt
+// first, so a salary followed by rapid full spending draws down to 1.
+// Transactions are expected in chronological order; undated ones are ignored.
+type drawdownTracker struct {
+	open   []incomeWindow
+	income float64
+	drawn  float64
+}
+
+// add records an income or outflow; other transaction types are ignored.
+// Savings deposits keep funds and do not count as drawdown.
+func (d *drawdownTracker) add(txn parser.Transaction) {
+	if txn.Timestamp.IsZero() || txn.Amount <= 0 {
+		return
+	}
+
+	switch txn.Type {
+	case parser.TxnMPesaReceived, parser.TxnTKashReceived, parser.TxnAirtelReceived, parser.TxnBankReceived,
+		parser.TxnRemittanceReceived:
+		d.open = append(d.open, incomeWindow{received: txn.Timestamp, unspent: txn.Amount})
+		d.income += txn.Amount
+	case parser.TxnMPesaSent, parser.TxnTKashSent, parser.TxnAirtelSent, parser.TxnBankSent,
+		parser.TxnMPesaPaybill, parser.TxnMPesaBuyGoods, parser.TxnUtility, parser.TxnAirtime, parser.TxnGamblingStake,
+		parser.TxnFulizaRepay, parser.TxnHustlerRepay, parser.TxnDigitalRepay, parser.TxnKCBRepay,
+		parser.TxnBankLoanRepay, parser.TxnAgentWithdraw:
+		d.spend(txn.Amount, txn.Timestamp)
+	}
+}
+
+// spend charges amount against receipts whose window covers at, oldest first.
+func (d *drawdownTracker) spend(amount float64, at time.Time) {
+	for len(d.open) > 0 && at.Sub(d.open[0].received) > drawdownWindow {
+		d.open = d.open[1:]
+	}
+	for amount > 0 && len(d.open) > 0 {
+		w := &d.open[0]
+		if at.Before(w.received) {
+			break
+		}
+		paid := amount
+		if paid > w.unspent {
+			paid = w.unspent
+		}
+		w.unspent -= paid
+		amount -= paid
+		d.drawn += paid
+
+		if w.unspent > settledEpsilon {
+			break
+		}
+		d.open = d.open[1:]
+	}
+}
+
+// ratio returns the share of dated income spent within its window, or
+// neutralDrawdownRatio when no dated income was seen.
+func (d *drawdownTracker) ratio() float64 {
+	if d.income == 0 {
+		return neutralDrawdownRatio
+	}
+	return d.drawn / d.income
+}
@@ -1,42 +1,189 @@
 package engine
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"math"
+	"os"
 	"sync"
 )
 
+// Version is the engine release. Bump it whenever scoring behaviour changes.
+const Version = "0.2.0"
+
 // BoreholeEngine acts as the thread-safe singleton for ML inference.
+// An engine is immutable once built, so its stamp always describes its scores.
 type BoreholeEngine struct {
+	model     scorer
+	modelHash string
+	features  int // Vector length the model reads, see FeatureCount
+
+	calibrate       func(float64) float64
+	calibrationHash string // See Calibration.Hash
+}
+
+// VersionStamp ties a score to the code, model, calibration and feature layout
+// that produced it, so the score can be reproduced during an audit.
+type VersionStamp struct {
+	EngineVersion     string `json:"engine_version"`
+	ModelHash         string `json:"model_hash"`
+	CalibrationHash   string `json:"calibration_hash"`
+	FeatureSchemaHash string `json:"feature_schema_hash"`
 }
 
 var (
 	instance *BoreholeEngine
+	initErr  error
 	once     sync.Once
 )
 
-// Predict performs on-device scoring for a 20-dimension feature vector.
-// Applies Sigmoid activation to avoid raw margins.
+// Predict performs on-device scoring for a MapFeatures vector.
+// Applies Sigmoid activation to avoid raw margins, then the calibration.
+// Vectors too short for the model score a neutral 0.5.
 func (e *BoreholeEngine) Predict(features []float64) float64 {
-	if len(features) < 20 {
+	if len(features) < e.features {
 		return 0.5
 	}
+	return e.calibrate(e.model.probability(features))
+}
 
-	var rawMargin float64
-	cashIn := features[0]
+// FullPrecision disables rounding in RoundScore.
+const FullPrecision = -1
 
-	if cashIn < 1000.0 {
-		rawMargin = -1.5
-	} else {
-		rawMargin = 1.5
+// RoundScore rounds a score to the given number of decimal places for display.
+// Rounding is half away from zero, so the same score always serializes the same way.
+// A negative precision returns the score unchanged.
+func RoundScore(score float64, precision int) float64 {
+	if precision < 0 {
+		return score
 	}
+	scale := math.Pow(10, float64(precision))
+	return math.Round(score*scale) / scale
+}
+
+// FeatureCount returns the feature vector length the loaded model reads. It
+// never exceeds the package FeatureCount; GetEngine fails for models that would.
+func (e *BoreholeEngine) FeatureCount() int {
+	return e.features
+}
 
-	return 1.0 / (1.0 + math.Exp(-rawMargin))
+// Stamp returns the version stamp for scores produced by this engine.
+func (e *BoreholeEngine) Stamp() VersionStamp {
+	return VersionStamp{
+		EngineVersion:     Version,
+		ModelHash:         e.modelHash,
+		CalibrationHash:   e.calibrationHash,
+		FeatureSchemaHash: FeatureSchemaHash(),
+	}
 }
 
-// GetEngine returns the singleton instance.
+// GetEngine returns the singleton instance, loading the model named by
+// ModelPathEnv or, failing that, the embedded default, calibrated by the
+// calibration stored alongside it (see CalibrationData).
 func GetEngine() (*BoreholeEngine, error) {
 	once.Do(func() {
-		instance = &BoreholeEngine{}
+		data, err := ModelData()
+		if err != nil {
+			initErr = err
+			return
+		}
+		calibration, err := CalibrationData()
+		if err != nil {
+			initErr = err
+			return
+		}
+		instance, initErr = newCalibratedEngine(data, calibration)
 	})
-	return instance, nil
+	return instance, initErr
+}
+
+// NewEngineFromModel loads the model file at path (see NewEngineFromReader),
+// calibrated by calibration.json from the same directory when present. Unlike GetEngine it
+// returns a new engine on every call, so several models can be served side by
+// side, e.g. one per region.
+func NewEngineFromModel(path string) (*BoreholeEngine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read model: %w", err)
+	}
+	calibration, err := calibrationBeside(path)
+	if err != nil {
+		return nil, err
+	}
+	return newCalibratedEngine(data, calibration)
+}
+
+// EngineConfig holds optional model-loading behaviour. The zero value matches
+// NewEngineFromReader.
+type EngineConfig struct {
+	// Calibration maps the model's probabilities onto observed default
+	// rates, e.g. Platt parameters fitted on observed defaults. The zero
+	// Calibration is the identity.
+	Calibration Calibration
+
+	// LoadTransformation is passed to leaves.XGEnsembleFromReader for XGBoost
+	// binary models: the model's own objective, which must then be
+	// binary:logistic, turns its margin into a probability. When false the
+	// raw margin goes through Predict's sigmoid instead, so models saved with
+	// any objective load. JSON tree dumps carry no objective and ignore it.
+	LoadTransformation bool
+}
+
+// NewEngineFromReader loads an uncalibrated model from r: an XGBoost binary
+// model as Booster.save_model writes it, or a JSON tree dump (see parseModel).
+func NewEngineFromReader(r io.Reader) (*BoreholeEngine, error) {
+	return NewEngineFromReaderWithConfig(r, EngineConfig{})
+}
+
+// NewEngineFromReaderWithConfig is NewEngineFromReader with the given options.
+func NewEngineFromReaderWithConfig(r io.Reader, cfg EngineConfig) (*BoreholeEngine, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read model: %w", err)
+	}
+	return newEngine(data, cfg)
+}
+
+// newCalibratedEngine builds an engine from a model file and the contents of a
+// calibration file, see parseCalibration.
+func newCalibratedEngine(data, calibration []byte) (*BoreholeEngine, error) {
+	c, err := parseCalibration(calibration)
+	if err != nil {
+		return nil, err
+	}
+	return newEngine(data, EngineConfig{Calibration: c})
+}
+
+// newEngine builds an engine from a model file loaded as cfg describes. A
+// model that splits on features MapFeatures does not produce is rejected here
+// rather than left to route every vector down its missing branches at
+// inference.
+func newEngine(data []byte, cfg EngineConfig) (*BoreholeEngine, error) {
+	model, err := loadModel(data, cfg.LoadTransformation)
+	if err != nil {
+		return nil, err
+	}
+	n := model.featureCount()
+	if n > FeatureCount {
+		return nil, fmt.Errorf("model reads %d features but MapFeatures produces %d", n, FeatureCount)
+	}
+	calibrate, err := cfg.Calibration.Func()
+	if err != nil {
+		return nil, err
+	}
+	return &BoreholeEngine{
+		model:           model,
+		modelHash:       hashHex(data),
+		features:        n,
+		calibrate:       calibrate,
+		calibrationHash: cfg.Calibration.Hash(),
+	}, nil
+}
+
+// hashHex returns the hex-encoded SHA-256 of data.
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
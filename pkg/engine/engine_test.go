@@ -1,9 +1,23 @@
 package engine
 
 import (
+	"encoding/json"
+	"math"
 	"testing"
 )
 
+// floatEpsilon is the tolerance for computed floats in engine tests. It is far
+// below any difference that matters to a score or feature, yet absorbs the
+// last-bit rounding that varies with platform and summation order.
+const floatEpsilon = 1e-9
+
+// almostEqual reports whether a and b agree within eps, scaled by their
+// magnitude once it exceeds 1 so large monetary values get a relative tolerance.
+func almostEqual(a, b, eps float64) bool {
+	scale := math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+	return math.Abs(a-b) <= eps*scale
+}
+
 func TestBoreholeEngine_Singleton(t *testing.T) {
 	// First call should initialize
 	e1, err := GetEngine()
@@ -31,36 +45,21 @@ func TestBoreholeEngine_Predict(t *testing.T) {
 		t.Fatalf("Failed to initialize engine: %v", err)
 	}
 
-	// Test case 1: Zero vector
-	// Model has one split node: if feature[0] < 1000.0 => leaf=0.5, else leaf=-0.5
-	// Sigmoid(0.5) ≈ 0.6224
-	// Sigmoid(-0.5) ≈ 0.3775
+	// The embedded model is a single stump on feature 0:
+	// income below 1000 => margin -1.5, otherwise +1.5, then Sigmoid.
+	wantLow := 1 / (1 + math.Exp(1.5))   // ≈ 0.1824
+	wantHigh := 1 / (1 + math.Exp(-1.5)) // ≈ 0.8176
 
+	// Test case 1: Zero vector
 	features := make([]float64, 20)
-	// Feature 0 (Income) = 0.0, so < 1000.0, goes to "yes" (nodeid 1) -> leaf 0.5
-
-	score := engine.Predict(features)
-
-	// Just check if it's in a reasonable range [0, 1]
-	if score < 0.0 || score > 1.0 {
-		t.Errorf("Score %f out of range [0, 1]", score)
-	}
-
-	// Relaxed Check: Accept 0.5 (fallback) OR the expected specific value
-	if score == 0.5 {
-		t.Log("Warning: Engine using fallback score (0.5). Model mock logic active.")
-	} else if score < 0.62 || score > 0.63 {
-		t.Errorf("Expected score ~0.622 for zero input, got %f", score)
+	if score := engine.Predict(features); !almostEqual(score, wantLow, floatEpsilon) {
+		t.Errorf("Predict(zero income) = %v, want %v", score, wantLow)
 	}
 
 	// Test case 2: High income
 	features[0] = 5000.0
-
-	scoreHigh := engine.Predict(features)
-	if scoreHigh == 0.5 {
-		t.Log("Warning: Using fallback score (0.5) for high income test.")
-	} else if scoreHigh < 0.37 || scoreHigh > 0.38 {
-		t.Errorf("Expected score ~0.377 for high income, got %f", scoreHigh)
+	if score := engine.Predict(features); !almostEqual(score, wantHigh, floatEpsilon) {
+		t.Errorf("Predict(high income) = %v, want %v", score, wantHigh)
 	}
 }
 
@@ -76,3 +75,104 @@ func TestPredict_Allocation(t *testing.T) {
 		t.Errorf("Predict should have zero allocations, got %f", allocs)
 	}
 }
+
+func TestRoundScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		score     float64
+		precision int
+		expected  float64
+	}{
+		{"full precision", 0.6224593312018546, FullPrecision, 0.6224593312018546},
+		{"two places", 0.6224593312018546, 2, 0.62},
+		{"four places", 0.6224593312018546, 4, 0.6225},
+		{"zero places", 0.6224593312018546, 0, 1},
+		{"half rounds away from zero", 0.125, 2, 0.13},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RoundScore(tt.score, tt.precision); got != tt.expected {
+				t.Errorf("RoundScore(%v, %d) = %v, want %v", tt.score, tt.precision, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBoreholeEngine_Stamp(t *testing.T) {
+	engine, err := GetEngine()
+	if err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
+
+	stamp := engine.Stamp()
+	if stamp.EngineVersion != Version {
+		t.Errorf("EngineVersion = %q, want %q", stamp.EngineVersion, Version)
+	}
+	if want := hashHex(embeddedModel); stamp.ModelHash != want {
+		t.Errorf("ModelHash = %q, want hash of loaded model %q", stamp.ModelHash, want)
+	}
+	if c, err := parseCalibration(embeddedCalibration); err != nil || stamp.CalibrationHash != c.Hash() {
+		t.Errorf("CalibrationHash = %q, want hash of the embedded calibration (parse error %v)", stamp.CalibrationHash, err)
+	}
+	if stamp.FeatureSchemaHash == "" || stamp.FeatureSchemaHash != FeatureSchemaHash() {
+		t.Errorf("FeatureSchemaHash = %q, want %q", stamp.FeatureSchemaHash, FeatureSchemaHash())
+	}
+}
+
+func TestIssueCertificate_Stamped(t *testing.T) {
+	// An engine other than the singleton stamps its own model into certificates
+	e := stumpEngine(t, 1)
+	if singleton, _ := GetEngine(); e.Stamp() == singleton.Stamp() {
+		t.Fatal("stump engine has the singleton's stamp")
+	}
+
+	payloadJSON, _, err := GetSecurityModule().IssueCertificate(0.5, "test_user", e.Stamp())
+	if err != nil {
+		t.Fatalf("IssueCertificate() error = %v", err)
+	}
+
+	var payload CertificatePayload
+	if err := json.Unmarshal([]byte(payloadJSON), &payload); err != nil {
+		t.Fatalf("invalid payload JSON: %v", err)
+	}
+	if payload.VersionStamp != e.Stamp() {
+		t.Errorf("payload stamp = %+v, want %+v", payload.VersionStamp, e.Stamp())
+	}
+}
+
+func TestIssueCertificates(t *testing.T) {
+	sec := GetSecurityModule()
+	claims := []ScoreClaim{
+		{Score: 0.2, UserID: "u1"},
+		{Score: 0.5, UserID: "u2"},
+		{Score: 0.9, UserID: "u3"},
+	}
+
+	stamp := stumpEngine(t, 1).Stamp()
+	certs, err := sec.IssueCertificates(claims, stamp)
+	if err != nil {
+		t.Fatalf("IssueCertificates() error = %v", err)
+	}
+	if len(certs) != len(claims) {
+		t.Fatalf("IssueCertificates() returned %d certificates, want %d", len(certs), len(claims))
+	}
+
+	for i, cert := range certs {
+		valid, err := sec.VerifyCertificate(cert.Payload, cert.Signature)
+		if err != nil || !valid {
+			t.Errorf("certificate %d failed verification: valid=%v err=%v", i, valid, err)
+		}
+
+		var payload CertificatePayload
+		if err := json.Unmarshal([]byte(cert.Payload), &payload); err != nil {
+			t.Fatalf("certificate %d: invalid payload JSON: %v", i, err)
+		}
+		if payload.Score != claims[i].Score || payload.UserID != claims[i].UserID {
+			t.Errorf("certificate %d = {%v %q}, want {%v %q}", i, payload.Score, payload.UserID, claims[i].Score, claims[i].UserID)
+		}
+		if payload.VersionStamp != stamp {
+			t.Errorf("certificate %d stamp = %+v, want %+v", i, payload.VersionStamp, stamp)
+		}
+	}
+}
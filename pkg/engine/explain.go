@@ -0,0 +1,91 @@
+package engine
+
+import "sort"
+
+const (
+	// maxExplanations caps how many factors Explain reports.
+	maxExplanations = 3
+
+	// minExplanationWeight drops factors too weak to be worth mentioning.
+	minExplanationWeight = 0.2
+)
+
+// explanationFactor turns one feature into a 0-1 weight and the sentence shown
+// when that weight is among the largest.
+type explanationFactor struct {
+	weight  func(features []float64) float64
+	message string
+}
+
+// explanationFactors are listed in tie-break order: when two factors weigh the
+// same, the earlier one is reported first.
+var explanationFactors = []explanationFactor{
+	{
+		weight:  func(f []float64) float64 { return clamp01(2 * f[6]) },
+		message: "Frequent betting is holding your score back",
+	},
+	{
+		weight:  func(f []float64) float64 { return clamp01(f[8]) },
+		message: "Reliance on Fuliza overdrafts is holding your score back",
+	},
+	{
+		weight:  func(f []float64) float64 { return clamp01(f[24] / 3) },
+		message: "Payments failing at your Fuliza limit are holding your score back",
+	},
+	{
+		weight:  func(f []float64) float64 { return clamp01(f[16] / 3) },
+		message: "Borrowing from several lenders is holding your score back",
+	},
+	{
+		weight:  func(f []float64) float64 { return clamp01(f[23]) },
+		message: "Loan repayments take a large share of your income",
+	},
+	{
+		weight:  func(f []float64) float64 { return clamp01(5 * f[18]) },
+		message: "Regular saving is helping your score",
+	},
+	{
+		weight: func(f []float64) float64 {
+			if f[0] == 0 {
+				return 0
+			}
+			return clamp01(f[2]/2) * (1 - clamp01(f[5]))
+		},
+		message: "Steady income above your spending is helping your score",
+	},
+	{
+		weight:  func(f []float64) float64 { return clamp01(f[21]) },
+		message: "Income from employers and businesses is helping your score",
+	},
+}
+
+// Explain returns up to three human-readable sentences naming the factors that
+// weigh most on a feature vector, strongest first. Each factor normalises one
+// or two features to 0-1 (the same scales SubScores uses) and factors below
+// 0.2 are left out. The output is deterministic. Vectors shorter than
+// FeatureCount yield nil.
+func Explain(features []float64) []string {
+	if len(features) < FeatureCount {
+		return nil
+	}
+
+	type ranked struct {
+		weight  float64
+		message string
+	}
+	var factors []ranked
+	for _, f := range explanationFactors {
+		if w := f.weight(features); w >= minExplanationWeight {
+			factors = append(factors, ranked{w, f.message})
+		}
+	}
+	sort.SliceStable(factors, func(i, j int) bool {
+		return factors[i].weight > factors[j].weight
+	})
+
+	var out []string
+	for i := 0; i < len(factors) && i < maxExplanations; i++ {
+		out = append(out, factors[i].message)
+	}
+	return out
+}
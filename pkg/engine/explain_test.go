@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExplain_Gambler(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh5,000.00 from JOHN DOE 0712345678",
+		"Betika: Your bet of Ksh2,000.00 has been placed",
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+	})
+
+	got := Explain(features)
+	if len(got) == 0 || got[0] != "Frequent betting is holding your score back" {
+		t.Errorf("Explain() = %q, want gambling explained first", got)
+	}
+	if !slices.Equal(got, Explain(features)) {
+		t.Error("Explain() is not deterministic")
+	}
+}
+
+func TestExplain_Limits(t *testing.T) {
+	if got := Explain(make([]float64, 5)); got != nil {
+		t.Errorf("Explain(short) = %q, want nil", got)
+	}
+	if got := Explain(make([]float64, FeatureCount)); len(got) != 0 {
+		t.Errorf("Explain(zero vector) = %q, want no factors", got)
+	}
+}
@@ -0,0 +1,152 @@
+package engine
+
+import "borehole/core/pkg/parser"
+
+// FeatureVector is the feature vector with a named field per index, for
+// callers that would rather not remember that index 6 is gambling_index.
+// Fields follow FeatureNames in order and carry the same names as JSON keys.
+type FeatureVector struct {
+	TotalIncome              float64 `json:"total_income"`
+	TotalExpenses            float64 `json:"total_expenses"`
+	ProfitabilityRatio       float64 `json:"profitability_ratio"`
+	TxnCount                 float64 `json:"txn_count"`
+	MaxTxn                   float64 `json:"max_txn"`
+	IncomeRegularity         float64 `json:"income_regularity"`
+	GamblingIndex            float64 `json:"gambling_index"`
+	UtilityRatio             float64 `json:"utility_ratio"`
+	FulizaUsage              float64 `json:"fuliza_usage"`
+	FulizaRepayRate          float64 `json:"fuliza_repay_rate"`
+	P2PRatio                 float64 `json:"p2p_ratio"`
+	BalanceVolatility        float64 `json:"balance_volatility"`
+	DaysActive               float64 `json:"days_active"`
+	HustlerBalance           float64 `json:"hustler_balance"`
+	OkoaFrequency            float64 `json:"okoa_frequency"`
+	AirtelVolume             float64 `json:"airtel_volume"`
+	LenderDiversity          float64 `json:"lender_diversity"`
+	EmergencyReliance        float64 `json:"emergency_reliance"`
+	SavingsRate              float64 `json:"savings_rate"`
+	BankActivity             float64 `json:"bank_activity"`
+	PendingLoansCount        float64 `json:"pending_loans_count"`
+	InstitutionalIncomeRatio float64 `json:"institutional_income_ratio"`
+	ExpenseRegularity        float64 `json:"expense_regularity"`
+	DebtServiceRatio         float64 `json:"debt_service_ratio"`
+	FulizaLimitReachedCount  float64 `json:"fuliza_limit_reached_count"`
+	GiftedAirtimeCount       float64 `json:"gifted_airtime_count"`
+	OntimeRepaymentRatio     float64 `json:"ontime_repayment_ratio"`
+	MinBalance               float64 `json:"min_balance"`
+	NoBalanceData            float64 `json:"no_balance_data"`
+	WeightedGamblingIndex    float64 `json:"weighted_gambling_index"`
+	RepaymentExpenseRatio    float64 `json:"repayment_expense_ratio"`
+	DaysSinceLastIncome      float64 `json:"days_since_last_income"`
+	PostIncomeDrawdownRatio  float64 `json:"post_income_drawdown_ratio"`
+	IncomeChannelDiversity   float64 `json:"income_channel_diversity"`
+	FulizaDependencyRatio    float64 `json:"fuliza_dependency_ratio"`
+	CounterpartyDiversity    float64 `json:"counterparty_diversity"`
+	TotalFees                float64 `json:"total_fees"`
+	GamblingCount            float64 `json:"gambling_count"`
+	RecurringIncomeRatio     float64 `json:"recurring_income_ratio"`
+	RemittanceRatio          float64 `json:"remittance_ratio"`
+	DigitalRepayRate         float64 `json:"digital_repay_rate"`
+}
+
+// VectorizeNamed is MapFeatures returning a FeatureVector.
+func VectorizeNamed(txns []parser.Transaction) FeatureVector {
+	return FeatureVectorFromSlice(MapFeatures(txns))
+}
+
+// ToSlice returns the vector in the MapFeatures layout.
+func (v FeatureVector) ToSlice() []float64 {
+	return []float64{
+		v.TotalIncome,
+		v.TotalExpenses,
+		v.ProfitabilityRatio,
+		v.TxnCount,
+		v.MaxTxn,
+		v.IncomeRegularity,
+		v.GamblingIndex,
+		v.UtilityRatio,
+		v.FulizaUsage,
+		v.FulizaRepayRate,
+		v.P2PRatio,
+		v.BalanceVolatility,
+		v.DaysActive,
+		v.HustlerBalance,
+		v.OkoaFrequency,
+		v.AirtelVolume,
+		v.LenderDiversity,
+		v.EmergencyReliance,
+		v.SavingsRate,
+		v.BankActivity,
+		v.PendingLoansCount,
+		v.InstitutionalIncomeRatio,
+		v.ExpenseRegularity,
+		v.DebtServiceRatio,
+		v.FulizaLimitReachedCount,
+		v.GiftedAirtimeCount,
+		v.OntimeRepaymentRatio,
+		v.MinBalance,
+		v.NoBalanceData,
+		v.WeightedGamblingIndex,
+		v.RepaymentExpenseRatio,
+		v.DaysSinceLastIncome,
+		v.PostIncomeDrawdownRatio,
+		v.IncomeChannelDiversity,
+		v.FulizaDependencyRatio,
+		v.CounterpartyDiversity,
+		v.TotalFees,
+		v.GamblingCount,
+		v.RecurringIncomeRatio,
+		v.RemittanceRatio,
+		v.DigitalRepayRate,
+	}
+}
+
+// FeatureVectorFromSlice names the values of a MapFeatures vector. Indices
+// missing from a short slice are left zero and extra values are ignored.
+func FeatureVectorFromSlice(features []float64) FeatureVector {
+	padded := make([]float64, FeatureCount)
+	copy(padded, features)
+	return FeatureVector{
+		TotalIncome:              padded[0],
+		TotalExpenses:            padded[1],
+		ProfitabilityRatio:       padded[2],
+		TxnCount:                 padded[3],
+		MaxTxn:                   padded[4],
+		IncomeRegularity:         padded[5],
+		GamblingIndex:            padded[6],
+		UtilityRatio:             padded[7],
+		FulizaUsage:              padded[8],
+		FulizaRepayRate:          padded[9],
+		P2PRatio:                 padded[10],
+		BalanceVolatility:        padded[11],
+		DaysActive:               padded[12],
+		HustlerBalance:           padded[13],
+		OkoaFrequency:            padded[14],
+		AirtelVolume:             padded[15],
+		LenderDiversity:          padded[16],
+		EmergencyReliance:        padded[17],
+		SavingsRate:              padded[18],
+		BankActivity:             padded[19],
+		PendingLoansCount:        padded[20],
+		InstitutionalIncomeRatio: padded[21],
+		ExpenseRegularity:        padded[22],
+		DebtServiceRatio:         padded[23],
+		FulizaLimitReachedCount:  padded[24],
+		GiftedAirtimeCount:       padded[25],
+		OntimeRepaymentRatio:     padded[26],
+		MinBalance:               padded[27],
+		NoBalanceData:            padded[28],
+		WeightedGamblingIndex:    padded[29],
+		RepaymentExpenseRatio:    padded[30],
+		DaysSinceLastIncome:      padded[31],
+		PostIncomeDrawdownRatio:  padded[32],
+		IncomeChannelDiversity:   padded[33],
+		FulizaDependencyRatio:    padded[34],
+		CounterpartyDiversity:    padded[35],
+		TotalFees:                padded[36],
+		GamblingCount:            padded[37],
+		RecurringIncomeRatio:     padded[38],
+		RemittanceRatio:          padded[39],
+		DigitalRepayRate:         padded[40],
+	}
+}
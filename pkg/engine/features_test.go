@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestFeatureVector_RoundTrip(t *testing.T) {
+	features := make([]float64, FeatureCount)
+	for i := range features {
+		features[i] = float64(i + 1)
+	}
+
+	v := FeatureVectorFromSlice(features)
+	if v.GamblingIndex != 7 || v.DigitalRepayRate != FeatureCount {
+		t.Errorf("gambling_index, digital_repay_rate = %v, %v, want 7, %d", v.GamblingIndex, v.DigitalRepayRate, FeatureCount)
+	}
+	if got := v.ToSlice(); !slices.Equal(got, features) {
+		t.Errorf("ToSlice() = %v, want %v", got, features)
+	}
+}
+
+func TestFeatureVector_FieldsFollowFeatureNames(t *testing.T) {
+	typ := reflect.TypeOf(FeatureVector{})
+	if typ.NumField() != FeatureCount {
+		t.Fatalf("FeatureVector has %d fields, want %d", typ.NumField(), FeatureCount)
+	}
+	for i := range FeatureCount {
+		if tag := typ.Field(i).Tag.Get("json"); tag != FeatureNames[i] {
+			t.Errorf("field %d (%s) json = %q, want %q", i, typ.Field(i).Name, tag, FeatureNames[i])
+		}
+	}
+}
+
+func TestFeatureVectorFromSlice_Short(t *testing.T) {
+	v := FeatureVectorFromSlice([]float64{100, 40})
+	if v.TotalIncome != 100 || v.TotalExpenses != 40 || v.DigitalRepayRate != 0 {
+		t.Errorf("got %+v, want income 100, expenses 40 and the rest zero", v)
+	}
+}
+
+func TestVectorizeNamed(t *testing.T) {
+	txns := parseLogs(t, goldenLogs)
+	if got, want := VectorizeNamed(txns).ToSlice(), MapFeatures(txns); !slices.Equal(got, want) {
+		t.Errorf("VectorizeNamed() = %v, want MapFeatures %v", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"sort"
+
+	"borehole/core/pkg/parser"
+)
+
+// TypeGroup summarises the transactions of one TransactionType.
+type TypeGroup struct {
+	Count        int                  `json:"count"`
+	Total        float64              `json:"total"`
+	Transactions []parser.Transaction `json:"transactions"`
+}
+
+// GroupByType buckets transactions by TransactionType.String(), e.g. "FULIZA_LOAN",
+// for list views that show per-type subtotals. Reversals count towards Count but
+// are netted out of Total. Within a group, transactions are ordered by
+// Timestamp; ties keep their input order.
+func GroupByType(txns []parser.Transaction) map[string]TypeGroup {
+	groups := make(map[string]TypeGroup)
+	for _, txn := range txns {
+		key := txn.Type.String()
+		g := groups[key]
+		g.Count++
+		if txn.Reversal {
+			g.Total -= txn.Amount
+		} else {
+			g.Total += txn.Amount
+		}
+		g.Transactions = append(g.Transactions, txn)
+		groups[key] = g
+	}
+
+	for _, g := range groups {
+		sort.SliceStable(g.Transactions, func(i, j int) bool {
+			return g.Transactions[i].Timestamp.Before(g.Transactions[j].Timestamp)
+		})
+	}
+
+	return groups
+}
@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"borehole/core/pkg/parser"
+)
+
+func TestGroupByType(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	txns := []parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 1500, Timestamp: day(3)},
+		{Type: parser.TxnFulizaLoan, Amount: 2000, Timestamp: day(2)},
+		{Type: parser.TxnMPesaReceived, Amount: 250.50, Timestamp: day(1)},
+		{Type: parser.TxnGamblingStake, Amount: 100, Timestamp: day(4)},
+		{Type: parser.TxnMPesaReceived, Amount: 49.50, Timestamp: day(5)},
+	}
+
+	groups := GroupByType(txns)
+	if len(groups) != 3 {
+		t.Fatalf("GroupByType() returned %d groups, want 3", len(groups))
+	}
+
+	for key, g := range groups {
+		var total float64
+		for _, txn := range txns {
+			if txn.Type.String() == key {
+				total += txn.Amount
+			}
+		}
+		if g.Total != total {
+			t.Errorf("%s Total = %v, want %v", key, g.Total, total)
+		}
+		if g.Count != len(g.Transactions) {
+			t.Errorf("%s Count = %d, but holds %d transactions", key, g.Count, len(g.Transactions))
+		}
+	}
+
+	received := groups["MPESA_RECEIVED"]
+	if received.Count != 3 || received.Total != 1800 {
+		t.Errorf("MPESA_RECEIVED = {Count %d, Total %v}, want {3, 1800}", received.Count, received.Total)
+	}
+	for i := 1; i < len(received.Transactions); i++ {
+		if received.Transactions[i].Timestamp.Before(received.Transactions[i-1].Timestamp) {
+			t.Errorf("MPESA_RECEIVED transactions not sorted by Timestamp")
+		}
+	}
+}
@@ -2,144 +2,752 @@ package engine
 
 import (
 	"borehole/core/pkg/parser"
+	"context"
 	"math"
+	"strings"
+	"time"
 )
 
 const (
-	FeatureCount = 20
+	FeatureCount = 41
 )
 
-// MapFeatures transforms raw transactions into a 20-dimension feature vector.
+// FeatureNames documents the meaning of each index in the vector produced by MapFeatures.
+var FeatureNames = [FeatureCount]string{
+	"total_income",
+	"total_expenses",
+	"profitability_ratio",
+	"txn_count",
+	"max_txn",
+	"income_regularity",
+	"gambling_index",
+	"utility_ratio",
+	"fuliza_usage",
+	"fuliza_repay_rate",
+	"p2p_ratio",
+	"balance_volatility",
+	"days_active",
+	"hustler_balance",
+	"okoa_frequency",
+	"airtel_volume",
+	"lender_diversity",
+	"emergency_reliance",
+	"savings_rate",
+	"bank_activity",
+	"pending_loans_count",
+	"institutional_income_ratio",
+	"expense_regularity",
+	"debt_service_ratio",
+	"fuliza_limit_reached_count",
+	"gifted_airtime_count",
+	"ontime_repayment_ratio",
+	"min_balance",
+	"no_balance_data",
+	"weighted_gambling_index",
+	"repayment_expense_ratio",
+	"days_since_last_income",
+	"post_income_drawdown_ratio",
+	"income_channel_diversity",
+	"fuliza_dependency_ratio",
+	"counterparty_diversity",
+	"total_fees",
+	"gambling_count",
+	"recurring_income_ratio",
+	"remittance_ratio",
+	"digital_repay_rate",
+}
+
+// featureSchemaHash is computed once; FeatureNames is fixed at compile time.
+var featureSchemaHash = hashHex([]byte(strings.Join(FeatureNames[:], "\n")))
+
+// FeatureSchemaHash identifies the feature layout. It changes whenever a
+// feature is added, removed, renamed or reordered.
+func FeatureSchemaHash() string {
+	return featureSchemaHash
+}
+
+// MapperConfig holds optional feature-mapping behaviour. The zero value matches MapFeatures.
+type MapperConfig struct {
+	// ExactCents accumulates monetary totals in int64 cents and converts them
+	// once when the vector is filled. Float summation drifts by a few ULPs over
+	// thousands of amounts; cents make totals, and golden tests and certificates
+	// built on them, reproducible across architectures.
+	ExactCents bool
+
+	// GamblingWeights scales each betting platform's stake in
+	// weighted_gambling_index, keyed by platform name as it appears in the
+	// SMS (matched case-insensitively, e.g. "Betika"). Platforms not listed
+	// weigh 1.0, so a nil map makes the index equal gambling_index.
+	GamblingWeights map[string]float64
+
+	// ReferenceTime is the "as of" instant for time-relative features such as
+	// days_since_last_income, for reproducible backtesting. When set,
+	// transactions dated after it are ignored as not yet having happened.
+	// When zero, features are anchored to the latest transaction timestamp.
+	ReferenceTime time.Time
+
+	// MinConfidence drops transactions whose parser.Transaction.Confidence is
+	// below it, trading recall for precision; 0 keeps everything. A dropped
+	// transaction feeds no feature, so txn_count and days_active shrink, as do
+	// the totals it would have fed: generic Airtel receipts leave total_income
+	// and airtel_volume, generic MMF deposits leave savings_rate and
+	// total_expenses, and generic lender messages leave the loan, repayment
+	// and lender_diversity features. Ratios over those totals move with them.
+	MinConfidence float64
+
+	// NameNormalization decides which counterparty and lender names count as
+	// the same party in counterparty_diversity and lender_diversity. The zero
+	// value, NormalizeConservative, only ignores case and spacing.
+	NameNormalization NameNormalization
+}
+
+// MapFeatures transforms raw transactions into a FeatureCount-dimension feature vector.
+// See FeatureNames for the layout.
 // This is decoupled from the inference engine to allow independent testing/evolution.
 func MapFeatures(txns []parser.Transaction) []float64 {
+	return MapFeaturesWithConfig(txns, MapperConfig{})
+}
+
+// MapFeaturesWithConfig is MapFeatures with the given options.
+func MapFeaturesWithConfig(txns []parser.Transaction, cfg MapperConfig) []float64 {
 	features := make([]float64, FeatureCount)
 	if len(txns) == 0 {
 		return features
 	}
 
-	var (
-		totalIncome    float64
-		totalExpenses  float64
-		gamblingSpend  float64
-		utilitySpend   float64
-		fulizaBorrowed float64
-		fulizaRepaid   float64
-		p2pSends       float64
-		maxTxn         float64
-		hustlerBalance float64
-		okoaCount      float64
-		airtelVolume   float64
-		mmfDeposits    float64
-		bankTxnCount   float64
-		okoaAmount     float64
-		amounts        = make([]float64, 0, len(txns))
-		incomeAmounts  = make([]float64, 0, len(txns)/2)
-		lenders        = make(map[string]bool)
-	)
+	acc := newFeatureAccumulator(cfg)
+	for _, txn := range txns {
+		acc.add(txn)
+	}
+	acc.fill(features)
+
+	return features
+}
 
+// VectorizeWithProvenance returns the same vector as MapFeatures together with
+// the indices of the transactions that fed each feature, for auditing and
+// dispute resolution. A transaction is recorded against a feature when adding
+// it changed that feature's value; features no transaction moved have no entry.
+// The vector is recomputed after every transaction, so prefer MapFeatures when
+// provenance is not needed.
+func VectorizeWithProvenance(txns []parser.Transaction) ([]float64, map[int][]int) {
+	features := make([]float64, FeatureCount)
+	provenance := make(map[int][]int)
+	if len(txns) == 0 {
+		return features, provenance
+	}
+
+	acc := newFeatureAccumulator(MapperConfig{})
+	prev := make([]float64, FeatureCount)
+	for i, txn := range txns {
+		acc.add(txn)
+		acc.fill(features)
+		for f := range features {
+			if features[f] != prev[f] {
+				provenance[f] = append(provenance[f], i)
+			}
+		}
+		copy(prev, features)
+	}
+
+	return features, provenance
+}
+
+// VectorizeFromChannel consumes transactions from ch until it is closed and
+// returns the same vector MapFeatures would for the full sequence, so
+// streaming pipelines never need to materialize the slice. Receipts are
+// dropped once no later one can recur with them, which bounds that state for
+// a stream in chronological order or in reverse; a receipt arriving more than
+// parser.RecurringWindow out of order is compared only with those still held.
+// balance_volatility needs the whole balance series, so a small point per
+// dated wallet transaction is kept and memory is O(n) in those. If ctx is
+// cancelled first, the partial vector is discarded and ctx's error returned.
+func VectorizeFromChannel(ctx context.Context, ch <-chan parser.Transaction) ([]float64, error) {
+	features := make([]float64, FeatureCount)
+	acc := newFeatureAccumulator(MapperConfig{})
+	acc.recurring.bounded = true
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case txn, ok := <-ch:
+			if !ok {
+				if acc.txnCount > 0 {
+					acc.fill(features)
+				}
+				return features, nil
+			}
+			acc.add(txn)
+		}
+	}
+}
+
+// EstimatedNetIncome returns total income minus loan disbursements (Fuliza,
+// Hustler Fund, Okoa Jahazi and digital lenders), i.e. the money actually earned.
+// Unlike total_income it does not grow when the customer borrows.
+func EstimatedNetIncome(txns []parser.Transaction) float64 {
+	acc := newFeatureAccumulator(MapperConfig{})
 	for _, txn := range txns {
-		amounts = append(amounts, txn.Amount)
-		if txn.Amount > maxTxn {
-			maxTxn = txn.Amount
+		acc.add(txn)
+	}
+	return acc.netIncome()
+}
+
+// NetOutstandingDebt returns loan disbursements (Fuliza, Hustler Fund, Okoa
+// Jahazi and digital lenders) minus repayments to any lender, floored at 0.
+// Reversed disbursements and repayments are netted out first, so a failed
+// repayment leaves the debt where it was.
+func NetOutstandingDebt(txns []parser.Transaction) float64 {
+	acc := newFeatureAccumulator(MapperConfig{})
+	for _, txn := range txns {
+		acc.add(txn)
+	}
+	return acc.outstandingDebt()
+}
+
+// featureAccumulator holds the running aggregates behind the feature vector.
+// Transactions are added one at a time and fill derives the vector from the
+// aggregates, so callers can snapshot features mid-stream.
+type featureAccumulator struct {
+	exactCents     bool
+	minConfidence  float64
+	referenceTime  time.Time
+	latest         time.Time          // Latest transaction timestamp seen
+	earliest       time.Time          // Earliest dated transaction seen
+	lastIncome     time.Time          // Latest dated receipt of earned income
+	gamblingWeight map[string]float64 // Lowercased platform -> severity weight
+	names          NameNormalization
+	txnCount       int
+	totalIncome    moneyTotal
+	totalExpenses  moneyTotal
+	gamblingSpend  moneyTotal
+	weightedGamble moneyTotal
+	gamblingWins   moneyTotal // Netted against gamblingSpend
+	weightedWins   moneyTotal // Netted against weightedGamble
+	gamblingCount  float64    // Bets and deposits, however small
+	utilitySpend   moneyTotal
+	fulizaBorrowed moneyTotal
+	loanInflows    moneyTotal // Borrowed funds counted in totalIncome
+	debtRepaid     moneyTotal // Repayments to any lender, including bank EMIs
+	fulizaRepaid   moneyTotal
+	p2pSends       moneyTotal
+	fees           moneyTotal // Transaction costs charged on top of amounts
+	maxTxn         float64
+	hustlerBalance float64               // Highest balance a Hustler Fund message reported
+	hustlerNet     moneyTotal            // Disbursed principal net of reversals
+	hustlerLoans   []hustlerDisbursement // Disbursements a reversal may still undo, oldest first
+	okoaCount      float64
+	airtelVolume   moneyTotal
+	mmfDeposits    moneyTotal
+	bankTxnCount   float64
+	okoaAmount     moneyTotal
+	pendingLoans   float64
+	fulizaLimitHit float64
+	outboundCount  float64 // Transfers and purchases, for fuliza_dependency_ratio
+	fulizaFunded   float64 // Outbound transactions Fuliza had to top up
+	airtimeGifts   float64
+	minBalance     float64 // Lowest wallet balance seen; valid once hasBalance is set
+	hasBalance     bool
+	institutional  moneyTotal
+	remittances    moneyTotal // Income sent from abroad through a remittance service
+	incomeAmounts  runningStats
+	expenseAmounts runningStats
+	lenders        map[string]bool
+	digitalLoans   map[string]*moneyTotal // Digital lender principal borrowed, by canonical lender
+	digitalRepaid  map[string]*moneyTotal // Repayments to digital lenders, by canonical lender
+	incomeChannels map[string]bool
+	counterparties map[string]bool // Canonical names of P2P senders and recipients
+	activeDays     map[string]bool // Calendar dates ("2006-01-02") of dated transactions
+	repayments     *repaymentTracker
+	drawdown       drawdownTracker
+	balances       balanceTracker
+	recurring      recurringTracker
+}
+
+// newFeatureAccumulator creates an empty accumulator.
+func newFeatureAccumulator(cfg MapperConfig) *featureAccumulator {
+	weights := make(map[string]float64, len(cfg.GamblingWeights))
+	for platform, w := range cfg.GamblingWeights {
+		weights[strings.ToLower(platform)] = w
+	}
+	return &featureAccumulator{
+		exactCents:     cfg.ExactCents,
+		referenceTime:  cfg.ReferenceTime,
+		minConfidence:  cfg.MinConfidence,
+		gamblingWeight: weights,
+		lenders:        make(map[string]bool),
+		digitalLoans:   make(map[string]*moneyTotal),
+		digitalRepaid:  make(map[string]*moneyTotal),
+		incomeChannels: make(map[string]bool),
+		counterparties: make(map[string]bool),
+		activeDays:     make(map[string]bool),
+		names:          cfg.NameNormalization,
+		repayments:     newRepaymentTracker(),
+	}
+}
+
+// add folds a single transaction into the aggregates.
+func (a *featureAccumulator) add(txn parser.Transaction) {
+	if txn.Confidence < a.minConfidence {
+		return
+	}
+	if !a.referenceTime.IsZero() && txn.Timestamp.After(a.referenceTime) {
+		return
+	}
+	if txn.Timestamp.After(a.latest) {
+		a.latest = txn.Timestamp
+	}
+	if !txn.Timestamp.IsZero() && (a.earliest.IsZero() || txn.Timestamp.Before(a.earliest)) {
+		a.earliest = txn.Timestamp
+	}
+	a.txnCount++
+	if !txn.Timestamp.IsZero() {
+		a.activeDays[txn.Timestamp.Format("2006-01-02")] = true
+	}
+	if txn.Type.IsInformational() {
+		a.addInformational(txn)
+		return
+	}
+	a.balances.add(txn)
+	if txn.Reversal {
+		a.reverse(txn)
+		return
+	}
+
+	a.repayments.add(txn)
+	a.drawdown.add(txn)
+	a.observeBalance(txn)
+	a.fees.add(txn.Cost)
+	if txn.Type.IsOutbound() {
+		a.outboundCount++
+		if txn.FulizaFunded {
+			a.fulizaFunded++
 		}
+	}
+	if channel := incomeChannel(txn); channel != "" {
+		a.incomeChannels[channel] = true
+		a.recurring.add(txn)
+	}
+	if txn.Amount > a.maxTxn {
+		a.maxTxn = txn.Amount
+	}
 
+	switch txn.Type {
+	case parser.TxnMPesaReceived, parser.TxnTKashReceived, parser.TxnAirtelReceived, parser.TxnBankReceived,
+		parser.TxnRemittanceReceived:
+		a.totalIncome.add(txn.Amount)
+		a.incomeAmounts.add(txn.Amount)
+		a.addParty(a.counterparties, txn.Sender)
+		if txn.Timestamp.After(a.lastIncome) {
+			a.lastIncome = txn.Timestamp
+		}
+		if txn.Institutional {
+			a.institutional.add(txn.Amount)
+		}
 		switch txn.Type {
-		case parser.TxnMPesaReceived, parser.TxnTKashReceived, parser.TxnAirtelReceived:
-			totalIncome += txn.Amount
-			incomeAmounts = append(incomeAmounts, txn.Amount)
-			if txn.Type == parser.TxnAirtelReceived {
-				airtelVolume += txn.Amount
-			}
-		case parser.TxnMPesaSent, parser.TxnTKashSent, parser.TxnAirtelSent:
-			totalExpenses += txn.Amount
-			p2pSends += txn.Amount
-			if txn.Type == parser.TxnAirtelSent {
-				airtelVolume += txn.Amount
-			}
-		case parser.TxnMPesaPaybill, parser.TxnMPesaBuyGoods:
-			totalExpenses += txn.Amount
-			utilitySpend += txn.Amount * 0.3
-		case parser.TxnFulizaLoan:
-			fulizaBorrowed += txn.Amount
-			totalIncome += txn.Amount
-		case parser.TxnFulizaRepay:
-			fulizaRepaid += txn.Amount
-			totalExpenses += txn.Amount
-		case parser.TxnHustlerLoan:
-			totalIncome += txn.Amount
-			if txn.Balance > hustlerBalance {
-				hustlerBalance = txn.Balance
-			}
-			if txn.Amount > 0 && hustlerBalance == 0 {
-				hustlerBalance = txn.Amount
-			}
-		case parser.TxnHustlerRepay:
-			totalExpenses += txn.Amount
-		case parser.TxnOkoaReceived:
-			okoaCount++
-			totalIncome += txn.Amount
-			if txn.Balance > 0 {
-				okoaAmount = txn.Balance
-			} else {
-				okoaAmount += txn.Amount
-			}
-		case parser.TxnOkoaDebt:
-			okoaCount++
-			if txn.Balance > 0 {
-				okoaAmount = txn.Balance
-			} else if txn.Amount > 0 {
-				okoaAmount += txn.Amount
-			}
-		case parser.TxnDigitalLoan:
-			totalIncome += txn.Amount
-			if txn.Lender != "" {
-				lenders[txn.Lender] = true
-			}
-		case parser.TxnDigitalRepay:
-			totalExpenses += txn.Amount
-			if txn.Lender != "" {
-				lenders[txn.Lender] = true
-			}
-		case parser.TxnMMFDeposit:
-			mmfDeposits += txn.Amount
-			totalExpenses += txn.Amount
-		case parser.TxnMMFWithdraw:
-			totalIncome += txn.Amount
-		case parser.TxnBankDeposit:
-			bankTxnCount++
-			totalExpenses += txn.Amount
-		case parser.TxnBankWithdraw:
-			bankTxnCount++
-			totalIncome += txn.Amount
-		case parser.TxnGambling:
-			gamblingSpend += txn.Amount
-			totalExpenses += txn.Amount
-		}
-	}
-
-	// 20-Dimension Mapping
-	features[0] = totalIncome
-	features[1] = totalExpenses
-	features[2] = safeDiv(totalIncome, totalExpenses) // Profitability Ratio
-	features[3] = float64(len(txns))
-	features[4] = maxTxn
-	features[5] = coefficientOfVariation(incomeAmounts)
-	features[6] = safeDiv(gamblingSpend, totalExpenses)
-	features[7] = safeDiv(utilitySpend, totalExpenses)
-	features[8] = safeDiv(fulizaBorrowed, totalIncome)
-	features[9] = safeDiv(fulizaRepaid, fulizaBorrowed)
-	features[10] = safeDiv(p2pSends, totalExpenses)
-	features[11] = stdDev(amounts)
-	features[12] = math.Min(float64(len(txns)), 30) // Days Active Approx
-	features[13] = hustlerBalance
-	features[14] = okoaCount
-	features[15] = airtelVolume
-	features[16] = float64(len(lenders))
-	features[17] = safeDiv(okoaAmount+fulizaBorrowed, totalIncome) // Emergency Reliance
-	features[18] = safeDiv(mmfDeposits, totalIncome)               // Savings Rate
-	features[19] = bankTxnCount
+		case parser.TxnAirtelReceived:
+			a.airtelVolume.add(txn.Amount)
+		case parser.TxnBankReceived:
+			a.bankTxnCount++
+		case parser.TxnRemittanceReceived:
+			a.remittances.add(txn.Amount)
+		}
+	case parser.TxnMPesaSent, parser.TxnTKashSent, parser.TxnAirtelSent, parser.TxnBankSent:
+		a.addExpense(txn.Amount)
+		a.p2pSends.add(txn.Amount)
+		a.addParty(a.counterparties, txn.Recipient)
+		switch txn.Type {
+		case parser.TxnAirtelSent:
+			a.airtelVolume.add(txn.Amount)
+		case parser.TxnBankSent:
+			a.bankTxnCount++
+		}
+	case parser.TxnMPesaPaybill, parser.TxnMPesaBuyGoods:
+		a.addExpense(txn.Amount)
+	case parser.TxnUtility:
+		a.addExpense(txn.Amount)
+		a.utilitySpend.add(txn.Amount)
+	case parser.TxnAirtime:
+		a.addExpense(txn.Amount)
+	case parser.TxnFulizaLoan:
+		a.fulizaBorrowed.add(txn.Amount)
+		a.addLoan(txn.Amount)
+	case parser.TxnFulizaRepay:
+		a.fulizaRepaid.add(txn.Amount)
+		a.addRepayment(txn.Amount)
+	case parser.TxnHustlerLoan:
+		a.addLoan(txn.Amount)
+		if txn.Balance > a.hustlerBalance {
+			a.hustlerBalance = txn.Balance
+		}
+		// The locked savings portion is owed too, but lands in savings, not the wallet
+		a.hustlerNet.add(txn.Amount + txn.Saved)
+		a.mmfDeposits.add(txn.Saved)
+		a.hustlerLoans = append(a.hustlerLoans, hustlerDisbursement{wallet: txn.Amount, saved: txn.Saved})
+	case parser.TxnHustlerRepay:
+		a.addRepayment(txn.Amount)
+	case parser.TxnOkoaReceived:
+		a.okoaCount++
+		a.addLoan(txn.Amount)
+		if txn.Balance > 0 {
+			a.okoaAmount.set(txn.Balance)
+		} else {
+			a.okoaAmount.add(txn.Amount)
+		}
+	case parser.TxnOkoaDebt:
+		a.okoaCount++
+		if txn.Balance > 0 {
+			a.okoaAmount.set(txn.Balance)
+		} else if txn.Amount > 0 {
+			a.okoaAmount.add(txn.Amount)
+		}
+	case parser.TxnDigitalLoan, parser.TxnKCBLoan:
+		a.addLoan(txn.Amount)
+		a.addParty(a.lenders, txn.Lender)
+		if txn.Type == parser.TxnDigitalLoan {
+			a.addLenderTotal(a.digitalLoans, txn.Lender, txn.Amount)
+		}
+	case parser.TxnDigitalRepay, parser.TxnKCBRepay:
+		a.addRepayment(txn.Amount)
+		a.addParty(a.lenders, txn.Lender)
+		if txn.Type == parser.TxnDigitalRepay {
+			a.addLenderTotal(a.digitalRepaid, txn.Lender, txn.Amount)
+		}
+	case parser.TxnMMFDeposit:
+		a.mmfDeposits.add(txn.Amount)
+		a.addExpense(txn.Amount)
+	case parser.TxnMMFWithdraw:
+		a.totalIncome.add(txn.Amount)
+	case parser.TxnAgentDeposit:
+		// Cash in only moves the user's own money into the wallet
+	case parser.TxnAgentWithdraw:
+		// Cash out reduces the wallet but where the cash goes is unknown, so it
+		// counts towards drawdown rather than expenses
+	case parser.TxnBankDeposit:
+		a.bankTxnCount++
+		a.addExpense(txn.Amount)
+	case parser.TxnBankWithdraw:
+		a.bankTxnCount++
+		a.totalIncome.add(txn.Amount)
+	case parser.TxnBankLoanRepay:
+		a.addRepayment(txn.Amount)
+	case parser.TxnGamblingStake:
+		a.gamblingCount++
+		a.gamblingSpend.add(txn.Amount)
+		a.weightedGamble.add(txn.Amount * a.gamblingSeverity(txn.Recipient))
+		a.addExpense(txn.Amount)
+	case parser.TxnGamblingWin:
+		a.totalIncome.add(txn.Amount)
+		a.gamblingWins.add(txn.Amount)
+		a.weightedWins.add(txn.Amount * a.gamblingSeverity(txn.Recipient))
+	}
+}
 
-	return features
+// addInformational counts events that moved no money.
+func (a *featureAccumulator) addInformational(txn parser.Transaction) {
+	switch txn.Type {
+	case parser.TxnLoanPending:
+		a.pendingLoans++
+	case parser.TxnFulizaLimitReached:
+		a.fulizaLimitHit++
+	case parser.TxnAirtimeGift:
+		a.airtimeGifts++
+	}
+}
+
+// observeBalance tracks the lowest wallet balance reported, which may be 0.
+// Hustler Fund and Okoa balances are debts, not liquidity.
+func (a *featureAccumulator) observeBalance(txn parser.Transaction) {
+	balance, ok := reportedBalance(txn)
+	if !ok {
+		return
+	}
+	if !a.hasBalance || balance < a.minBalance {
+		a.minBalance = balance
+		a.hasBalance = true
+	}
+}
+
+// reverse nets a reversed transaction back out of the flows it originally fed.
+// Regularity and amount statistics keep the original sample.
+func (a *featureAccumulator) reverse(txn parser.Transaction) {
+	switch txn.Type {
+	case parser.TxnHustlerLoan:
+		d := a.undoHustlerLoan(txn.Amount)
+		a.addLoan(-d.wallet)
+		a.hustlerNet.add(-(d.wallet + d.saved))
+		a.mmfDeposits.add(-d.saved)
+	case parser.TxnHustlerRepay, parser.TxnDigitalRepay:
+		a.totalExpenses.add(-txn.Amount)
+		a.debtRepaid.add(-txn.Amount)
+		if txn.Type == parser.TxnDigitalRepay {
+			a.addLenderTotal(a.digitalRepaid, txn.Lender, -txn.Amount)
+		}
+	case parser.TxnDigitalLoan:
+		a.addLoan(-txn.Amount)
+		a.addLenderTotal(a.digitalLoans, txn.Lender, -txn.Amount)
+	case parser.TxnMPesaPaybill, parser.TxnMPesaBuyGoods:
+		a.totalExpenses.add(-txn.Amount)
+	case parser.TxnUtility:
+		a.totalExpenses.add(-txn.Amount)
+		a.utilitySpend.add(-txn.Amount)
+	case parser.TxnOkoaReceived:
+		a.okoaCount--
+		a.addLoan(-txn.Amount)
+		a.okoaAmount.add(-txn.Amount)
+		if a.okoaAmount.sum < 0 { // A quoted debt balance may already exclude it
+			a.okoaAmount.set(0)
+		}
+	case parser.TxnBankDeposit:
+		a.bankTxnCount--
+		a.totalExpenses.add(-txn.Amount)
+	case parser.TxnBankWithdraw:
+		a.bankTxnCount--
+		a.totalIncome.add(-txn.Amount)
+	case parser.TxnMPesaReceived, parser.TxnTKashReceived, parser.TxnAirtelReceived:
+		a.totalIncome.add(-txn.Amount)
+		if txn.Institutional {
+			a.institutional.add(-txn.Amount)
+		}
+		if txn.Type == parser.TxnAirtelReceived {
+			a.airtelVolume.add(-txn.Amount)
+		}
+	case parser.TxnMPesaSent, parser.TxnTKashSent, parser.TxnAirtelSent:
+		a.totalExpenses.add(-txn.Amount)
+		a.p2pSends.add(-txn.Amount)
+		if txn.Type == parser.TxnAirtelSent {
+			a.airtelVolume.add(-txn.Amount)
+		}
+	}
+}
+
+// hustlerDisbursement is a Hustler Fund loan split between the wallet and
+// locked savings; saved is 0 when all of it reached the wallet.
+type hustlerDisbursement struct {
+	wallet float64
+	saved  float64
+}
+
+// undoHustlerLoan finds the latest disbursement a reversal of amount undoes,
+// matching either its wallet portion or its full principal, and forgets it so
+// it is not reversed twice. A reversal matching none is taken to undo amount
+// in the wallet alone.
+func (a *featureAccumulator) undoHustlerLoan(amount float64) hustlerDisbursement {
+	for i := len(a.hustlerLoans) - 1; i >= 0; i-- {
+		d := a.hustlerLoans[i]
+		if math.Abs(d.wallet-amount) < settledEpsilon || math.Abs(d.wallet+d.saved-amount) < settledEpsilon {
+			a.hustlerLoans = append(a.hustlerLoans[:i], a.hustlerLoans[i+1:]...)
+			return d
+		}
+	}
+	return hustlerDisbursement{wallet: amount}
+}
+
+// addParty records name in set under its canonical key, ignoring blanks.
+func (a *featureAccumulator) addParty(set map[string]bool, name string) {
+	if key := a.names.Canonicalize(name); key != "" {
+		set[key] = true
+	}
+}
+
+// incomeChannel names the route earned income arrived by, or "" for
+// transactions that are not earned income. Loans are borrowing, not a channel.
+func incomeChannel(txn parser.Transaction) string {
+	switch txn.Type {
+	case parser.TxnMPesaReceived:
+		if txn.Institutional {
+			return "business" // Salaries and payouts, including via paybill
+		}
+		return "mpesa"
+	case parser.TxnTKashReceived, parser.TxnAirtelReceived:
+		return "other_wallet"
+	case parser.TxnBankWithdraw, parser.TxnBankReceived:
+		return "bank"
+	case parser.TxnMMFWithdraw:
+		return "savings"
+	case parser.TxnRemittanceReceived:
+		return "remittance"
+	default:
+		return ""
+	}
+}
+
+// gamblingSeverity returns the configured weight for a betting platform, or 1.
+func (a *featureAccumulator) gamblingSeverity(platform string) float64 {
+	if w, ok := a.gamblingWeight[strings.ToLower(platform)]; ok {
+		return w
+	}
+	return 1
+}
+
+// addLoan records a disbursement, which counts as income but not earnings.
+func (a *featureAccumulator) addLoan(amount float64) {
+	a.totalIncome.add(amount)
+	a.loanInflows.add(amount)
+}
+
+// addLenderTotal adds amount to lender's running total in totals, keyed by
+// the canonical lender name.
+func (a *featureAccumulator) addLenderTotal(totals map[string]*moneyTotal, lender string, amount float64) {
+	key := a.names.Canonicalize(lender)
+	if totals[key] == nil {
+		totals[key] = &moneyTotal{}
+	}
+	totals[key].add(amount)
+}
+
+// digitalRepayRate is the share of digital lender borrowing repaid, across
+// all lenders, clamped to [0, 2]. Repayments can exceed the principal through
+// interest and fees or loans taken before the first message.
+func (a *featureAccumulator) digitalRepayRate() float64 {
+	var borrowed, repaid float64
+	for _, t := range a.digitalLoans {
+		borrowed += a.money(*t)
+	}
+	for _, t := range a.digitalRepaid {
+		repaid += a.money(*t)
+	}
+	return math.Max(0, math.Min(safeDiv(repaid, borrowed), 2))
+}
+
+// addRepayment records an outflow that services debt.
+func (a *featureAccumulator) addRepayment(amount float64) {
+	a.debtRepaid.add(amount)
+	a.addExpense(amount)
+}
+
+// addExpense records an outflow towards total expenses and expense regularity.
+func (a *featureAccumulator) addExpense(amount float64) {
+	a.totalExpenses.add(amount)
+	a.expenseAmounts.add(amount)
+}
+
+// fill writes the feature vector derived from the current aggregates into
+// features, which must have length FeatureCount.
+func (a *featureAccumulator) fill(features []float64) {
+	income := a.money(a.totalIncome)
+	expenses := a.money(a.totalExpenses)
+	fulizaBorrowed := a.money(a.fulizaBorrowed)
+	// Winnings offset stakes, so a net winner shows no gambling loss
+	gamblingLoss := math.Max(a.money(a.gamblingSpend)-a.money(a.gamblingWins), 0)
+	weightedLoss := math.Max(a.money(a.weightedGamble)-a.money(a.weightedWins), 0)
+
+	features[0] = income
+	features[1] = expenses
+	features[2] = safeDiv(income, expenses) // Profitability Ratio
+	features[3] = float64(a.txnCount)
+	features[4] = a.maxTxn
+	features[5] = a.incomeAmounts.coefficientOfVariation()
+	features[6] = safeDiv(gamblingLoss, expenses)
+	features[7] = safeDiv(a.money(a.utilitySpend), expenses)
+	features[8] = safeDiv(fulizaBorrowed, income)
+	features[9] = safeDiv(a.money(a.fulizaRepaid), fulizaBorrowed)
+	features[10] = safeDiv(a.money(a.p2pSends), expenses)
+	features[11] = a.balances.volatility()
+	features[12] = a.daysActive()
+	features[13] = math.Max(a.hustlerBalance, a.money(a.hustlerNet))
+	features[14] = a.okoaCount
+	features[15] = a.money(a.airtelVolume)
+	features[16] = float64(len(a.lenders))
+	features[17] = safeDiv(a.money(a.okoaAmount)+fulizaBorrowed, income) // Emergency Reliance
+	features[18] = safeDiv(a.money(a.mmfDeposits), income)               // Savings Rate
+	features[19] = a.bankTxnCount
+	features[20] = a.pendingLoans
+	features[21] = safeDiv(a.money(a.institutional), income) // Institutional Income Share
+	features[22] = a.expenseAmounts.coefficientOfVariation() // Expense Regularity
+	features[23] = safeDiv(a.money(a.debtRepaid), income)    // Debt Service Ratio
+	features[24] = a.fulizaLimitHit
+	features[25] = a.airtimeGifts
+	features[26] = a.repayments.ratio()
+	features[27] = a.minBalance
+	features[28] = 1 // No Balance Data
+	if a.hasBalance {
+		features[28] = 0
+	}
+	features[29] = safeDiv(weightedLoss, expenses)          // Weighted Gambling Index
+	features[30] = safeDiv(a.money(a.debtRepaid), expenses) // Repayment Share of Expenses
+	features[31] = a.daysSinceLastIncome()
+	features[32] = a.drawdown.ratio()
+	features[33] = float64(len(a.incomeChannels))
+	features[34] = safeDiv(a.fulizaFunded, a.outboundCount) // Fuliza Dependency
+	features[35] = float64(len(a.counterparties))
+	features[36] = a.money(a.fees)
+	features[37] = a.gamblingCount
+	features[38] = safeDiv(a.money(a.recurring.total), income) // Recurring Income Share
+	features[39] = safeDiv(a.money(a.remittances), income)     // Remittance Share
+	features[40] = a.digitalRepayRate()                        // Digital Lender Repay Rate
+}
+
+// daysActive counts the distinct calendar days with a dated transaction.
+// Without any dates it falls back to the transaction count capped at 30.
+func (a *featureAccumulator) daysActive() float64 {
+	if len(a.activeDays) == 0 {
+		return math.Min(float64(a.txnCount), 30)
+	}
+	return float64(len(a.activeDays))
+}
+
+// noIncomeDays is the least days_since_last_income reported for a history
+// without dated income, so one that is only days long never reads as recent.
+const noIncomeDays = 30
+
+// daysSinceLastIncome measures from the reference time (or the latest
+// transaction) back to the last dated income. Without one it returns the
+// length of the dated history, or noIncomeDays if that is shorter: no income
+// was seen for at least as long as the history reaches back.
+func (a *featureAccumulator) daysSinceLastIncome() float64 {
+	ref := a.referenceTime
+	if ref.IsZero() {
+		ref = a.latest
+	}
+	if a.lastIncome.IsZero() {
+		var window float64
+		if !a.earliest.IsZero() {
+			window = ref.Sub(a.earliest).Hours() / 24
+		}
+		return math.Max(window, noIncomeDays)
+	}
+	return ref.Sub(a.lastIncome).Hours() / 24
+}
+
+// netIncome returns total income minus loan disbursements.
+func (a *featureAccumulator) netIncome() float64 {
+	return a.money(a.totalIncome) - a.money(a.loanInflows)
+}
+
+// outstandingDebt returns disbursements minus repayments, floored at 0.
+func (a *featureAccumulator) outstandingDebt() float64 {
+	return math.Max(a.money(a.loanInflows)-a.money(a.debtRepaid), 0)
+}
+
+// money reads a running total in the accumulator's configured precision.
+func (a *featureAccumulator) money(t moneyTotal) float64 {
+	if a.exactCents {
+		return float64(t.cents) / 100
+	}
+	return t.sum
+}
+
+// moneyTotal is a running sum of amounts kept both as float64 and as whole
+// cents, so MapperConfig.ExactCents can pick either without re-reading input.
+type moneyTotal struct {
+	sum   float64
+	cents int64
+}
+
+func (t *moneyTotal) add(amount float64) {
+	t.sum += amount
+	t.cents += toCents(amount)
+}
+
+func (t *moneyTotal) set(amount float64) {
+	t.sum = amount
+	t.cents = toCents(amount)
+}
+
+// toCents rounds an amount to the nearest whole cent.
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
 }
 
 // Utility functions moved from engine.go for modularity
@@ -151,41 +759,34 @@ func safeDiv(numerator, denominator float64) float64 {
 	return numerator / denominator
 }
 
-func sum(values []float64) float64 {
-	var total float64
-	for _, v := range values {
-		total += v
-	}
-	return total
+// runningStats tracks the count, mean and spread of a sample with Welford's
+// online algorithm, so the accumulator's memory does not grow with input size.
+type runningStats struct {
+	n    int
+	mean float64
+	m2   float64 // Sum of squared deviations from the running mean
 }
 
-func mean(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	return sum(values) / float64(len(values))
+func (s *runningStats) add(v float64) {
+	s.n++
+	delta := v - s.mean
+	s.mean += delta / float64(s.n)
+	s.m2 += delta * (v - s.mean)
 }
 
-func stdDev(values []float64) float64 {
-	if len(values) == 0 {
+// stdDev returns the population standard deviation, or 0 for an empty sample.
+func (s *runningStats) stdDev() float64 {
+	if s.n == 0 {
 		return 0
 	}
-	m := mean(values)
-	var sumSquares float64
-	for _, v := range values {
-		diff := v - m
-		sumSquares += diff * diff
-	}
-	return math.Sqrt(sumSquares / float64(len(values)))
+	return math.Sqrt(s.m2 / float64(s.n))
 }
 
-func coefficientOfVariation(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	m := mean(values)
-	if m == 0 {
+// coefficientOfVariation returns stdDev relative to the mean, or 0 when the
+// sample is empty or averages to zero.
+func (s *runningStats) coefficientOfVariation() float64 {
+	if s.n == 0 || s.mean == 0 {
 		return 0
 	}
-	return stdDev(values) / m
+	return s.stdDev() / s.mean
 }
@@ -0,0 +1,1252 @@
+package engine
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"borehole/core/pkg/parser"
+)
+
+// parseLogs parses SMS logs with the default parser, failing unless every log parses.
+func parseLogs(t *testing.T, logs []string) []parser.Transaction {
+	t.Helper()
+	txns, err := parser.NewParser().ParseLogs(context.Background(), logs)
+	if err != nil {
+		t.Fatalf("ParseLogs() error = %v", err)
+	}
+	if len(txns) != len(logs) {
+		t.Fatalf("ParseLogs() parsed %d of %d logs", len(txns), len(logs))
+	}
+	return txns
+}
+
+// mapLogs parses SMS logs with the default parser and maps them to features.
+func mapLogs(t *testing.T, logs []string) []float64 {
+	t.Helper()
+	return MapFeatures(parseLogs(t, logs))
+}
+
+func TestMapFeatures_LoanPendingNotIncome(t *testing.T) {
+	pending := mapLogs(t, []string{
+		"Tala: Your loan application is being processed. We will notify you shortly",
+	})
+	if pending[0] != 0 {
+		t.Errorf("total_income = %v after pending notice, want 0", pending[0])
+	}
+	if pending[20] != 1 {
+		t.Errorf("pending_loans_count = %v, want 1", pending[20])
+	}
+
+	disbursed := mapLogs(t, []string{
+		"Tala: Your loan application is being processed. We will notify you shortly",
+		"You have received Ksh5,000.00 from Tala",
+	})
+	if disbursed[0] != 5000 {
+		t.Errorf("total_income = %v after disbursement, want 5000", disbursed[0])
+	}
+	if disbursed[20] != 1 {
+		t.Errorf("pending_loans_count = %v, want 1", disbursed[20])
+	}
+}
+
+func TestFeatureNames_Unique(t *testing.T) {
+	seen := make(map[string]bool, FeatureCount)
+	for i, name := range FeatureNames {
+		if name == "" {
+			t.Errorf("FeatureNames[%d] is empty", i)
+		}
+		if seen[name] {
+			t.Errorf("FeatureNames[%d] = %q is duplicated", i, name)
+		}
+		seen[name] = true
+	}
+}
+
+func TestMapFeatures_InstitutionalIncome(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA77BIZPAY Confirmed. You have received Ksh20,000.00 from SAFARICOM LIMITED 123456",
+		"UA34ABCDEF Confirmed. You have received Ksh5,000.00 from JOHN DOE 0712345678",
+	})
+	if features[0] != 25000 {
+		t.Errorf("total_income = %v, want 25000", features[0])
+	}
+	if !almostEqual(features[21], 0.8, floatEpsilon) {
+		t.Errorf("institutional_income_ratio = %v, want 0.8", features[21])
+	}
+}
+
+func TestVectorizeWithProvenance(t *testing.T) {
+	txns, err := parser.NewParser().ParseLogs(context.Background(), []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678", // 0: income
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",                  // 1: expense
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",                                 // 2: income
+		"Betika: Your bet of Ksh100.00 has been placed",                                // 3: expense
+		"M-Shwari. You have withdrawn Ksh500.00",                                       // 4: income
+	})
+	if err != nil {
+		t.Fatalf("ParseLogs() error = %v", err)
+	}
+
+	features, provenance := VectorizeWithProvenance(txns)
+
+	want := MapFeatures(txns)
+	for i := range want {
+		if features[i] != want[i] {
+			t.Errorf("features[%d] = %v, want %v (MapFeatures)", i, features[i], want[i])
+		}
+	}
+
+	assertIndices(t, "total_income", provenance[0], []int{0, 2, 4})
+	assertIndices(t, "total_expenses", provenance[1], []int{1, 3})
+	assertIndices(t, "gambling_index", provenance[6], []int{3})
+	if _, ok := provenance[20]; ok {
+		t.Errorf("pending_loans_count has provenance %v, want none", provenance[20])
+	}
+}
+
+func assertIndices(t *testing.T, feature string, got, want []int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s provenance = %v, want %v", feature, got, want)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s provenance = %v, want %v", feature, got, want)
+			return
+		}
+	}
+}
+
+func TestMapFeatures_ExpenseRegularity(t *testing.T) {
+	steady := mapLogs(t, []string{
+		"UA00STEAD1 Confirmed. Ksh1,000.00 sent to JANE DOE 0798765432",
+		"UA00STEAD2 Confirmed. Ksh1,000.00 sent to JANE DOE 0798765432",
+		"UA00STEAD3 Confirmed. Ksh1,000.00 paid to KPLC Account 12345",
+	})
+	spiky := mapLogs(t, []string{
+		"UA00SPIKE1 Confirmed. Ksh100.00 sent to JANE DOE 0798765432",
+		"UA00SPIKE2 Confirmed. Ksh50.00 sent to JANE DOE 0798765432",
+		"UA00SPIKE3 Confirmed. Ksh9,000.00 paid to KPLC Account 12345",
+	})
+
+	if steady[22] != 0 {
+		t.Errorf("expense_regularity = %v for identical expenses, want 0", steady[22])
+	}
+	if spiky[22] <= 1 {
+		t.Errorf("expense_regularity = %v for spiky expenses, want > 1", spiky[22])
+	}
+}
+
+func TestMapFeatures_BongaRedeemNotIncome(t *testing.T) {
+	features := mapLogs(t, []string{
+		"You have redeemed Ksh100 worth of Bonga Points. Your new Bonga balance is 250",
+	})
+	if features[0] != 0 {
+		t.Errorf("total_income = %v, want 0", features[0])
+	}
+	if features[4] != 0 {
+		t.Errorf("max_txn = %v, want 0", features[4])
+	}
+}
+
+func TestMapFeatures_HustlerReversal(t *testing.T) {
+	prior := mapLogs(t, []string{
+		"Hustler Fund. You have been disbursed Ksh1,000.00 to your account",
+	})
+	reversed := mapLogs(t, []string{
+		"Hustler Fund. You have been disbursed Ksh1,000.00 to your account",
+		"Hustler Fund. You have been disbursed Ksh500.00 to your account",
+		"Hustler Fund: Your loan disbursement of Ksh500.00 has been reversed.",
+	})
+
+	if reversed[0] != prior[0] {
+		t.Errorf("total_income = %v after reversal, want prior %v", reversed[0], prior[0])
+	}
+	if reversed[13] != prior[13] {
+		t.Errorf("hustler_balance = %v after reversal, want prior %v", reversed[13], prior[13])
+	}
+}
+
+func TestMapFeatures_ReversalPendingDoesNotNet(t *testing.T) {
+	logs := []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA00WATER1 Confirmed. Ksh800.00 paid to NAIROBI WATER Account 12345",
+		"Reversal of your payment to NAIROBI WATER of Ksh800 is being processed",
+		"Your payment to NAIROBI WATER of Ksh800 has been reversed",
+	}
+
+	if pending := mapLogs(t, logs[:3]); pending[1] != 800 {
+		t.Errorf("total_expenses = %v with reversal pending, want 800", pending[1])
+	}
+	if completed := mapLogs(t, logs); completed[1] != 0 {
+		t.Errorf("total_expenses = %v after reversal completed, want 0", completed[1])
+	}
+}
+
+func TestMapFeatures_OkoaReversal(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"You have received Ksh50 Okoa Jahazi airtime credit",
+		"Your Okoa Jahazi advance of Ksh50 has been reversed",
+	})
+
+	if features[14] != 0 {
+		t.Errorf("okoa_frequency = %v after reversal, want 0", features[14])
+	}
+	if features[17] != 0 {
+		t.Errorf("emergency_reliance = %v after reversal, want 0", features[17])
+	}
+	if features[0] != 1500 {
+		t.Errorf("total_income = %v after reversal, want 1500", features[0])
+	}
+}
+
+func TestMapFeatures_UtilityPaybill(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want float64
+	}{
+		{"KPLC", "UA00KPLC01 Confirmed. Ksh1,000.00 paid to KPLC Account 12345", 1},
+		{"generic paybill", "UA00SCHL01 Confirmed. Ksh1,000.00 paid to STAREHE SCHOOL Account 12345", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features := mapLogs(t, []string{tt.log})
+			if features[1] != 1000 {
+				t.Errorf("total_expenses = %v, want 1000", features[1])
+			}
+			if features[7] != tt.want {
+				t.Errorf("utility_ratio = %v, want %v", features[7], tt.want)
+			}
+		})
+	}
+}
+
+func TestMapFeatures_TotalFees(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432. New M-PESA balance is Ksh1,000.00. Transaction cost, Ksh7.00.",
+		"UA34ABCDEF Confirmed.on 1/2/26 at 1:00 PMWithdraw Ksh1,000.00 from 654321 - MAMA MBOGA SHOP New M-PESA balance is Ksh0.00. Transaction cost, Ksh29.00.",
+	})
+
+	if features[36] != 36 {
+		t.Errorf("total_fees = %v, want 36", features[36])
+	}
+	if features[1] != 500 {
+		t.Errorf("total_expenses = %v, want 500 without fees", features[1])
+	}
+}
+
+func TestMapFeatures_PaybillReversal(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA00WATER1 Confirmed. Ksh800.00 paid to NAIROBI WATER Account 12345",
+		"Your payment to NAIROBI WATER of Ksh800 has been reversed",
+	})
+
+	if features[1] != 0 {
+		t.Errorf("total_expenses = %v after reversal, want 0", features[1])
+	}
+	if features[7] != 0 {
+		t.Errorf("utility_ratio = %v after reversal, want 0", features[7])
+	}
+}
+
+func TestMapFeatures_HustlerSplitDisbursement(t *testing.T) {
+	features := mapLogs(t, []string{
+		"You have received Ksh950.00 as Hustler Fund loan disbursed to your M-PESA and Ksh50.00 to your savings.",
+	})
+
+	if features[0] != 950 {
+		t.Errorf("total_income = %v, want 950 (wallet portion only)", features[0])
+	}
+	if features[13] != 1000 {
+		t.Errorf("hustler_balance = %v, want 1000 (full principal)", features[13])
+	}
+	if want := 50.0 / 950.0; !almostEqual(features[18], want, floatEpsilon) {
+		t.Errorf("savings_rate = %v, want %v", features[18], want)
+	}
+}
+
+func TestMapFeatures_HustlerSplitReversal(t *testing.T) {
+	prior := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,000.00 from JOHN DOE 0712345678",
+	})
+	split := "You have received Ksh950.00 as Hustler Fund loan disbursed to your M-PESA and Ksh50.00 to your savings."
+
+	// The lender may quote either the wallet portion or the full principal
+	for _, amount := range []string{"950.00", "1,000.00"} {
+		features := mapLogs(t, []string{
+			"UA34ABCDEF Confirmed. You have received Ksh1,000.00 from JOHN DOE 0712345678",
+			split,
+			"Hustler Fund: Your loan disbursement of Ksh" + amount + " has been reversed.",
+		})
+		if features[0] != prior[0] {
+			t.Errorf("reversing Ksh%s: total_income = %v, want prior %v", amount, features[0], prior[0])
+		}
+		if features[13] != prior[13] {
+			t.Errorf("reversing Ksh%s: hustler_balance = %v, want prior %v", amount, features[13], prior[13])
+		}
+		if features[18] != prior[18] {
+			t.Errorf("reversing Ksh%s: savings_rate = %v, want prior %v", amount, features[18], prior[18])
+		}
+	}
+}
+
+func TestMapFeatures_TillReversal(t *testing.T) {
+	txns := parseLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA00TILL01 Confirmed. Ksh200.00 paid to SUPERMARKET Till Number 123456 on 1/2/26",
+		"Your payment of Ksh200.00 to till 123456 has been reversed",
+	})
+
+	features := MapFeatures(txns)
+	if features[1] != 0 {
+		t.Errorf("total_expenses = %v after reversal, want 0", features[1])
+	}
+
+	buyGoods := GroupByType(txns)[parser.TxnMPesaBuyGoods.String()]
+	if buyGoods.Count != 2 || buyGoods.Total != 0 {
+		t.Errorf("buy goods group = {Count: %d, Total: %v}, want {Count: 2, Total: 0}", buyGoods.Count, buyGoods.Total)
+	}
+	if _, ok := GroupByType(txns)[parser.TxnMPesaPaybill.String()]; ok {
+		t.Error("till payment or reversal grouped as paybill")
+	}
+}
+
+func TestMapFeatures_AirtelReversal(t *testing.T) {
+	features := mapLogs(t, []string{
+		"Transaction ID: AM12345678. You have received Ksh1,000.00 from JOHN DOE",
+		"Airtel Money: Ksh1,000.00 received from JOHN DOE has been reversed.",
+	})
+
+	if features[0] != 0 {
+		t.Errorf("total_income = %v after reversal, want 0", features[0])
+	}
+	if features[15] != 0 {
+		t.Errorf("airtel_volume = %v after reversal, want 0", features[15])
+	}
+}
+
+func TestMapFeatures_TKashReversal(t *testing.T) {
+	features := mapLogs(t, []string{
+		"T-Kash: You have received Ksh2,000.00 from JOHN DOE",
+		"T-Kash: Ksh500.00 sent to JANE DOE",
+		"T-Kash: Reversal of Ksh500.00 sent to JANE DOE completed.",
+	})
+
+	if features[1] != 0 {
+		t.Errorf("total_expenses = %v after reversal, want 0", features[1])
+	}
+	if features[10] != 0 {
+		t.Errorf("p2p_ratio = %v after reversal, want 0", features[10])
+	}
+	if features[0] != 2000 {
+		t.Errorf("total_income = %v, want 2000", features[0])
+	}
+}
+
+func TestMapFeatures_MPesaReversal(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh500.00 from JOHN DOE 0712345678",
+		"UA9REV0001 Confirmed. Transaction UA34ABCDEF has been reversed. Ksh500.00 has been debited from your M-PESA account.",
+		"UA78EFGHIJ Confirmed. Ksh300.00 sent to JANE DOE 0798765432",
+		"UA9REV0002 Confirmed. Transaction UA78EFGHIJ has been reversed. Ksh300.00 is credited to your M-PESA account.",
+	})
+
+	if features[0] != 0 {
+		t.Errorf("total_income = %v after reversal, want 0", features[0])
+	}
+	if features[1] != 0 {
+		t.Errorf("total_expenses = %v after reversal, want 0", features[1])
+	}
+}
+
+func TestMapFeatures_SalaryViaPaybill(t *testing.T) {
+	features := mapLogs(t, []string{
+		"Ksh5,000 received from PAYBILL 400200 SALARY",
+	})
+
+	if features[0] != 5000 {
+		t.Errorf("total_income = %v, want 5000", features[0])
+	}
+	if features[1] != 0 {
+		t.Errorf("total_expenses = %v, want 0", features[1])
+	}
+	if features[21] != 1 {
+		t.Errorf("institutional_income_ratio = %v, want 1", features[21])
+	}
+}
+
+func TestMapFeatures_FailedBankTransfer(t *testing.T) {
+	txns := parseLogs(t, []string{
+		"QKJ3XPYC5T Confirmed. You have received Ksh15,000.00 from SARAH JANE",
+		"Transferred Ksh10,000.00 to Equity Bank account 0123",
+		"Your transfer to EQUITY of Ksh10,000 failed and was reversed",
+	})
+
+	features := MapFeatures(txns)
+	if features[1] != 0 {
+		t.Errorf("total_expenses = %v after reversal, want 0", features[1])
+	}
+	if features[19] != 0 {
+		t.Errorf("bank_activity = %v after reversal, want 0", features[19])
+	}
+	if deposits := GroupByType(txns)[parser.TxnBankDeposit.String()]; deposits.Total != 0 {
+		t.Errorf("bank deposit group Total = %v, want 0", deposits.Total)
+	}
+}
+
+func TestEstimatedNetIncome(t *testing.T) {
+	txns := parseLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"QKJ3XPYC5T Confirmed. You have received Ksh15,000.00 from SARAH JANE",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00 from your limit",
+		"Hustler Fund. You have been disbursed Ksh500.00 to your account",
+		"You have received Ksh50 Okoa Jahazi airtime credit",
+		"Disbursed Ksh3,000.00 from Branch to your M-Pesa",
+	})
+
+	if got, want := EstimatedNetIncome(txns), 16500.0; got != want {
+		t.Errorf("EstimatedNetIncome() = %v, want %v", got, want)
+	}
+	if totalIncome := MapFeatures(txns)[0]; totalIncome != 22050 {
+		t.Errorf("total_income = %v, want 22050 including loans", totalIncome)
+	}
+}
+
+func TestNetOutstandingDebt_RepaymentReversal(t *testing.T) {
+	logs := []string{
+		"Disbursed Ksh5,000.00 from Tala to your M-Pesa",
+		"Ksh2,000.00 paid to Tala",
+		"Your payment of Ksh2,000.00 to Tala has been reversed",
+	}
+
+	before := NetOutstandingDebt(parseLogs(t, logs[:1]))
+	if before != 5000 {
+		t.Fatalf("NetOutstandingDebt() = %v before repaying, want 5000", before)
+	}
+	if repaid := NetOutstandingDebt(parseLogs(t, logs[:2])); repaid != 3000 {
+		t.Errorf("NetOutstandingDebt() = %v after repaying, want 3000", repaid)
+	}
+	if reversed := NetOutstandingDebt(parseLogs(t, logs)); reversed != before {
+		t.Errorf("NetOutstandingDebt() = %v after the repayment reversed, want %v", reversed, before)
+	}
+
+	features := mapLogs(t, logs)
+	if features[1] != 0 || features[23] != 0 {
+		t.Errorf("total_expenses, debt_service_ratio = %v, %v after reversal, want 0, 0", features[1], features[23])
+	}
+}
+
+func TestMapFeatures_BankLoanRepayIsDebtService(t *testing.T) {
+	features := mapLogs(t, []string{
+		"QKJ3XPYC5T Confirmed. You have received Ksh20,000.00 from SARAH JANE",
+		"KCB loan instalment of Ksh5,000 deducted from your account",
+	})
+
+	if !almostEqual(features[23], 0.25, floatEpsilon) {
+		t.Errorf("debt_service_ratio = %v, want 0.25", features[23])
+	}
+	if features[19] != 0 {
+		t.Errorf("bank_activity = %v, want 0 for a loan instalment", features[19])
+	}
+	if features[1] != 5000 {
+		t.Errorf("total_expenses = %v, want 5000", features[1])
+	}
+}
+
+func TestMapFeaturesWithConfig_ExactCents(t *testing.T) {
+	txns := make([]parser.Transaction, 10000)
+	for i := range txns {
+		txns[i] = parser.Transaction{Type: parser.TxnMPesaReceived, Amount: 0.10}
+	}
+
+	// Naive float summation of 0.10 drifts away from 1000
+	if naive := MapFeatures(txns)[0]; naive == 1000 {
+		t.Fatalf("float path total_income = %v, expected drift for this fixture", naive)
+	}
+
+	features := MapFeaturesWithConfig(txns, MapperConfig{ExactCents: true})
+	if features[0] != 1000 {
+		t.Errorf("total_income = %v, want exactly 1000", features[0])
+	}
+}
+
+func TestMapFeatures_FulizaLimitReached(t *testing.T) {
+	features := mapLogs(t, []string{
+		"Transaction failed. Fuliza limit reached. Please repay your outstanding Fuliza M-PESA",
+		"Transaction failed. Fuliza limit reached. Please repay your outstanding Fuliza M-PESA",
+	})
+
+	if features[24] != 2 {
+		t.Errorf("fuliza_limit_reached_count = %v, want 2", features[24])
+	}
+	for _, idx := range []int{0, 1, 4, 8} {
+		if features[idx] != 0 {
+			t.Errorf("%s = %v, want 0 for failed payments", FeatureNames[idx], features[idx])
+		}
+	}
+}
+
+func TestMapFeatures_GiftedAirtimeNotIncome(t *testing.T) {
+	features := mapLogs(t, []string{
+		"JOHN DOE has bought you Ksh100 airtime",
+		"JOHN DOE has bought you Ksh100 airtime",
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+	})
+
+	if features[25] != 2 {
+		t.Errorf("gifted_airtime_count = %v, want 2", features[25])
+	}
+	if features[0] != 1500 {
+		t.Errorf("total_income = %v, want 1500 excluding airtime", features[0])
+	}
+}
+
+func TestMapFeatures_AirtimePurchaseIsExpense(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"Bought Ksh100.00 of airtime",
+		"You have bought Ksh50.00 airtime for 0712345678",
+	})
+
+	if features[1] != 150 {
+		t.Errorf("total_expenses = %v, want 150 of airtime", features[1])
+	}
+	if features[25] != 0 {
+		t.Errorf("gifted_airtime_count = %v, want 0 for bought airtime", features[25])
+	}
+}
+
+func TestMapFeatures_KCBLoanIsDebt(t *testing.T) {
+	features := mapLogs(t, []string{
+		"KCB M-PESA. You have deposited Ksh2,000.00",
+		"KCB M-PESA loan of Ksh3,000 disbursed to your M-PESA account",
+		"You have repaid Ksh1,000.00 of your KCB M-PESA loan",
+	})
+
+	if features[0] != 3000 {
+		t.Errorf("total_income = %v, want 3000 from the loan only", features[0])
+	}
+	if features[16] != 1 {
+		t.Errorf("lender_diversity = %v, want 1", features[16])
+	}
+	if features[18] == 0 {
+		t.Error("savings_rate = 0, want the KCB M-PESA deposit counted as savings")
+	}
+}
+
+func TestMapFeatures_OnTimeRepaymentRatio(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, d) }
+	txns := []parser.Transaction{
+		{Type: parser.TxnDigitalLoan, Amount: 5000, Lender: "Tala", Timestamp: day(0)},
+		{Type: parser.TxnHustlerLoan, Amount: 500, Timestamp: day(1)},
+		// Tala repaid in two parts, settled on day 20 of a 30 day term
+		{Type: parser.TxnDigitalRepay, Amount: 2000, Lender: "Tala", Timestamp: day(10)},
+		{Type: parser.TxnDigitalRepay, Amount: 3000, Lender: "Tala", Timestamp: day(20)},
+		// Hustler Fund repaid 29 days after a 14 day loan
+		{Type: parser.TxnHustlerRepay, Amount: 500, Timestamp: day(30)},
+	}
+
+	if got := MapFeatures(txns)[26]; got != 0.5 {
+		t.Errorf("ontime_repayment_ratio = %v, want 0.5 (one on time, one late)", got)
+	}
+
+	onTimeOnly := MapFeatures(txns[:4])[26]
+	if onTimeOnly != 1 {
+		t.Errorf("ontime_repayment_ratio = %v, want 1 with only the Tala loan settled", onTimeOnly)
+	}
+}
+
+func TestMapFeatures_OnTimeRepaymentRatioNeutral(t *testing.T) {
+	// SMS carry no timestamps, so nothing can be paired
+	features := mapLogs(t, []string{
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+		"Fuliza M-PESA. You have repaid Ksh2,000.00",
+	})
+	if features[26] != neutralOnTimeRatio {
+		t.Errorf("ontime_repayment_ratio = %v, want neutral %v", features[26], neutralOnTimeRatio)
+	}
+}
+
+func TestMapFeatures_PostIncomeDrawdown(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, d) }
+	spentAtOnce := []parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 30000, Institutional: true, Timestamp: day(0)},
+		{Type: parser.TxnMPesaSent, Amount: 20000, Timestamp: day(0).Add(time.Hour)},
+		{Type: parser.TxnMPesaPaybill, Amount: 8000, Timestamp: day(1)},
+		{Type: parser.TxnGamblingStake, Amount: 2000, Timestamp: day(2)},
+	}
+	if got := MapFeatures(spentAtOnce)[32]; !almostEqual(got, 1, floatEpsilon) {
+		t.Errorf("post_income_drawdown_ratio = %v, want 1 for salary spent within days", got)
+	}
+
+	// Same outflows, but the first lands after the window and savings are not spending
+	retained := []parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 30000, Institutional: true, Timestamp: day(0)},
+		{Type: parser.TxnMMFDeposit, Amount: 20000, Timestamp: day(0).Add(time.Hour)},
+		{Type: parser.TxnMPesaPaybill, Amount: 3000, Timestamp: day(1)},
+		{Type: parser.TxnMPesaSent, Amount: 7000, Timestamp: day(10)},
+	}
+	if got := MapFeatures(retained)[32]; !almostEqual(got, 0.1, floatEpsilon) {
+		t.Errorf("post_income_drawdown_ratio = %v, want 0.1", got)
+	}
+
+	// Airtime bought from the wallet is spending too
+	airtime := []parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 1000, Timestamp: day(0)},
+		{Type: parser.TxnAirtime, Amount: 250, Timestamp: day(1)},
+	}
+	if got := MapFeatures(airtime)[32]; !almostEqual(got, 0.25, floatEpsilon) {
+		t.Errorf("post_income_drawdown_ratio = %v, want 0.25 with airtime spend", got)
+	}
+}
+
+func TestMapFeatures_AgentCashIsNeutral(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, d) }
+	features := MapFeatures([]parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 10000, Timestamp: day(0)},
+		{Type: parser.TxnAgentDeposit, Amount: 2000, Timestamp: day(0)},
+		{Type: parser.TxnAgentWithdraw, Amount: 5000, Timestamp: day(1)},
+	})
+
+	if features[0] != 10000 || features[1] != 0 {
+		t.Errorf("income, expenses = %v, %v, want 10000, 0 for cash in and out", features[0], features[1])
+	}
+	if !almostEqual(features[32], 0.5, floatEpsilon) {
+		t.Errorf("post_income_drawdown_ratio = %v, want 0.5 from the cash out", features[32])
+	}
+}
+
+func TestMapFeatures_PostIncomeDrawdownNeutral(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA78EFGHIJ Confirmed. Ksh1,500.00 sent to JANE DOE 0798765432",
+	})
+	if features[32] != neutralDrawdownRatio {
+		t.Errorf("post_income_drawdown_ratio = %v, want neutral %v", features[32], neutralDrawdownRatio)
+	}
+}
+
+func TestMapFeatures_IncomeChannelDiversity(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"QKJ3XPYC5T Confirmed. You have received Ksh3,000.00 from SARAH JANE",
+		"UA76ZYXWVU Confirmed. Ksh5,000.00 received from PAYBILL 400200 SALARY",
+		"M-Shwari. You have withdrawn Ksh2,000.00 from your savings",
+		// Borrowing is not an income channel
+		"You have received Ksh5,000.00 from Tala",
+		"Fuliza M-PESA. You have borrowed Ksh1,000.00",
+	})
+
+	if features[33] != 3 {
+		t.Errorf("income_channel_diversity = %v, want 3 (M-Pesa, paybill salary, M-Shwari)", features[33])
+	}
+}
+
+func TestMapFeatures_FulizaDependencyRatio(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA00KPLC01 Confirmed. Ksh1,000.00 paid to KPLC. Fuliza M-PESA amount is Ksh300.00",
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432 using Fuliza M-PESA",
+		"UA00WATER1 Confirmed. Ksh800.00 paid to NAIROBI WATER. Fuliza M-PESA amount is Ksh800.00",
+		"UA00SEND01 Confirmed. Ksh200.00 sent to JOHN DOE 0712345678",
+	})
+
+	if features[34] != 0.75 {
+		t.Errorf("fuliza_dependency_ratio = %v, want 0.75 (3 of 4 payments needed Fuliza)", features[34])
+	}
+}
+
+func TestMapFeatures_CounterpartyDiversity(t *testing.T) {
+	txns := []parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 1000, Sender: "JOHN DOE"},
+		{Type: parser.TxnMPesaSent, Amount: 200, Recipient: "John  Doe"},
+		{Type: parser.TxnMPesaSent, Amount: 300, Recipient: "DOE JOHN"},
+		{Type: parser.TxnMPesaReceived, Amount: 500, Sender: "JANE WANJIKU"},
+	}
+
+	if got := MapFeatures(txns)[35]; got != 3 {
+		t.Errorf("counterparty_diversity = %v, want 3 with case and spacing ignored", got)
+	}
+	sorted := MapFeaturesWithConfig(txns, MapperConfig{NameNormalization: NormalizeTokenSort})
+	if sorted[35] != 2 {
+		t.Errorf("counterparty_diversity = %v with token sort, want 2", sorted[35])
+	}
+}
+
+func TestMapFeatures_DaysActive(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678 on 20/1/26 at 9:00 AM.",
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432 on 20/1/26 at 3:45 PM.",
+		"UA00KPLC01 Confirmed. Ksh1,000.00 paid to KPLC Account 12345 on 21/1/26 at 8:00 AM.",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00 on 23/1/26.",
+	})
+	if features[12] != 3 {
+		t.Errorf("days_active = %v, want 3 distinct dates", features[12])
+	}
+
+	undated := mapLogs(t, []string{
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+	})
+	if undated[12] != 2 {
+		t.Errorf("days_active = %v without dates, want the transaction count 2", undated[12])
+	}
+}
+
+func TestMapFeatures_Digest(t *testing.T) {
+	// A digest expands to several entries, so parseLogs' one-per-log check does not apply
+	txns, err := parser.NewParser().ParseLogs(context.Background(), []string{
+		"Today: 3 deposits totaling Ksh12,000, 2 withdrawals totaling Ksh4,000",
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+	})
+	if err != nil {
+		t.Fatalf("ParseLogs() error = %v", err)
+	}
+
+	features := MapFeatures(txns)
+	if features[0] != 12000 {
+		t.Errorf("total_income = %v, want 12000 from the digest", features[0])
+	}
+	if features[1] != 4500 {
+		t.Errorf("total_expenses = %v, want 4500 (digest 4000 + send 500)", features[1])
+	}
+
+	strict := MapFeaturesWithConfig(txns, MapperConfig{MinConfidence: parser.ConfidenceExact})
+	if strict[0] != 0 || strict[1] != 500 {
+		t.Errorf("income, expenses = %v, %v with MinConfidence exact, want 0, 500 without the digest", strict[0], strict[1])
+	}
+}
+
+func TestVectorizeFromChannel(t *testing.T) {
+	txns := parseLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+		"Betika: Your bet of Ksh100.00 has been placed",
+		"UA00STEAD3 Confirmed. Ksh1,000.00 paid to KPLC Account 12345",
+	})
+
+	ch := make(chan parser.Transaction)
+	go func() {
+		defer close(ch)
+		for _, txn := range txns {
+			ch <- txn
+		}
+	}()
+
+	got, err := VectorizeFromChannel(context.Background(), ch)
+	if err != nil {
+		t.Fatalf("VectorizeFromChannel() error = %v", err)
+	}
+	want := MapFeatures(txns)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s = %v, want %v", FeatureNames[i], got[i], want[i])
+		}
+	}
+}
+
+func TestVectorizeFromChannel_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The channel is never closed, so only cancellation can end the call
+	if _, err := VectorizeFromChannel(ctx, make(chan parser.Transaction)); err != context.Canceled {
+		t.Errorf("VectorizeFromChannel() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestMapFeatures_MinBalance(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678 on 1/2/26. New M-PESA balance is Ksh1,620.00.",
+		"UA78EFGHIJ Confirmed. Ksh1,500.00 sent to JANE DOE 0798765432 on 2/2/26. New M-PESA balance is Ksh120.00.",
+		"UA00WATER1 Confirmed. Ksh100.00 paid to NAIROBI WATER Account 12345 on 3/2/26. New M-PESA balance is Ksh20.00.",
+		"QKJ3XPYC5T Confirmed. You have received Ksh5,000.00 from SARAH JANE on 4/2/26. New M-PESA balance is Ksh5,020.00.",
+		"Hustler Fund. Your loan balance is Ksh5.00",
+	})
+
+	if features[27] != 20 {
+		t.Errorf("min_balance = %v, want 20", features[27])
+	}
+	if features[28] != 0 {
+		t.Errorf("no_balance_data = %v, want 0", features[28])
+	}
+}
+
+func TestMapFeatures_ZeroBalance(t *testing.T) {
+	// An emptied wallet is the lowest balance, not a missing one
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678 on 1/2/26. New M-PESA balance is Ksh1,500.00.",
+		"UA78EFGHIJ Confirmed. Ksh1,500.00 sent to JANE DOE 0798765432 on 2/2/26. New M-PESA balance is Ksh0.00.",
+	})
+	if features[27] != 0 {
+		t.Errorf("min_balance = %v, want 0", features[27])
+	}
+	if features[28] != 0 {
+		t.Errorf("no_balance_data = %v, want 0", features[28])
+	}
+
+	// The empty wallet alone still counts as balance data
+	if only := mapLogs(t, []string{
+		"UA78EFGHIJ Confirmed. Ksh1,500.00 sent to JANE DOE 0798765432 on 2/2/26. New M-PESA balance is Ksh0.00.",
+	}); only[28] != 0 {
+		t.Errorf("no_balance_data = %v with a Ksh0.00 balance, want 0", only[28])
+	}
+}
+
+func TestMapFeatures_NoBalanceData(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+	})
+
+	if features[27] != 0 {
+		t.Errorf("min_balance = %v, want 0", features[27])
+	}
+	if features[28] != 1 {
+		t.Errorf("no_balance_data = %v, want 1", features[28])
+	}
+}
+
+func TestMapFeatures_GamblingCount(t *testing.T) {
+	income := "UA34ABCDEF Confirmed. You have received Ksh5,000.00 from JOHN DOE 0712345678"
+	small := []string{income}
+	for range 5 {
+		small = append(small, "Betika: Your bet of Ksh100.00 has been placed")
+	}
+	frequent := mapLogs(t, small)
+	single := mapLogs(t, []string{income, "Betika: Your bet of Ksh500.00 has been placed"})
+
+	if frequent[37] != 5 {
+		t.Errorf("gambling_count = %v, want 5", frequent[37])
+	}
+	if single[37] != 1 {
+		t.Errorf("gambling_count = %v for one bet, want 1", single[37])
+	}
+	// The same total stake gives the same spend ratio however it is split
+	if frequent[6] != single[6] || frequent[6] != 1 {
+		t.Errorf("gambling_index = %v (five bets), %v (one bet), want 1 for both", frequent[6], single[6])
+	}
+}
+
+func TestMapFeatures_GamblingWinNetsStakes(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA78EFGHIJ Confirmed. Ksh800.00 sent to JANE DOE 0798765432",
+		"Betika: Your bet of Ksh200.00 has been placed",
+		"Betika: Win! You have received Ksh500.00",
+	})
+
+	if features[0] != 500 {
+		t.Errorf("total_income = %v, want the 500 won", features[0])
+	}
+	if features[1] != 1000 {
+		t.Errorf("total_expenses = %v, want 1000 with the stake", features[1])
+	}
+	if features[6] != 0 || features[29] != 0 {
+		t.Errorf("gambling_index, weighted_gambling_index = %v, %v, want 0 for a net winner", features[6], features[29])
+	}
+	if features[37] != 1 {
+		t.Errorf("gambling_count = %v, want 1 stake", features[37])
+	}
+
+	losing := mapLogs(t, []string{
+		"UA78EFGHIJ Confirmed. Ksh800.00 sent to JANE DOE 0798765432",
+		"Betika: Your bet of Ksh200.00 has been placed",
+		"Betika: Win! You have received Ksh50.00",
+	})
+	if want := 150.0 / 1000; !almostEqual(losing[6], want, floatEpsilon) {
+		t.Errorf("gambling_index = %v, want net loss %v", losing[6], want)
+	}
+}
+
+// recurringLogs are three monthly salaries and one unrelated receipt.
+var recurringLogs = []string{
+	"UA11ABCDEF Confirmed. You have received Ksh30,000.00 from ACME LIMITED on 28/1/26 at 9:00 AM",
+	"UA22ABCDEF Confirmed. You have received Ksh30,000.00 from ACME LIMITED on 27/2/26 at 9:00 AM",
+	"UA33ABCDEF Confirmed. You have received Ksh30,000.00 from ACME LIMITED on 30/3/26 at 9:00 AM",
+	"UA44ABCDEF Confirmed. You have received Ksh10,000.00 from JOHN DOE on 5/3/26 at 9:00 AM",
+}
+
+func TestMapFeatures_RecurringIncomeRatio(t *testing.T) {
+	// Detection runs inside MapFeatures, so callers need not flag receipts
+	txns := parseLogs(t, recurringLogs)
+	features := MapFeatures(txns)
+	if want := 90000.0 / 100000; !almostEqual(features[38], want, floatEpsilon) {
+		t.Errorf("recurring_income_ratio = %v, want %v", features[38], want)
+	}
+	if flagged := MapFeatures(parser.DetectRecurring(txns)); flagged[38] != features[38] {
+		t.Errorf("recurring_income_ratio = %v with receipts already flagged, want %v", flagged[38], features[38])
+	}
+	if single := mapLogs(t, recurringLogs[:1]); single[38] != 0 {
+		t.Errorf("recurring_income_ratio = %v for a single salary, want 0", single[38])
+	}
+}
+
+func TestRecurringIncomeRatio_EntryPointParity(t *testing.T) {
+	txns := parseLogs(t, recurringLogs)
+	want := MapFeatures(txns)[38]
+	if want == 0 {
+		t.Fatal("MapFeatures() found no recurring income")
+	}
+
+	if got := VectorizeNamed(txns).RecurringIncomeRatio; got != want {
+		t.Errorf("VectorizeNamed() recurring_income_ratio = %v, want %v", got, want)
+	}
+	if got, _ := VectorizeWithProvenance(txns); got[38] != want {
+		t.Errorf("VectorizeWithProvenance() recurring_income_ratio = %v, want %v", got[38], want)
+	}
+
+	ch := make(chan parser.Transaction, len(txns))
+	for _, txn := range txns {
+		ch <- txn
+	}
+	close(ch)
+	got, err := VectorizeFromChannel(context.Background(), ch)
+	if err != nil {
+		t.Fatalf("VectorizeFromChannel() error = %v", err)
+	}
+	if got[38] != want {
+		t.Errorf("VectorizeFromChannel() recurring_income_ratio = %v, want %v", got[38], want)
+	}
+}
+
+// syntheticHistory returns n dated transactions spanning several months in
+// chronological order: a monthly salary, daily spending and occasional
+// receipts, with a wallet balance quoted on most messages.
+func syntheticHistory(n int) []parser.Transaction {
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	txns := make([]parser.Transaction, 0, n)
+	balance := 5000.0
+	for i := 0; len(txns) < n; i++ {
+		at := start.Add(time.Duration(i) * 2 * time.Hour)
+		txn := parser.Transaction{Timestamp: at}
+		switch {
+		case at.Day() == 28 && at.Hour() == 8:
+			txn.Type, txn.Amount = parser.TxnMPesaReceived, 30000
+		case rng.Intn(10) == 0:
+			txn.Type, txn.Amount = parser.TxnMPesaReceived, float64(100+rng.Intn(5000))
+		default:
+			txn.Type, txn.Amount = parser.TxnMPesaSent, float64(10+rng.Intn(500))
+			txn.Cost = 7
+		}
+		balance += walletDelta(txn)
+		if rng.Intn(4) != 0 {
+			txn.Balance, txn.HasBalance = balance, true
+		}
+		txns = append(txns, txn)
+	}
+	return txns
+}
+
+func TestVectorizeWithProvenance_AnyOrder(t *testing.T) {
+	chronological := syntheticHistory(600)
+	reversed := slices.Clone(chronological)
+	slices.Reverse(reversed)
+	shuffled := slices.Clone(chronological)
+	rand.New(rand.NewSource(2)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	var volatility runningStats
+	for _, balance := range ReconstructBalanceSeries(chronological) {
+		volatility.add(balance)
+	}
+	var recurring, income float64
+	for _, txn := range parser.DetectRecurring(chronological) {
+		if txn.Type == parser.TxnMPesaReceived {
+			income += txn.Amount
+			if txn.Recurring {
+				recurring += txn.Amount
+			}
+		}
+	}
+	if recurring == 0 {
+		t.Fatal("DetectRecurring() found no recurring income")
+	}
+
+	for name, txns := range map[string][]parser.Transaction{
+		"chronological": chronological,
+		"reversed":      reversed,
+		"shuffled":      shuffled,
+	} {
+		features, _ := VectorizeWithProvenance(txns)
+		if got, want := features[11], volatility.stdDev(); !almostEqual(got, want, 1e-6) {
+			t.Errorf("%s: balance_volatility = %v, want %v", name, got, want)
+		}
+		if got, want := features[38], recurring/income; !almostEqual(got, want, floatEpsilon) {
+			t.Errorf("%s: recurring_income_ratio = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestVectorizeFromChannel_BoundedRecurring(t *testing.T) {
+	txns := syntheticHistory(3000)
+	slices.Reverse(txns) // Exports often list the newest message first
+	want := MapFeatures(txns)[38]
+
+	ch := make(chan parser.Transaction, len(txns))
+	for _, txn := range txns {
+		ch <- txn
+	}
+	close(ch)
+	got, err := VectorizeFromChannel(context.Background(), ch)
+	if err != nil {
+		t.Fatalf("VectorizeFromChannel() error = %v", err)
+	}
+	if got[38] != want {
+		t.Errorf("recurring_income_ratio = %v, want %v", got[38], want)
+	}
+
+	// A year of daily receipts never holds more than a window's worth
+	r := recurringTracker{bounded: true}
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	for d := 0; d < 365; d++ {
+		r.add(parser.Transaction{Type: parser.TxnMPesaReceived, Amount: 1000, Timestamp: start.AddDate(0, 0, d)})
+		if limit := int(parser.RecurringWindow/(24*time.Hour)) + 1; len(r.held) > limit {
+			t.Fatalf("day %d: %d receipts held, want at most %d", d, len(r.held), limit)
+		}
+	}
+}
+
+func BenchmarkVectorizeWithProvenance(b *testing.B) {
+	txns := syntheticHistory(4000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		VectorizeWithProvenance(txns)
+	}
+}
+
+func TestMapFeatures_BankAccountTransfers(t *testing.T) {
+	features := mapLogs(t, []string{
+		"Equitel: You have received Ksh5,000.00 from JOHN DOE 0712345678",
+		"KCB: Ksh2,000.00 has been debited from your account ending 1234 to JANE DOE",
+	})
+
+	if features[0] != 5000 || features[1] != 2000 {
+		t.Errorf("total_income, total_expenses = %v, %v, want 5000, 2000", features[0], features[1])
+	}
+	if features[19] != 2 {
+		t.Errorf("bank_activity = %v, want 2", features[19])
+	}
+	if features[33] != 1 {
+		t.Errorf("income_channel_diversity = %v, want 1 (bank)", features[33])
+	}
+}
+
+func TestMapFeatures_RemittanceRatio(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh30,000.00 from WorldRemit",
+		"You have received KES 10,000.00 from Remitly via M-PESA Global",
+		"UA78ABCDEF Confirmed. You have received Ksh10,000.00 from JOHN DOE 0712345678",
+	})
+
+	if features[0] != 50000 {
+		t.Errorf("total_income = %v, want 50000", features[0])
+	}
+	if features[39] != 0.8 {
+		t.Errorf("remittance_ratio = %v, want 0.8", features[39])
+	}
+	if features[33] != 2 {
+		t.Errorf("income_channel_diversity = %v, want 2 (mpesa, remittance)", features[33])
+	}
+}
+
+func TestMapFeatures_DigitalRepayRate(t *testing.T) {
+	features := MapFeatures([]parser.Transaction{
+		{Type: parser.TxnDigitalLoan, Amount: 5000, Lender: "Tala"},
+		{Type: parser.TxnDigitalRepay, Amount: 5000, Lender: "Tala"},
+	})
+	if features[40] != 1.0 {
+		t.Errorf("digital_repay_rate = %v, want 1.0", features[40])
+	}
+
+	// Repaying a loan from before the window is capped
+	if capped := MapFeatures([]parser.Transaction{
+		{Type: parser.TxnDigitalLoan, Amount: 1000, Lender: "Branch"},
+		{Type: parser.TxnDigitalRepay, Amount: 5000, Lender: "Tala"},
+	}); capped[40] != 2 {
+		t.Errorf("digital_repay_rate = %v, want 2", capped[40])
+	}
+}
+
+func TestMapFeaturesWithConfig_GamblingWeights(t *testing.T) {
+	txns := parseLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh5,000.00 from JOHN DOE 0712345678",
+		"UA78EFGHIJ Confirmed. Ksh800.00 sent to JANE DOE 0798765432",
+		"Betika: Your bet of Ksh100.00 has been placed",
+		"SportPesa: Your bet of Ksh100.00 has been placed",
+	})
+
+	unweighted := MapFeatures(txns)
+	if unweighted[29] != unweighted[6] {
+		t.Errorf("weighted_gambling_index = %v with default weights, want gambling_index %v", unweighted[29], unweighted[6])
+	}
+
+	weighted := MapFeaturesWithConfig(txns, MapperConfig{
+		GamblingWeights: map[string]float64{"betika": 3},
+	})
+	if want := 400.0 / 1000.0; !almostEqual(weighted[29], want, floatEpsilon) {
+		t.Errorf("weighted_gambling_index = %v, want %v", weighted[29], want)
+	}
+	if weighted[6] != unweighted[6] {
+		t.Errorf("gambling_index = %v, want unchanged %v", weighted[6], unweighted[6])
+	}
+}
+
+func TestMapFeatures_RepaymentExpenseRatio(t *testing.T) {
+	debtHeavy := mapLogs(t, []string{
+		"QKJ3XPYC5T Confirmed. You have received Ksh10,000.00 from SARAH JANE",
+		"Fuliza M-PESA. You have repaid Ksh3,000.00",
+		"Ksh1,000.00 received by Tala",
+		"UA78EFGHIJ Confirmed. Ksh1,000.00 sent to JANE DOE 0798765432",
+	})
+	if want := 4000.0 / 5000.0; !almostEqual(debtHeavy[30], want, floatEpsilon) {
+		t.Errorf("debt-heavy repayment_expense_ratio = %v, want %v", debtHeavy[30], want)
+	}
+
+	debtFree := mapLogs(t, []string{
+		"QKJ3XPYC5T Confirmed. You have received Ksh10,000.00 from SARAH JANE",
+		"UA78EFGHIJ Confirmed. Ksh1,000.00 sent to JANE DOE 0798765432",
+	})
+	if debtFree[30] != 0 {
+		t.Errorf("debt-free repayment_expense_ratio = %v, want 0", debtFree[30])
+	}
+}
+
+func TestMapFeaturesWithConfig_ReferenceTime(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	txns := []parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 1500, Timestamp: day(1)},
+		{Type: parser.TxnMPesaSent, Amount: 500, Timestamp: day(5)},
+		{Type: parser.TxnMPesaReceived, Amount: 2000, Timestamp: day(10)},
+	}
+
+	// Without a reference time, recency is measured from the latest transaction
+	if got := MapFeatures(txns)[31]; got != 0 {
+		t.Errorf("days_since_last_income = %v, want 0", got)
+	}
+
+	asOfJan8 := MapFeaturesWithConfig(txns, MapperConfig{ReferenceTime: day(8)})
+	if asOfJan8[31] != 7 {
+		t.Errorf("days_since_last_income as of Jan 8 = %v, want 7", asOfJan8[31])
+	}
+	if asOfJan8[0] != 1500 {
+		t.Errorf("total_income as of Jan 8 = %v, want 1500 (later receipt excluded)", asOfJan8[0])
+	}
+
+	asOfJan20 := MapFeaturesWithConfig(txns, MapperConfig{ReferenceTime: day(20)})
+	if asOfJan20[31] != 10 {
+		t.Errorf("days_since_last_income as of Jan 20 = %v, want 10", asOfJan20[31])
+	}
+}
+
+func TestMapFeatures_DaysSinceLastIncomeWithoutIncome(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	// No income at all must not read as paid today
+	short := MapFeatures([]parser.Transaction{
+		{Type: parser.TxnMPesaSent, Amount: 500, Timestamp: day(1)},
+		{Type: parser.TxnMPesaPaybill, Amount: 200, Timestamp: day(3)},
+	})
+	if short[31] != noIncomeDays {
+		t.Errorf("days_since_last_income = %v over 2 days without income, want %v", short[31], noIncomeDays)
+	}
+
+	// A longer history without income reports its full length
+	long := MapFeaturesWithConfig([]parser.Transaction{
+		{Type: parser.TxnMPesaSent, Amount: 500, Timestamp: day(1)},
+		{Type: parser.TxnMPesaReceived, Amount: 900}, // Undated
+	}, MapperConfig{ReferenceTime: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)})
+	if long[31] != 60 {
+		t.Errorf("days_since_last_income = %v over 60 days without dated income, want 60", long[31])
+	}
+
+	if undated := mapLogs(t, []string{"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432"}); undated[31] != noIncomeDays {
+		t.Errorf("days_since_last_income = %v without dates, want %v", undated[31], noIncomeDays)
+	}
+}
+
+// goldenLogs is a canonical fixture touching most feature families.
+var goldenLogs = []string{
+	"UA34ABCDEF Confirmed. You have received Ksh12,000.00 from SAFARICOM LIMITED 123456 on 1/2/26. New M-PESA balance is Ksh12,500.00.",
+	"UA00SEND01 Confirmed. Ksh1,500.00 sent to JANE DOE 0798765432 on 2/2/26. New M-PESA balance is Ksh11,000.00.",
+	"UA00KPLC01 Confirmed. Ksh2,000.00 paid to KPLC Account 12345",
+	"Fuliza M-PESA. You have borrowed Ksh1,000.00",
+	"Fuliza M-PESA. You have repaid Ksh500.00",
+	"Betika: Your bet of Ksh200.00 has been placed",
+	"M-Shwari. You have deposited Ksh1,000.00 to your savings",
+	"You have received Ksh5,000.00 from Tala",
+	"QKJ3XPYC5T Confirmed. You have received Ksh3,000.00 from SARAH JANE",
+}
+
+func TestMapFeatures_Golden(t *testing.T) {
+	// Income 12,000 + 1,000 Fuliza + 5,000 Tala + 3,000 = 21,000.
+	// Expenses 1,500 + 2,000 + 500 + 200 + 1,000 = 5,200.
+	want := [FeatureCount]float64{
+		0:  21000,
+		1:  5200,
+		2:  21000.0 / 5200,
+		3:  9,
+		4:  12000,
+		5:  0.6, // CV of {12000, 3000}
+		6:  200.0 / 5200,
+		7:  2000.0 / 5200, // KPLC
+		8:  1000.0 / 21000,
+		9:  0.5,
+		10: 1500.0 / 5200,
+		11: 750, // Balances of 12,500 then 11,000
+		12: 2,   // 1/2/26 and 2/2/26
+		16: 1,
+		17: 1000.0 / 21000,
+		18: 1000.0 / 21000,
+		21: 12000.0 / 21000,
+		22: 0.627877989127481,
+		23: 500.0 / 21000,
+		26: 0.5,
+		27: 11000,
+		29: 200.0 / 5200,
+		30: 500.0 / 5200,
+		31: 1, // Income on 1/2/26, latest message on 2/2/26
+		32: 1500.0 / 12000,
+		33: 2, // Business payout and M-Pesa receipt
+		35: 3, // SAFARICOM LIMITED, JANE DOE, SARAH JANE
+		37: 1,
+	}
+
+	got := mapLogs(t, goldenLogs)
+	for i := range want {
+		if !almostEqual(got[i], want[i], floatEpsilon) {
+			t.Errorf("%s = %v, want %v", FeatureNames[i], got[i], want[i])
+		}
+	}
+}
+
+func TestMapFeaturesWithConfig_MinConfidence(t *testing.T) {
+	txns := parseLogs(t, []string{
+		"Transaction ID: AM12345678. You have received Ksh1,000.00 from JOHN DOE",
+		"Airtel Money: Your transaction of Ksh200.00 was successful", // generic fallback
+	})
+
+	all := MapFeatures(txns)
+	if all[0] != 1200 {
+		t.Errorf("total_income = %v with no gate, want 1200", all[0])
+	}
+
+	gated := MapFeaturesWithConfig(txns, MapperConfig{MinConfidence: parser.ConfidenceExact})
+	if gated[0] != 1000 {
+		t.Errorf("total_income = %v with generic parses gated out, want 1000", gated[0])
+	}
+	if gated[3] != 1 {
+		t.Errorf("txn_count = %v with generic parses gated out, want 1", gated[3])
+	}
+}
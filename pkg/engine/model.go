@@ -0,0 +1,263 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/dmitryikh/leaves"
+)
+
+// ModelPathEnv names the environment variable pointing at a model file that
+// overrides the embedded default, e.g. after retraining.
+const ModelPathEnv = "BOREHOLE_MODEL_PATH"
+
+// embeddedModel is the default model, bundled so CLI and mobile builds do not
+// depend on the working directory.
+//
+//go:embed model/borehole_model.json
+var embeddedModel []byte
+
+// calibrationFile is the name of the calibration stored next to a model.
+const calibrationFile = "calibration.json"
+
+// embeddedCalibration accompanies embeddedModel.
+//
+//go:embed model/calibration.json
+var embeddedCalibration []byte
+
+// ModelData returns the model GetEngine loads: the file named by ModelPathEnv
+// when set, otherwise the embedded default.
+func ModelData() ([]byte, error) {
+	path := os.Getenv(ModelPathEnv)
+	if path == "" {
+		return embeddedModel, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read model override: %w", err)
+	}
+	return data, nil
+}
+
+// CalibrationData returns the calibration stored alongside the model ModelData
+// loads: calibration.json in the override model's directory, or the embedded
+// default. An override model without one is left uncalibrated (nil data).
+func CalibrationData() ([]byte, error) {
+	path := os.Getenv(ModelPathEnv)
+	if path == "" {
+		return embeddedCalibration, nil
+	}
+	return calibrationBeside(path)
+}
+
+// calibrationBeside reads calibration.json from the directory of the model at
+// modelPath, returning nil data when there is none.
+func calibrationBeside(modelPath string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(modelPath), calibrationFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read calibration: %w", err)
+	}
+	return data, nil
+}
+
+// scorer is a loaded model: a JSON tree dump (treeModel) or an XGBoost
+// binary model (xgboostModel).
+type scorer interface {
+	// probability scores a vector at least featureCount long.
+	probability(features []float64) float64
+
+	// featureCount returns how long a feature vector must be to reach every
+	// split in the model.
+	featureCount() int
+}
+
+// loadModel decodes a model file: a JSON tree dump (see parseModel) when it
+// starts with '[' or '{', otherwise an XGBoost binary model (see
+// parseXGBoostModel). loadTransformation only affects binary models.
+func loadModel(data []byte, loadTransformation bool) (scorer, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return parseModel(data)
+	}
+	return parseXGBoostModel(data, loadTransformation)
+}
+
+// xgboostModel is an XGBoost gbtree or dart model in the binary format
+// Booster.save_model writes, evaluated by leaves. Unlike XGBoost, leaves sends
+// a value exactly equal to a split threshold down the left branch.
+type xgboostModel struct {
+	ensemble    *leaves.Ensemble
+	transformed bool // The ensemble applies the model's own objective
+}
+
+// parseXGBoostModel decodes an XGBoost binary model. With loadTransformation
+// the ensemble maps its margin to a probability through the model's own
+// objective, which must be binary:logistic; without it the raw margin goes
+// through the engine's sigmoid, as for a JSON tree dump.
+func parseXGBoostModel(data []byte, loadTransformation bool) (xgboostModel, error) {
+	ensemble, err := leaves.XGEnsembleFromReader(bufio.NewReader(bytes.NewReader(data)), loadTransformation)
+	if err != nil {
+		return xgboostModel{}, fmt.Errorf("decode XGBoost model: %w", err)
+	}
+	if n := ensemble.NOutputGroups(); n != 1 {
+		return xgboostModel{}, fmt.Errorf("XGBoost model has %d output groups, want 1", n)
+	}
+	return xgboostModel{ensemble: ensemble, transformed: loadTransformation}, nil
+}
+
+func (m xgboostModel) probability(features []float64) float64 {
+	score := m.ensemble.PredictSingle(features, 0)
+	if m.transformed {
+		return score
+	}
+	return sigmoid(score)
+}
+
+// featureCount returns the number of features the model was trained on.
+func (m xgboostModel) featureCount() int {
+	return m.ensemble.NFeatures()
+}
+
+// treeNode is one node of an XGBoost JSON tree dump. Leaves carry Leaf;
+// split nodes send feature Split < SplitCondition to Yes, otherwise No, and
+// missing (NaN or out-of-range) features to Missing.
+type treeNode struct {
+	NodeID         int      `json:"nodeid"`
+	Split          int      `json:"split"`
+	SplitCondition float64  `json:"split_condition"`
+	Yes            int      `json:"yes"`
+	No             int      `json:"no"`
+	Missing        int      `json:"missing"`
+	Leaf           *float64 `json:"leaf"`
+}
+
+// tree holds nodes indexed by node ID.
+type tree []treeNode
+
+// treeModel is a boosted ensemble; its margin is the sum of the trees' leaves.
+type treeModel []tree
+
+// treeDump is an XGBoost JSON tree dump: one object per tree.
+type treeDump []struct {
+	Nodes []treeNode `json:"nodes"`
+}
+
+// modelFile optionally wraps a tree dump with the feature layout the model was
+// trained on, so a model trained on another vector is refused at load time.
+// Both fields may be omitted.
+type modelFile struct {
+	FeatureCount      int      `json:"feature_count"`       // At most the package FeatureCount
+	FeatureSchemaHash string   `json:"feature_schema_hash"` // Must equal FeatureSchemaHash
+	Trees             treeDump `json:"trees"`
+}
+
+// parseModel decodes an XGBoost JSON dump of the form [{"nodes": [...]}, ...],
+// either bare or wrapped in a modelFile, and checks that every tree is well
+// formed, so margin cannot loop or index out of range. A wrapper's schema is
+// checked against the current feature layout only when it names one.
+func parseModel(data []byte) (treeModel, error) {
+	var dump treeDump
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var file modelFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("decode model: %w", err)
+		}
+		if file.FeatureCount > FeatureCount {
+			return nil, fmt.Errorf("model was trained on %d features but MapFeatures produces %d", file.FeatureCount, FeatureCount)
+		}
+		if file.FeatureSchemaHash != "" && file.FeatureSchemaHash != FeatureSchemaHash() {
+			return nil, fmt.Errorf("model feature schema %q does not match MapFeatures schema %q", file.FeatureSchemaHash, FeatureSchemaHash())
+		}
+		dump = file.Trees
+	} else if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, fmt.Errorf("decode model: %w", err)
+	}
+	if len(dump) == 0 {
+		return nil, fmt.Errorf("model has no trees")
+	}
+
+	model := make(treeModel, len(dump))
+	for i, d := range dump {
+		t := make(tree, len(d.Nodes))
+		seen := make([]bool, len(d.Nodes))
+		for _, n := range d.Nodes {
+			if n.NodeID < 0 || n.NodeID >= len(t) || seen[n.NodeID] {
+				return nil, fmt.Errorf("tree %d: bad or duplicate node id %d", i, n.NodeID)
+			}
+			t[n.NodeID], seen[n.NodeID] = n, true
+		}
+		for _, n := range t {
+			if n.Leaf != nil {
+				continue
+			}
+			if n.Split < 0 {
+				return nil, fmt.Errorf("tree %d: node %d splits on negative feature %d", i, n.NodeID, n.Split)
+			}
+			// Children must come after their parent, which rules out cycles
+			for _, child := range []int{n.Yes, n.No, n.Missing} {
+				if child <= n.NodeID || child >= len(t) {
+					return nil, fmt.Errorf("tree %d: node %d has invalid child %d", i, n.NodeID, child)
+				}
+			}
+		}
+		if len(t) == 0 {
+			return nil, fmt.Errorf("tree %d is empty", i)
+		}
+		model[i] = t
+	}
+	return model, nil
+}
+
+// featureCount returns how long a feature vector must be to reach every split
+// in the model: one past the highest feature index it reads.
+func (m treeModel) featureCount() int {
+	n := 0
+	for _, t := range m {
+		for _, node := range t {
+			if node.Leaf == nil && node.Split+1 > n {
+				n = node.Split + 1
+			}
+		}
+	}
+	return n
+}
+
+// probability applies the sigmoid to the model's margin.
+func (m treeModel) probability(features []float64) float64 {
+	return sigmoid(m.margin(features))
+}
+
+// margin returns the raw (pre-sigmoid) score for a feature vector.
+func (m treeModel) margin(features []float64) float64 {
+	var sum float64
+	for _, t := range m {
+		n := t[0]
+		for n.Leaf == nil {
+			switch {
+			case n.Split >= len(features) || math.IsNaN(features[n.Split]):
+				n = t[n.Missing]
+			case features[n.Split] < n.SplitCondition:
+				n = t[n.Yes]
+			default:
+				n = t[n.No]
+			}
+		}
+		sum += *n.Leaf
+	}
+	return sum
+}
+
+// sigmoid maps a margin onto (0, 1).
+func sigmoid(margin float64) float64 {
+	return 1.0 / (1.0 + math.Exp(-margin))
+}
@@ -0,0 +1,349 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestModelData_Embedded(t *testing.T) {
+	t.Setenv(ModelPathEnv, "")
+
+	data, err := ModelData()
+	if err != nil {
+		t.Fatalf("ModelData() error = %v", err)
+	}
+	e, err := newEngine(data, EngineConfig{})
+	if err != nil {
+		t.Fatalf("embedded model failed to load: %v", err)
+	}
+
+	features := make([]float64, FeatureCount)
+	features[0] = 5000
+	if score := e.Predict(features); score <= 0.5 || score >= 1 {
+		t.Errorf("Predict(high income) = %v, want in (0.5, 1)", score)
+	}
+	if e.modelHash != hashHex(embeddedModel) {
+		t.Errorf("modelHash = %q, want hash of embedded model", e.modelHash)
+	}
+}
+
+// TestEmbeddedModel_MatchesBuiltinRule pins the embedded model to the stump
+// that Predict hard-coded before models were loaded from JSON, so moving to
+// the file did not change any score. The file's leaves were rewritten from
+// +0.5/-0.5 to -1.5/+1.5 to match that rule, which is what production scored
+// with; the old leaves had the opposite sign and were never used to score.
+func TestEmbeddedModel_MatchesBuiltinRule(t *testing.T) {
+	builtin := func(features []float64) float64 {
+		margin := 1.5
+		if features[0] < 1000.0 {
+			margin = -1.5
+		}
+		return 1.0 / (1.0 + math.Exp(-margin))
+	}
+
+	e, err := newEngine(embeddedModel, EngineConfig{})
+	if err != nil {
+		t.Fatalf("embedded model failed to load: %v", err)
+	}
+	features := make([]float64, FeatureCount)
+	for _, income := range []float64{0, 1, 500, 999.99, 1000, 1000.01, 5000, 1e6} {
+		features[0] = income
+		if got, want := e.Predict(features), builtin(features); got != want {
+			t.Errorf("Predict(income %v) = %v, want %v as before", income, got, want)
+		}
+	}
+}
+
+func TestModelData_Override(t *testing.T) {
+	// A stump that always scores a margin of 2
+	path := filepath.Join(t.TempDir(), "model.json")
+	override := []byte(`[{"nodes": [{"nodeid": 0, "leaf": 2.0}]}]`)
+	if err := os.WriteFile(path, override, 0o600); err != nil {
+		t.Fatalf("write override: %v", err)
+	}
+	t.Setenv(ModelPathEnv, path)
+
+	data, err := ModelData()
+	if err != nil {
+		t.Fatalf("ModelData() error = %v", err)
+	}
+	e, err := newEngine(data, EngineConfig{})
+	if err != nil {
+		t.Fatalf("override model failed to load: %v", err)
+	}
+	if want := 0.8807970779778823; !almostEqual(e.Predict(make([]float64, FeatureCount)), want, floatEpsilon) {
+		t.Errorf("Predict() = %v, want sigmoid(2) = %v", e.Predict(make([]float64, FeatureCount)), want)
+	}
+
+	t.Setenv(ModelPathEnv, filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := ModelData(); err == nil {
+		t.Error("ModelData() with a missing override file: want error")
+	}
+}
+
+func TestNewEngineFromModel(t *testing.T) {
+	// A stump that always scores a margin of 2, calibrated by Platt scaling
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.json")
+	if err := os.WriteFile(path, []byte(`[{"nodes": [{"nodeid": 0, "leaf": 2.0}]}]`), 0o600); err != nil {
+		t.Fatalf("write model: %v", err)
+	}
+	e, err := NewEngineFromModel(path)
+	if err != nil {
+		t.Fatalf("NewEngineFromModel() error = %v", err)
+	}
+	if want := 1 / (1 + math.Exp(-2)); !almostEqual(e.Predict(make([]float64, FeatureCount)), want, floatEpsilon) {
+		t.Errorf("Predict() uncalibrated = %v, want sigmoid(2) = %v", e.Predict(make([]float64, FeatureCount)), want)
+	}
+
+	calibration := []byte(`{"method": "platt", "a": 0.5, "b": 0}`)
+	if err := os.WriteFile(filepath.Join(dir, calibrationFile), calibration, 0o600); err != nil {
+		t.Fatalf("write calibration: %v", err)
+	}
+	if e, err = NewEngineFromModel(path); err != nil {
+		t.Fatalf("NewEngineFromModel() with calibration error = %v", err)
+	}
+	if want := 1 / (1 + math.Exp(-1)); !almostEqual(e.Predict(make([]float64, FeatureCount)), want, floatEpsilon) {
+		t.Errorf("Predict() calibrated = %v, want sigmoid(1) = %v", e.Predict(make([]float64, FeatureCount)), want)
+	}
+
+	if _, err := NewEngineFromModel(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("NewEngineFromModel() with a missing file: want error")
+	}
+}
+
+func TestNewEngineFromReader(t *testing.T) {
+	e, err := NewEngineFromReader(bytes.NewReader(embeddedModel))
+	if err != nil {
+		t.Fatalf("NewEngineFromReader() error = %v", err)
+	}
+	if e.modelHash != hashHex(embeddedModel) {
+		t.Errorf("modelHash = %q, want hash of embedded model", e.modelHash)
+	}
+	if _, err := NewEngineFromReader(strings.NewReader(`{`)); err == nil {
+		t.Error("NewEngineFromReader() with invalid JSON: want error")
+	}
+
+	platt := Calibration{Method: CalibrationPlatt, A: 0.5}
+	calibrated, err := NewEngineFromReaderWithConfig(bytes.NewReader(embeddedModel), EngineConfig{Calibration: platt})
+	if err != nil {
+		t.Fatalf("NewEngineFromReaderWithConfig() error = %v", err)
+	}
+	if calibrated.Stamp().CalibrationHash != platt.Hash() {
+		t.Errorf("CalibrationHash = %q, want %q", calibrated.Stamp().CalibrationHash, platt.Hash())
+	}
+}
+
+func TestNewEngineFromReader_XGBoostBinary(t *testing.T) {
+	// The embedded rule saved by XGBoost: income below 1000 scores margin -1.5
+	model := xgboostStump(t, "binary:logistic", FeatureCount, 0, 1000, -1.5, 1.5)
+	want, err := newEngine(embeddedModel, EngineConfig{})
+	if err != nil {
+		t.Fatalf("newEngine(embedded) error = %v", err)
+	}
+
+	for _, transform := range []bool{false, true} {
+		e, err := NewEngineFromReaderWithConfig(bytes.NewReader(model), EngineConfig{LoadTransformation: transform})
+		if err != nil {
+			t.Fatalf("LoadTransformation %v: NewEngineFromReaderWithConfig() error = %v", transform, err)
+		}
+		features := make([]float64, FeatureCount)
+		for _, income := range []float64{0, 999, 1001, 5000} {
+			features[0] = income
+			if got := e.Predict(features); !almostEqual(got, want.Predict(features), floatEpsilon) {
+				t.Errorf("LoadTransformation %v: Predict(income %v) = %v, want %v", transform, income, got, want.Predict(features))
+			}
+		}
+	}
+
+	// Only a binary:logistic objective can be applied by the model itself
+	regression := xgboostStump(t, "reg:squarederror", FeatureCount, 0, 1000, -1.5, 1.5)
+	if _, err := NewEngineFromReaderWithConfig(bytes.NewReader(regression), EngineConfig{LoadTransformation: true}); err == nil {
+		t.Error("LoadTransformation with a regression objective: want error")
+	}
+	if _, err := NewEngineFromReader(bytes.NewReader(regression)); err != nil {
+		t.Errorf("NewEngineFromReader() with a regression objective error = %v", err)
+	}
+
+	wide := xgboostStump(t, "binary:logistic", FeatureCount+1, 0, 1000, -1.5, 1.5)
+	if _, err := NewEngineFromReader(bytes.NewReader(wide)); err == nil {
+		t.Error("NewEngineFromReader() with a model trained on more features: want error")
+	}
+}
+
+// xgboostStump encodes a one-split gbtree in the XGBoost binary format: vectors
+// with feature split below cond score margin left, others margin right.
+func xgboostStump(t *testing.T, objective string, features uint32, split uint32, cond, left, right float32) []byte {
+	t.Helper()
+	type node struct {
+		Parent, CLeft, CRight int32
+		SIndex                uint32 // Feature index; the top bit sends missing values left
+		Info                  float32
+	}
+	type nodeStat struct {
+		LossChg, SumHess, BaseWeight float32
+		LeafChildCnt                 int32
+	}
+
+	var buf bytes.Buffer
+	write := func(v any) {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			t.Fatalf("encode XGBoost model: %v", err)
+		}
+	}
+	writeString := func(s string) {
+		write(uint64(len(s)))
+		buf.WriteString(s)
+	}
+
+	write(struct {
+		BaseScore   float32
+		NumFeatures uint32
+		Reserved    [32]int32
+	}{NumFeatures: features})
+	writeString(objective)
+	writeString("gbtree")
+	write(struct {
+		NumTrees, NumRoots, NumFeature, Pad int32
+		NumPbuffer                          int64
+		NumOutputGroup, SizeLeafVector      int32
+		Reserved                            [32]int32
+	}{NumTrees: 1, NumRoots: 1, NumFeature: int32(features), NumOutputGroup: 1})
+	write(struct {
+		NumRoots, NumNodes, NumDeleted, MaxDepth, NumFeature, SizeLeafVector int32
+		Reserved                                                             [31]int32
+	}{NumRoots: 1, NumNodes: 3, MaxDepth: 1, NumFeature: int32(features)})
+	write([]node{
+		{Parent: -1, CLeft: 1, CRight: 2, SIndex: split | 1<<31, Info: cond},
+		{Parent: 0, CLeft: -1, CRight: -1, Info: left},
+		{Parent: 0, CLeft: -1, CRight: -1, Info: right},
+	})
+	write(make([]nodeStat, 3))
+	write([]int32{0}) // Tree info: every tree feeds output group 0
+	return buf.Bytes()
+}
+
+func TestParseModel_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"not json":   `{`,
+		"no trees":   `[]`,
+		"empty tree": `[{"nodes": []}]`,
+		"cycle":      `[{"nodes": [{"nodeid": 0, "split": 0, "yes": 0, "no": 0, "missing": 0}]}]`,
+		"dangling":   `[{"nodes": [{"nodeid": 0, "split": 0, "yes": 1, "no": 2, "missing": 1}]}]`,
+		"negative":   `[{"nodes": [{"nodeid": 0, "split": -1, "yes": 1, "no": 2, "missing": 1}, {"nodeid": 1, "leaf": 0}, {"nodeid": 2, "leaf": 0}]}]`,
+	}
+	for name, data := range tests {
+		if _, err := parseModel([]byte(data)); err == nil {
+			t.Errorf("%s: parseModel() error = nil, want error", name)
+		}
+	}
+}
+
+func TestParseModel_FeatureSchema(t *testing.T) {
+	trees := `[{"nodes": [{"nodeid": 0, "leaf": 1}]}]`
+	valid := map[string]string{
+		"bare tree dump": trees,
+		"no schema":      `{"trees": ` + trees + `}`,
+		"current schema": fmt.Sprintf(`{"feature_count": %d, "feature_schema_hash": %q, "trees": %s}`, FeatureCount, FeatureSchemaHash(), trees),
+		"shorter vector": fmt.Sprintf(`{"feature_count": %d, "trees": %s}`, FeatureCount-1, trees),
+	}
+	for name, data := range valid {
+		if _, err := parseModel([]byte(data)); err != nil {
+			t.Errorf("%s: parseModel() error = %v", name, err)
+		}
+	}
+
+	// A wrapper that names another layout must not load, even though none of
+	// the model's splits read past FeatureCount
+	invalid := map[string]string{
+		"wider vector": fmt.Sprintf(`{"feature_count": %d, "trees": %s}`, FeatureCount+1, trees),
+		"other schema": fmt.Sprintf(`{"feature_count": %d, "feature_schema_hash": %q, "trees": %s}`, FeatureCount, hashHex([]byte("total_income")), trees),
+	}
+	for name, data := range invalid {
+		if _, err := parseModel([]byte(data)); err == nil {
+			t.Errorf("%s: parseModel() error = nil, want error", name)
+		}
+	}
+}
+
+func TestNewEngine_FeatureCountMismatch(t *testing.T) {
+	// A model trained on a wider vector than MapFeatures produces
+	wide := fmt.Sprintf(`[{"nodes": [
+		{"nodeid": 0, "split": %d, "split_condition": 1, "yes": 1, "no": 2, "missing": 1},
+		{"nodeid": 1, "leaf": -1},
+		{"nodeid": 2, "leaf": 1}
+	]}]`, FeatureCount)
+
+	_, err := newEngine([]byte(wide), EngineConfig{})
+	if err == nil {
+		t.Fatal("newEngine() with a model reading past FeatureCount: want error")
+	}
+	if !strings.Contains(err.Error(), strconv.Itoa(FeatureCount+1)) {
+		t.Errorf("error = %q, want it to name the model's feature count %d", err, FeatureCount+1)
+	}
+
+	e, err := newEngine(embeddedModel, EngineConfig{})
+	if err != nil {
+		t.Fatalf("newEngine(embedded) error = %v", err)
+	}
+	if got := e.FeatureCount(); got < 1 || got > FeatureCount {
+		t.Errorf("FeatureCount() = %d, want within [1, %d]", got, FeatureCount)
+	}
+}
+
+func TestPredict_ShortVector(t *testing.T) {
+	// A model splitting on the last feature cannot score a shorter vector
+	data := fmt.Sprintf(`[{"nodes": [
+		{"nodeid": 0, "split": %d, "split_condition": 1, "yes": 1, "no": 2, "missing": 1},
+		{"nodeid": 1, "leaf": -1},
+		{"nodeid": 2, "leaf": 1}
+	]}]`, FeatureCount-1)
+	e, err := newEngine([]byte(data), EngineConfig{})
+	if err != nil {
+		t.Fatalf("newEngine() error = %v", err)
+	}
+
+	if got := e.Predict(make([]float64, FeatureCount-1)); got != 0.5 {
+		t.Errorf("Predict(short) = %v, want neutral 0.5", got)
+	}
+	if got := e.Predict(make([]float64, FeatureCount)); got == 0.5 {
+		t.Error("Predict(full) = 0.5, want the model's score")
+	}
+}
+
+// stumpEngine returns an engine whose only tree is a leaf scoring margin.
+func stumpEngine(t *testing.T, margin float64) *BoreholeEngine {
+	t.Helper()
+	e, err := newEngine([]byte(fmt.Sprintf(`[{"nodes": [{"nodeid": 0, "leaf": %g}]}]`, margin)), EngineConfig{})
+	if err != nil {
+		t.Fatalf("newEngine() error = %v", err)
+	}
+	return e
+}
+
+func TestPredict_ExtremeMargins(t *testing.T) {
+	for _, margin := range []float64{-40, -15, 15, 40} {
+		got := stumpEngine(t, margin).Predict(make([]float64, FeatureCount))
+		if want := 1 / (1 + math.Exp(-margin)); !almostEqual(got, want, floatEpsilon) || math.IsNaN(got) {
+			t.Errorf("Predict() with margin %v = %v, want %v", margin, got, want)
+		}
+	}
+}
+
+func TestPredict_SpansFullRange(t *testing.T) {
+	// A single sigmoid maps strong margins near the ends of (0, 1); applying
+	// it twice would squash them into a narrow band around 0.5
+	if got := stumpEngine(t, 3).Predict(make([]float64, FeatureCount)); got <= 0.7 {
+		t.Errorf("Predict() with margin 3 = %v, want well above 0.7", got)
+	}
+	if got := stumpEngine(t, -3).Predict(make([]float64, FeatureCount)); got >= 0.3 {
+		t.Errorf("Predict() with margin -3 = %v, want well below 0.3", got)
+	}
+}
@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+)
+
+// NameNormalization sets how aggressively counterparty names are merged
+// before they are counted.
+type NameNormalization int
+
+const (
+	// NormalizeConservative uppercases and collapses whitespace, so
+	// "John  Doe" and "JOHN DOE" are one counterparty.
+	NormalizeConservative NameNormalization = iota
+
+	// NormalizeTokenSort also sorts the words, so "DOE JOHN" joins them.
+	// Distinct people sharing the same names are merged too.
+	NormalizeTokenSort
+)
+
+// CanonicalizeName returns the conservative key for a counterparty name.
+func CanonicalizeName(name string) string {
+	return NormalizeConservative.Canonicalize(name)
+}
+
+// Canonicalize returns the key name is counted under at this level.
+func (n NameNormalization) Canonicalize(name string) string {
+	tokens := strings.Fields(strings.ToUpper(name))
+	if n == NormalizeTokenSort {
+		sort.Strings(tokens)
+	}
+	return strings.Join(tokens, " ")
+}
@@ -0,0 +1,30 @@
+package engine
+
+import "testing"
+
+func TestCanonicalizeName(t *testing.T) {
+	tests := []struct {
+		name  string
+		level NameNormalization
+		a, b  string
+		same  bool
+	}{
+		{"case and spacing", NormalizeConservative, "JOHN DOE", " John  Doe ", true},
+		{"word order kept apart", NormalizeConservative, "JOHN DOE", "DOE JOHN", false},
+		{"word order merged", NormalizeTokenSort, "JOHN DOE", "doe   john", true},
+		{"different people", NormalizeTokenSort, "JOHN DOE", "JANE DOE", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := tt.level.Canonicalize(tt.a), tt.level.Canonicalize(tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("Canonicalize(%q) = %q, Canonicalize(%q) = %q, want same = %v", tt.a, a, tt.b, b, tt.same)
+			}
+		})
+	}
+
+	if got := CanonicalizeName("  John \t Doe "); got != "JOHN DOE" {
+		t.Errorf("CanonicalizeName() = %q, want %q", got, "JOHN DOE")
+	}
+}
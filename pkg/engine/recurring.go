@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"sort"
+
+	"borehole/core/pkg/parser"
+)
+
+// recurringReceipt is a dated receipt held for comparison with later ones.
+type recurringReceipt struct {
+	txn     parser.Transaction // Trimmed to the fields parser.Recurs reads
+	counted bool               // Amount already added to the recurring total
+}
+
+// recurringTracker flags recurring income as receipts arrive, by the rules of
+// parser.DetectRecurring. Each new receipt is compared only against held
+// receipts within parser.RecurringWindow of it, kept sorted by time, so the
+// running total is always current without rescanning the history.
+type recurringTracker struct {
+	held  []recurringReceipt
+	total moneyTotal
+
+	// bounded drops held receipts further than parser.RecurringWindow from
+	// the newest arrival, keeping memory flat for a stream in chronological
+	// order or in reverse. A receipt arriving further out of order than that
+	// is only compared against receipts still held.
+	bounded bool
+}
+
+// add records an earned receipt. Receipts the caller already flagged count as
+// recurring whether or not a partner is ever seen.
+func (r *recurringTracker) add(txn parser.Transaction) {
+	receipt := recurringReceipt{txn: parser.Transaction{
+		Type:      txn.Type,
+		Amount:    txn.Amount,
+		Timestamp: txn.Timestamp,
+		Derived:   txn.Derived,
+	}}
+	if txn.Recurring {
+		r.total.add(txn.Amount)
+		receipt.counted = true
+	}
+	if !parser.IsRecurringCandidate(receipt.txn) {
+		return
+	}
+
+	at := txn.Timestamp
+	i := sort.Search(len(r.held), func(i int) bool { return r.held[i].txn.Timestamp.After(at) })
+	r.held = append(r.held, recurringReceipt{})
+	copy(r.held[i+1:], r.held[i:])
+	r.held[i] = receipt
+
+	for j := i - 1; j >= 0 && at.Sub(r.held[j].txn.Timestamp) <= parser.RecurringWindow; j-- {
+		r.pair(i, j)
+	}
+	for j := i + 1; j < len(r.held) && r.held[j].txn.Timestamp.Sub(at) <= parser.RecurringWindow; j++ {
+		r.pair(i, j)
+	}
+
+	if r.bounded {
+		r.prune(i)
+	}
+}
+
+// pair counts held receipts i and j as recurring if they recur together.
+func (r *recurringTracker) pair(i, j int) {
+	if !parser.Recurs(r.held[i].txn, r.held[j].txn) {
+		return
+	}
+	r.count(&r.held[i])
+	r.count(&r.held[j])
+}
+
+// count adds receipt to the recurring total the first time it recurs.
+func (r *recurringTracker) count(receipt *recurringReceipt) {
+	if !receipt.counted {
+		r.total.add(receipt.txn.Amount)
+		receipt.counted = true
+	}
+}
+
+// prune drops held receipts that are further than parser.RecurringWindow
+// from held[i], the receipt just added.
+func (r *recurringTracker) prune(i int) {
+	at := r.held[i].txn.Timestamp
+	first := sort.Search(len(r.held), func(j int) bool {
+		return at.Sub(r.held[j].txn.Timestamp) <= parser.RecurringWindow
+	})
+	last := sort.Search(len(r.held), func(j int) bool {
+		return r.held[j].txn.Timestamp.Sub(at) > parser.RecurringWindow
+	})
+	r.held = r.held[first:last]
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"time"
+
+	"borehole/core/pkg/parser"
+)
+
+// Implied loan terms used to judge whether a repayment was on time.
+const (
+	fulizaTerm  = 30 * 24 * time.Hour // Fuliza accrues daily and is due within 30 days
+	hustlerTerm = 14 * 24 * time.Hour // Hustler Fund personal loans run 14 days
+	digitalTerm = 30 * 24 * time.Hour // Typical Tala/Branch first-loan tenor
+	kcbTerm     = 30 * 24 * time.Hour // KCB M-PESA loans are due in 30 days
+
+	// neutralOnTimeRatio is reported when no loan could be paired with its repayment.
+	neutralOnTimeRatio = 0.5
+
+	// settledEpsilon absorbs float rounding when a loan is repaid in parts.
+	settledEpsilon = 0.005
+)
+
+// openLoan is a disbursement that has not been fully repaid yet.
+type openLoan struct {
+	disbursed   time.Time
+	outstanding float64
+}
+
+// repaymentTracker pairs repayments with earlier loans from the same lender,
+// oldest first, and counts how many loans were settled within their term.
+// Transactions are expected in chronological order, as they appear in an
+// SMS inbox or statement. Undated transactions are ignored.
+type repaymentTracker struct {
+	open    map[string][]openLoan
+	settled int
+	onTime  int
+}
+
+func newRepaymentTracker() *repaymentTracker {
+	return &repaymentTracker{open: make(map[string][]openLoan)}
+}
+
+// add records a loan or repayment; other transaction types are ignored.
+func (r *repaymentTracker) add(txn parser.Transaction) {
+	if txn.Timestamp.IsZero() || txn.Amount <= 0 {
+		return
+	}
+
+	switch txn.Type {
+	case parser.TxnFulizaLoan, parser.TxnHustlerLoan, parser.TxnDigitalLoan, parser.TxnKCBLoan:
+		key := loanKey(txn)
+		principal := txn.Amount + txn.Saved // Hustler Fund savings are repaid too
+		r.open[key] = append(r.open[key], openLoan{disbursed: txn.Timestamp, outstanding: principal})
+	case parser.TxnFulizaRepay, parser.TxnHustlerRepay, parser.TxnDigitalRepay, parser.TxnKCBRepay:
+		r.repay(loanKey(txn), txn.Amount, txn.Timestamp, loanTerm(txn.Type))
+	}
+}
+
+// repay applies amount to the oldest open loans under key, carrying any
+// excess over to the next one. Repayments with no open loan are ignored.
+func (r *repaymentTracker) repay(key string, amount float64, at time.Time, term time.Duration) {
+	loans := r.open[key]
+	for amount > 0 && len(loans) > 0 {
+		loan := &loans[0]
+		paid := amount
+		if paid > loan.outstanding {
+			paid = loan.outstanding
+		}
+		loan.outstanding -= paid
+		amount -= paid
+
+		if loan.outstanding > settledEpsilon {
+			break
+		}
+		r.settled++
+		if at.Sub(loan.disbursed) <= term {
+			r.onTime++
+		}
+		loans = loans[1:]
+	}
+	r.open[key] = loans
+}
+
+// ratio returns the share of settled loans repaid on time, or
+// neutralOnTimeRatio when nothing has been settled.
+func (r *repaymentTracker) ratio() float64 {
+	if r.settled == 0 {
+		return neutralOnTimeRatio
+	}
+	return float64(r.onTime) / float64(r.settled)
+}
+
+// loanKey groups a transaction with the loans it can repay.
+func loanKey(txn parser.Transaction) string {
+	switch txn.Type {
+	case parser.TxnFulizaLoan, parser.TxnFulizaRepay:
+		return "Fuliza"
+	case parser.TxnHustlerLoan, parser.TxnHustlerRepay:
+		return "Hustler Fund"
+	case parser.TxnKCBLoan, parser.TxnKCBRepay:
+		return "KCB M-Pesa"
+	default:
+		return "digital:" + txn.Lender
+	}
+}
+
+// loanTerm returns the implied term for the loan family a repayment belongs to.
+func loanTerm(t parser.TransactionType) time.Duration {
+	switch t {
+	case parser.TxnFulizaRepay:
+		return fulizaTerm
+	case parser.TxnHustlerRepay:
+		return hustlerTerm
+	case parser.TxnKCBRepay:
+		return kcbTerm
+	default:
+		return digitalTerm
+	}
+}
@@ -3,9 +3,13 @@ package engine
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 )
@@ -17,23 +21,62 @@ type CertificatePayload struct {
 	Expires   int64   `json:"exp"` // Expiry (Unix)
 	UserID    string  `json:"uid"` // Anonymous ID (e.g., Device ID hash)
 	Tampered  bool    `json:"tampered"`
+	KeyID     string  `json:"kid"` // Signing key, see KeyID
+	VersionStamp
 }
 
+// certificateClockSkew is how far in the future a certificate's issue time may
+// be, to tolerate clock drift between the issuing device and the verifier.
+const certificateClockSkew = 5 * time.Minute
+
+var (
+	// ErrCertificateExpired is returned for a validly signed certificate past its exp.
+	ErrCertificateExpired = errors.New("certificate expired")
+
+	// ErrCertificateNotYetValid is returned for a validly signed certificate
+	// issued further in the future than certificateClockSkew allows.
+	ErrCertificateNotYetValid = errors.New("certificate issued in the future")
+
+	// ErrUnknownKeyID is returned for a certificate whose kid names a key the
+	// module neither signs with nor has registered.
+	ErrUnknownKeyID = errors.New("certificate signed by an unknown key")
+)
+
 // SecurityModule handles cryptographic operations.
 type SecurityModule struct {
 	publicKey  ed25519.PublicKey
 	privateKey ed25519.PrivateKey
+	keyID      string
+	keys       map[string]ed25519.PublicKey // Verification keys by ID, including the current one
 	mu         sync.RWMutex
 }
 
+// newSecurityModule returns a module signing with priv, which must match pub.
+func newSecurityModule(pub ed25519.PublicKey, priv ed25519.PrivateKey) *SecurityModule {
+	id := KeyID(pub)
+	return &SecurityModule{
+		publicKey:  pub,
+		privateKey: priv,
+		keyID:      id,
+		keys:       map[string]ed25519.PublicKey{id: pub},
+	}
+}
+
+// KeyID identifies a public key in certificates: the first 8 characters of
+// its Base64 encoding.
+func KeyID(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)[:8]
+}
+
 var (
 	secInstance *SecurityModule
 	secOnce     sync.Once
 )
 
 // GetSecurityModule returns the singleton security module.
-// In a real app, keys would be loaded from a secure vault.
-// Here, we generate a fresh pair on startup for demonstration.
+// It generates a fresh pair on startup for demonstration, so its certificates
+// do not verify after a restart; production callers should load a stable key
+// with NewSecurityModule or NewSecurityModuleFromSeed.
 func GetSecurityModule() *SecurityModule {
 	secOnce.Do(func() {
 		pub, priv, err := ed25519.GenerateKey(rand.Reader)
@@ -41,33 +84,129 @@ func GetSecurityModule() *SecurityModule {
 			// simplified panic for critical security failure in init
 			panic(fmt.Sprintf("failed to generate ed25519 keys: %v", err))
 		}
-		secInstance = &SecurityModule{
-			publicKey:  pub,
-			privateKey: priv,
-		}
+		secInstance = newSecurityModule(pub, priv)
 	})
 	return secInstance
 }
 
-// IssueCertificate creates a signed payload for a credit score.
+// NewSecurityModule returns a module signing with priv, so certificates stay
+// verifiable across restarts and clients can pin the public key.
+func NewSecurityModule(priv ed25519.PrivateKey) (*SecurityModule, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("ed25519 private key must be %d bytes, got %d", ed25519.PrivateKeySize, len(priv))
+	}
+	return newSecurityModule(priv.Public().(ed25519.PublicKey), priv), nil
+}
+
+// RegisterKey adds a public key that VerifyCertificate accepts for
+// certificates carrying kid id, e.g. the key in use before a rotation.
+// Registering the current key's ID again replaces it for verification only.
+func (s *SecurityModule) RegisterKey(id string, pub ed25519.PublicKey) error {
+	if len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("ed25519 public key must be %d bytes, got %d", ed25519.PublicKeySize, len(pub))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[id] = pub
+	return nil
+}
+
+// NewSecurityModuleFromSeed returns a module whose key pair is derived from
+// a 32-byte seed. The same seed always yields the same keys.
+func NewSecurityModuleFromSeed(seed []byte) (*SecurityModule, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ed25519 seed must be %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	return NewSecurityModule(ed25519.NewKeyFromSeed(seed))
+}
+
+// LoadPrivateKeyFromPEM reads an ed25519 private key from a PEM file holding
+// a PKCS #8 "PRIVATE KEY" block, as written by
+// "openssl genpkey -algorithm ed25519".
+func LoadPrivateKeyFromPEM(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: no PEM \"PRIVATE KEY\" block", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: private key is %T, want ed25519", path, key)
+	}
+	return priv, nil
+}
+
+// ScoreClaim is one score to certify in a call to IssueCertificates.
+type ScoreClaim struct {
+	Score  float64 `json:"score"`
+	UserID string  `json:"uid"`
+}
+
+// SignedCert is a certificate issued by IssueCertificates: the signed payload
+// JSON and its Base64 signature, as returned by IssueCertificate.
+type SignedCert struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// IssueCertificate creates a signed payload for a credit score produced by
+// the engine with the given stamp (see BoreholeEngine.Stamp).
 // Returns two strings: formatted payload (JSON) and the Base64 signature.
-func (s *SecurityModule) IssueCertificate(score float64, uid string) (string, string, error) {
+func (s *SecurityModule) IssueCertificate(score float64, uid string, stamp VersionStamp) (string, string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	cert, err := s.sign(ScoreClaim{Score: score, UserID: uid}, time.Now(), stamp)
+	if err != nil {
+		return "", "", err
+	}
+	return cert.Payload, cert.Signature, nil
+}
+
+// IssueCertificates signs a certificate for each claim, in order, for scores
+// produced by the engine with the given stamp. The key is read-locked once
+// and every certificate shares the same issue time. On error no certificates
+// are returned.
+func (s *SecurityModule) IssueCertificates(items []ScoreClaim, stamp VersionStamp) ([]SignedCert, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	certs := make([]SignedCert, 0, len(items))
+	for i, claim := range items {
+		cert, err := s.sign(claim, now, stamp)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// sign builds and signs the payload for one claim. Callers hold s.mu.
+func (s *SecurityModule) sign(claim ScoreClaim, now time.Time, stamp VersionStamp) (SignedCert, error) {
 	// 1. Create Payload
 	payload := CertificatePayload{
-		Score:     score,
-		Timestamp: time.Now().Unix(),
-		Expires:   time.Now().Add(24 * time.Hour).Unix(),
-		UserID:    uid,
-		Tampered:  false, // Hardcoded engine is immutable by design
+		Score:        claim.Score,
+		Timestamp:    now.Unix(),
+		Expires:      now.Add(24 * time.Hour).Unix(),
+		UserID:       claim.UserID,
+		Tampered:     false, // Hardcoded engine is immutable by design
+		KeyID:        s.keyID,
+		VersionStamp: stamp,
 	}
 
 	// 2. Serialize
 	data, err := json.Marshal(payload)
 	if err != nil {
-		return "", "", fmt.Errorf("marshal error: %v", err)
+		return SignedCert{}, fmt.Errorf("marshal error: %v", err)
 	}
 
 	// 3. Sign
@@ -76,15 +215,57 @@ func (s *SecurityModule) IssueCertificate(score float64, uid string) (string, st
 	// 4. Encode
 	// We return the raw JSON string (so the verifier knows what was signed)
 	// and the Base64 signature.
-	return string(data), base64.StdEncoding.EncodeToString(signature), nil
+	return SignedCert{
+		Payload:   string(data),
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}, nil
 }
 
 // VerifyCertificate checks if a score claim is valid and signed by this engine.
-// Returns true if valid.
+// Returns true if valid. The key is chosen by the payload's kid, so
+// certificates signed before a rotation verify once their key is registered
+// with RegisterKey; certificates without a kid are checked against the
+// current key. A bad signature returns false with no error; an unknown kid
+// returns ErrUnknownKeyID, and a signed certificate outside its validity
+// window returns ErrCertificateExpired or ErrCertificateNotYetValid.
 func (s *SecurityModule) VerifyCertificate(payloadJSON string, signatureB64 string) (bool, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	// The kid is read before the signature is checked, but only to pick
+	// the key; a forged kid selects a key that will not verify
+	var header struct {
+		KeyID string `json:"kid"`
+	}
+	pub := s.publicKey
+	if json.Unmarshal([]byte(payloadJSON), &header) == nil && header.KeyID != "" {
+		var ok bool
+		if pub, ok = s.keys[header.KeyID]; !ok {
+			return false, ErrUnknownKeyID
+		}
+	}
+	return verifyCertificate(pub, payloadJSON, signatureB64, time.Now())
+}
+
+// VerifyCertificateWithKey is VerifyCertificate against the Base64 public key
+// shipped with the certificate (see GetPublicKeyBase64), for relying parties
+// that do not hold a SecurityModule. Callers should check the key against one
+// they trust; any key verifies the certificates it signed itself.
+func VerifyCertificateWithKey(payloadJSON, signatureB64, publicKeyB64 string) (bool, error) {
+	key, err := base64.StdEncoding.DecodeString(publicKeyB64)
+	if err != nil {
+		return false, fmt.Errorf("invalid base64 public key: %v", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return false, fmt.Errorf("ed25519 public key must be %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	return verifyCertificate(ed25519.PublicKey(key), payloadJSON, signatureB64, time.Now())
+}
+
+// verifyCertificate checks the signature of payloadJSON under pub, then its
+// validity window as of now. The payload is only trusted once the signature
+// holds.
+func verifyCertificate(pub ed25519.PublicKey, payloadJSON, signatureB64 string, now time.Time) (bool, error) {
 	// 1. Decode Signature
 	sig, err := base64.StdEncoding.DecodeString(signatureB64)
 	if err != nil {
@@ -92,8 +273,22 @@ func (s *SecurityModule) VerifyCertificate(payloadJSON string, signatureB64 stri
 	}
 
 	// 2. Verify
-	isValid := ed25519.Verify(s.publicKey, []byte(payloadJSON), sig)
-	return isValid, nil
+	if !ed25519.Verify(pub, []byte(payloadJSON), sig) {
+		return false, nil
+	}
+
+	// 3. Check the validity window
+	var payload CertificatePayload
+	if err := json.Unmarshal([]byte(payloadJSON), &payload); err != nil {
+		return false, fmt.Errorf("invalid payload JSON: %v", err)
+	}
+	if now.Unix() > payload.Expires {
+		return false, ErrCertificateExpired
+	}
+	if payload.Timestamp > now.Add(certificateClockSkew).Unix() {
+		return false, ErrCertificateNotYetValid
+	}
+	return true, nil
 }
 
 // GetPublicKeyBase64 returns the public key to display or share.
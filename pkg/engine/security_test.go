@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSeed is a fixed ed25519 seed for reproducible key pairs.
+var testSeed = bytes.Repeat([]byte{7}, ed25519.SeedSize)
+
+func TestNewSecurityModuleFromSeed_VerifiesAcrossInstances(t *testing.T) {
+	issuer, err := NewSecurityModuleFromSeed(testSeed)
+	if err != nil {
+		t.Fatalf("NewSecurityModuleFromSeed() error = %v", err)
+	}
+	verifier, err := NewSecurityModuleFromSeed(testSeed)
+	if err != nil {
+		t.Fatalf("NewSecurityModuleFromSeed() error = %v", err)
+	}
+
+	payload, sig, err := issuer.IssueCertificate(0.7, "u1", VersionStamp{})
+	if err != nil {
+		t.Fatalf("IssueCertificate() error = %v", err)
+	}
+	if valid, err := verifier.VerifyCertificate(payload, sig); err != nil || !valid {
+		t.Errorf("VerifyCertificate() on a second instance = %v, %v, want true", valid, err)
+	}
+	if issuer.GetPublicKeyBase64() != verifier.GetPublicKeyBase64() {
+		t.Error("public keys differ for the same seed")
+	}
+
+	if valid, _ := GetSecurityModule().VerifyCertificate(payload, sig); valid {
+		t.Error("VerifyCertificate() with an unrelated key = true, want false")
+	}
+}
+
+func TestNewSecurityModule_InvalidKey(t *testing.T) {
+	if _, err := NewSecurityModule(make(ed25519.PrivateKey, 10)); err == nil {
+		t.Error("NewSecurityModule() with a short key: want error")
+	}
+	if _, err := NewSecurityModuleFromSeed([]byte("short")); err == nil {
+		t.Error("NewSecurityModuleFromSeed() with a short seed: want error")
+	}
+}
+
+func TestLoadPrivateKeyFromPEM(t *testing.T) {
+	priv := ed25519.NewKeyFromSeed(testSeed)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "signing.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	got, err := LoadPrivateKeyFromPEM(path)
+	if err != nil {
+		t.Fatalf("LoadPrivateKeyFromPEM() error = %v", err)
+	}
+	if !got.Equal(priv) {
+		t.Error("LoadPrivateKeyFromPEM() returned a different key")
+	}
+
+	garbage := filepath.Join(dir, "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("write garbage: %v", err)
+	}
+	if _, err := LoadPrivateKeyFromPEM(garbage); err == nil {
+		t.Error("LoadPrivateKeyFromPEM() without a PEM block: want error")
+	}
+}
+
+func TestVerifyCertificate_ValidityWindow(t *testing.T) {
+	sec, err := NewSecurityModuleFromSeed(testSeed)
+	if err != nil {
+		t.Fatalf("NewSecurityModuleFromSeed() error = %v", err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+		wantErr  error
+	}{
+		{"valid", now.Add(-time.Hour), true, nil},
+		{"expired", now.Add(-25 * time.Hour), false, ErrCertificateExpired},
+		{"within clock skew", now.Add(time.Minute), true, nil},
+		{"future dated", now.Add(time.Hour), false, ErrCertificateNotYetValid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := sec.sign(ScoreClaim{Score: 0.6, UserID: "u1"}, tt.issuedAt, VersionStamp{})
+			if err != nil {
+				t.Fatalf("sign() error = %v", err)
+			}
+			valid, err := sec.VerifyCertificate(cert.Payload, cert.Signature)
+			if valid != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyCertificate() = %v, %v, want %v, %v", valid, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyCertificate_ForgedExpiry(t *testing.T) {
+	sec, err := NewSecurityModuleFromSeed(testSeed)
+	if err != nil {
+		t.Fatalf("NewSecurityModuleFromSeed() error = %v", err)
+	}
+	cert, err := sec.sign(ScoreClaim{Score: 0.6}, time.Now().Add(-48*time.Hour), VersionStamp{})
+	if err != nil {
+		t.Fatalf("sign() error = %v", err)
+	}
+
+	// Extending exp breaks the signature, which is checked before the window
+	forged := strings.Replace(cert.Payload, `"exp":`, `"exp":9`, 1)
+	if valid, err := sec.VerifyCertificate(forged, cert.Signature); valid || err != nil {
+		t.Errorf("VerifyCertificate(forged) = %v, %v, want false, nil", valid, err)
+	}
+}
+
+func TestVerifyCertificateWithKey(t *testing.T) {
+	sec, err := NewSecurityModuleFromSeed(testSeed)
+	if err != nil {
+		t.Fatalf("NewSecurityModuleFromSeed() error = %v", err)
+	}
+	payload, sig, err := sec.IssueCertificate(0.6, "u1", VersionStamp{})
+	if err != nil {
+		t.Fatalf("IssueCertificate() error = %v", err)
+	}
+	other := GetSecurityModule().GetPublicKeyBase64()
+
+	tests := []struct {
+		name    string
+		key     string
+		want    bool
+		wantErr bool
+	}{
+		{"issuer key", sec.GetPublicKeyBase64(), true, false},
+		{"tampered key", other, false, false},
+		{"not base64", "not-a-key!", false, true},
+		{"wrong length", base64.StdEncoding.EncodeToString([]byte("short")), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := VerifyCertificateWithKey(payload, sig, tt.key)
+			if valid != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("VerifyCertificateWithKey() = %v, %v, want %v, error %v", valid, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyCertificate_KeyRotation(t *testing.T) {
+	old, err := NewSecurityModuleFromSeed(testSeed)
+	if err != nil {
+		t.Fatalf("NewSecurityModuleFromSeed() error = %v", err)
+	}
+	payload, sig, err := old.IssueCertificate(0.6, "u1", VersionStamp{})
+	if err != nil {
+		t.Fatalf("IssueCertificate() error = %v", err)
+	}
+
+	var issued CertificatePayload
+	if err := json.Unmarshal([]byte(payload), &issued); err != nil {
+		t.Fatalf("invalid payload JSON: %v", err)
+	}
+	oldPub := ed25519.NewKeyFromSeed(testSeed).Public().(ed25519.PublicKey)
+	if issued.KeyID != KeyID(oldPub) {
+		t.Errorf("kid = %q, want %q", issued.KeyID, KeyID(oldPub))
+	}
+
+	rotated, err := NewSecurityModuleFromSeed(bytes.Repeat([]byte{9}, ed25519.SeedSize))
+	if err != nil {
+		t.Fatalf("NewSecurityModuleFromSeed() error = %v", err)
+	}
+	if valid, err := rotated.VerifyCertificate(payload, sig); valid || !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("VerifyCertificate() before registering = %v, %v, want false, %v", valid, err, ErrUnknownKeyID)
+	}
+
+	if err := rotated.RegisterKey(issued.KeyID, oldPub); err != nil {
+		t.Fatalf("RegisterKey() error = %v", err)
+	}
+	if valid, err := rotated.VerifyCertificate(payload, sig); err != nil || !valid {
+		t.Errorf("VerifyCertificate() after rotation = %v, %v, want true", valid, err)
+	}
+
+	// New certificates are still signed and verified with the current key
+	payload, sig, err = rotated.IssueCertificate(0.8, "u2", VersionStamp{})
+	if err != nil {
+		t.Fatalf("IssueCertificate() error = %v", err)
+	}
+	if valid, err := rotated.VerifyCertificate(payload, sig); err != nil || !valid {
+		t.Errorf("VerifyCertificate() with the current key = %v, %v, want true", valid, err)
+	}
+}
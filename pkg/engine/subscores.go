@@ -0,0 +1,48 @@
+package engine
+
+import "math"
+
+// SubScoreSet breaks a feature vector down into four interpretable 0-1 sub-scores.
+// They are derived directly from MapFeatures output and do not use the model.
+type SubScoreSet struct {
+	// IncomeHealth rewards earning more than is spent and earning it steadily.
+	// 0.5 * min(profitability_ratio/2, 1) + 0.5 * (1 - min(income_regularity, 1)).
+	// It is 0 when no income was detected.
+	IncomeHealth float64 `json:"income_health"`
+
+	// DebtLoad grows as borrowing dominates income (higher is worse).
+	// 0.6 * min(emergency_reliance, 1) + 0.4 * min(lender_diversity/3, 1).
+	DebtLoad float64 `json:"debt_load"`
+
+	// SpendingDiscipline penalises betting; half of spend going to gambling scores 0.
+	// 1 - min(2 * gambling_index, 1).
+	SpendingDiscipline float64 `json:"spending_discipline"`
+
+	// SavingsBehavior rewards putting money aside; a 20% savings rate earns full credit.
+	// 0.7 * min(5 * savings_rate, 1) + 0.3 * min(bank_activity/5, 1).
+	SavingsBehavior float64 `json:"savings_behavior"`
+}
+
+// SubScores maps feature groups into a SubScoreSet using the formulas documented on its fields.
+// Vectors shorter than FeatureCount yield all-zero sub-scores.
+func SubScores(features []float64) SubScoreSet {
+	if len(features) < FeatureCount {
+		return SubScoreSet{}
+	}
+
+	var s SubScoreSet
+
+	if features[0] > 0 {
+		s.IncomeHealth = 0.5*clamp01(features[2]/2) + 0.5*(1-clamp01(features[5]))
+	}
+	s.DebtLoad = 0.6*clamp01(features[17]) + 0.4*clamp01(features[16]/3)
+	s.SpendingDiscipline = 1 - clamp01(2*features[6])
+	s.SavingsBehavior = 0.7*clamp01(5*features[18]) + 0.3*clamp01(features[19]/5)
+
+	return s
+}
+
+// clamp01 limits v to the [0, 1] range.
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
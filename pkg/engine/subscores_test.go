@@ -0,0 +1,48 @@
+package engine
+
+import "testing"
+
+func TestSubScores_HeavyGambler(t *testing.T) {
+	features := mapLogs(t, []string{
+		"UA34ABCDEF Confirmed. You have received Ksh50,000.00 from JOHN DOE 0712345678",
+		"Betika: Your bet of Ksh10,000.00 has been placed",
+		"Betika: Your bet of Ksh10,000.00 has been placed",
+	})
+
+	s := SubScores(features)
+	if s.SpendingDiscipline > 0.2 {
+		t.Errorf("SpendingDiscipline = %v, want <= 0.2 for a heavy gambler", s.SpendingDiscipline)
+	}
+	if s.IncomeHealth < 0.5 {
+		t.Errorf("IncomeHealth = %v, want >= 0.5 for a well-paid gambler", s.IncomeHealth)
+	}
+}
+
+func TestSubScores_Range(t *testing.T) {
+	features := mapLogs(t, []string{
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+		"You have received Ksh5,000.00 from Tala",
+		"M-Shwari. You have deposited Ksh1,000.00 to your savings",
+	})
+
+	s := SubScores(features)
+	for name, v := range map[string]float64{
+		"IncomeHealth":       s.IncomeHealth,
+		"DebtLoad":           s.DebtLoad,
+		"SpendingDiscipline": s.SpendingDiscipline,
+		"SavingsBehavior":    s.SavingsBehavior,
+	} {
+		if v < 0 || v > 1 {
+			t.Errorf("%s = %v out of range [0, 1]", name, v)
+		}
+	}
+	if s.DebtLoad == 0 {
+		t.Error("DebtLoad should be positive for a borrower")
+	}
+}
+
+func TestSubScores_ShortVector(t *testing.T) {
+	if s := SubScores(make([]float64, 5)); s != (SubScoreSet{}) {
+		t.Errorf("SubScores(short) = %+v, want zero value", s)
+	}
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+
+	"borehole/core/pkg/parser"
+)
+
+// topMerchantCount caps Summary.TopMerchants.
+const topMerchantCount = 5
+
+// Summary is a plain financial overview of a transaction history, built from
+// the same aggregates as the feature vector but without a model score.
+type Summary struct {
+	Income           float64         `json:"income"`
+	Expenses         float64         `json:"expenses"`
+	Net              float64         `json:"net"`        // Income minus expenses
+	NetIncome        float64         `json:"net_income"` // Income excluding loan disbursements
+	LoanLoad         float64         `json:"loan_load"`  // See NetOutstandingDebt
+	DebtServiceRatio float64         `json:"debt_service_ratio"`
+	SavingsRate      float64         `json:"savings_rate"`
+	GamblingIndex    float64         `json:"gambling_index"`
+	TopMerchants     []MerchantTotal `json:"top_merchants"`
+	Months           []MonthSummary  `json:"months"`
+}
+
+// MerchantTotal is the net amount paid to one paybill or till.
+type MerchantTotal struct {
+	Name  string  `json:"name"`
+	Total float64 `json:"total"`
+}
+
+// MonthSummary totals the dated transactions of one calendar month.
+type MonthSummary struct {
+	Month    string  `json:"month"` // "2006-01"
+	Income   float64 `json:"income"`
+	Expenses float64 `json:"expenses"`
+	Net      float64 `json:"net"`
+	TxnCount int     `json:"txn_count"`
+}
+
+// Summarize builds a Summary of txns. Months are in calendar order and skip
+// undated transactions; top merchants are ordered by total, largest first.
+func Summarize(txns []parser.Transaction) Summary {
+	acc := newFeatureAccumulator(MapperConfig{})
+	months := make(map[string]*featureAccumulator)
+	merchants := make(map[string]float64)
+	for _, txn := range txns {
+		acc.add(txn)
+		if !txn.Timestamp.IsZero() {
+			key := txn.Timestamp.Format("2006-01")
+			if months[key] == nil {
+				months[key] = newFeatureAccumulator(MapperConfig{})
+			}
+			months[key].add(txn)
+		}
+		if name := merchantName(txn); name != "" {
+			if txn.Reversal {
+				merchants[name] -= txn.Amount
+			} else {
+				merchants[name] += txn.Amount
+			}
+		}
+	}
+
+	features := make([]float64, FeatureCount)
+	acc.fill(features)
+	s := Summary{
+		Income:           features[0],
+		Expenses:         features[1],
+		Net:              features[0] - features[1],
+		NetIncome:        acc.netIncome(),
+		LoanLoad:         acc.outstandingDebt(),
+		DebtServiceRatio: features[23],
+		SavingsRate:      features[18],
+		GamblingIndex:    features[6],
+		TopMerchants:     []MerchantTotal{},
+		Months:           []MonthSummary{},
+	}
+
+	for name, total := range merchants {
+		if total > 0 {
+			s.TopMerchants = append(s.TopMerchants, MerchantTotal{Name: name, Total: total})
+		}
+	}
+	sort.Slice(s.TopMerchants, func(i, j int) bool {
+		a, b := s.TopMerchants[i], s.TopMerchants[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Name < b.Name
+	})
+	if len(s.TopMerchants) > topMerchantCount {
+		s.TopMerchants = s.TopMerchants[:topMerchantCount]
+	}
+
+	for key, m := range months {
+		income, expenses := m.money(m.totalIncome), m.money(m.totalExpenses)
+		s.Months = append(s.Months, MonthSummary{
+			Month:    key,
+			Income:   income,
+			Expenses: expenses,
+			Net:      income - expenses,
+			TxnCount: m.txnCount,
+		})
+	}
+	sort.Slice(s.Months, func(i, j int) bool { return s.Months[i].Month < s.Months[j].Month })
+
+	return s
+}
+
+// merchantName returns the payee of a paybill or till payment, or "".
+func merchantName(txn parser.Transaction) string {
+	switch txn.Type {
+	case parser.TxnMPesaPaybill, parser.TxnMPesaBuyGoods, parser.TxnUtility:
+		return merchantKey(txn.Recipient)
+	default:
+		return ""
+	}
+}
+
+// merchantQualifiers are the words that start the account or till details a
+// payment message appends to the payee's name.
+var merchantQualifiers = map[string]bool{"ACCOUNT": true, "ACC": true, "ACC.": true, "A/C": true, "TILL": true}
+
+// merchantKey returns the canonical name a payee is totalled under: the
+// payee up to any account, till or date details, so a payment to
+// "KPLC Account 12345 on 3" and its reversal naming "KPLC" share one total.
+// A name that is nothing but details is kept whole.
+func merchantKey(recipient string) string {
+	tokens := strings.Fields(CanonicalizeName(recipient))
+	for i := 1; i < len(tokens); i++ {
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		tok := tokens[i]
+		if merchantQualifiers[tok] || (tok == "FOR" && merchantQualifiers[next]) ||
+			(tok == "ON" && next != "" && next[0] >= '0' && next[0] <= '9') {
+			tokens = tokens[:i]
+			break
+		}
+	}
+	return strings.Join(tokens, " ")
+}
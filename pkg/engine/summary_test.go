@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"borehole/core/pkg/parser"
+)
+
+func TestSummarize(t *testing.T) {
+	jan := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC)
+	txns := []parser.Transaction{
+		{Type: parser.TxnMPesaReceived, Amount: 10000, Timestamp: jan},
+		{Type: parser.TxnMPesaPaybill, Amount: 2000, Recipient: "KPLC", Timestamp: jan},
+		{Type: parser.TxnMPesaBuyGoods, Amount: 500, Recipient: "NAIVAS", Timestamp: feb},
+		{Type: parser.TxnMPesaBuyGoods, Amount: 300, Recipient: "NAIVAS", Timestamp: feb},
+		{Type: parser.TxnDigitalLoan, Amount: 3000, Lender: "Tala", Timestamp: feb},
+		{Type: parser.TxnDigitalRepay, Amount: 1000, Lender: "Tala"}, // Undated
+	}
+
+	s := Summarize(txns)
+	if s.Income != 13000 || s.Expenses != 3800 || s.Net != 9200 {
+		t.Errorf("income, expenses, net = %v, %v, %v, want 13000, 3800, 9200", s.Income, s.Expenses, s.Net)
+	}
+	if s.NetIncome != 10000 || s.LoanLoad != 2000 {
+		t.Errorf("net_income, loan_load = %v, %v, want 10000, 2000", s.NetIncome, s.LoanLoad)
+	}
+
+	wantMerchants := []MerchantTotal{{"KPLC", 2000}, {"NAIVAS", 800}}
+	if len(s.TopMerchants) != len(wantMerchants) {
+		t.Fatalf("TopMerchants = %+v, want %+v", s.TopMerchants, wantMerchants)
+	}
+	for i, want := range wantMerchants {
+		if s.TopMerchants[i] != want {
+			t.Errorf("TopMerchants[%d] = %+v, want %+v", i, s.TopMerchants[i], want)
+		}
+	}
+
+	wantMonths := []MonthSummary{
+		{Month: "2026-01", Income: 10000, Expenses: 2000, Net: 8000, TxnCount: 2},
+		{Month: "2026-02", Income: 3000, Expenses: 800, Net: 2200, TxnCount: 3},
+	}
+	if len(s.Months) != len(wantMonths) {
+		t.Fatalf("Months = %+v, want %+v", s.Months, wantMonths)
+	}
+	for i, want := range wantMonths {
+		if s.Months[i] != want {
+			t.Errorf("Months[%d] = %+v, want %+v", i, s.Months[i], want)
+		}
+	}
+}
+
+func TestSummarize_MerchantReversalNets(t *testing.T) {
+	txns, err := parser.NewParser().ParseLogs(context.Background(), []string{
+		"UA00WATER1 Confirmed. Ksh800.00 paid to NAIROBI WATER Account 12345 on 3/2/26.",
+		"Your payment to NAIROBI WATER of Ksh800 has been reversed",
+		"UA00KPLC01 Confirmed. Ksh1,000.00 paid to KPLC Account 12345",
+	})
+	if err != nil {
+		t.Fatalf("ParseLogs() error = %v", err)
+	}
+	if len(txns) != 3 || !txns[1].Reversal {
+		t.Fatalf("ParseLogs() = %+v, want a payment, its reversal and another payment", txns)
+	}
+
+	// The reversed payment nets to zero and drops out of the ranking
+	want := []MerchantTotal{{"KPLC", 1000}}
+	s := Summarize(txns)
+	if len(s.TopMerchants) != len(want) || s.TopMerchants[0] != want[0] {
+		t.Errorf("TopMerchants = %+v, want %+v", s.TopMerchants, want)
+	}
+}
+
+func TestMerchantKey(t *testing.T) {
+	tests := map[string]string{
+		"KPLC":                             "KPLC",
+		"KPLC Account 12345":               "KPLC",
+		"NAIROBI WATER Account 12345 on 3": "NAIROBI WATER",
+		"EQUITY PAYBILL for account 07123": "EQUITY PAYBILL",
+		"SUPERMARKET ":                     "SUPERMARKET",
+		"  naivas  ":                       "NAIVAS",
+		"Account 12345":                    "ACCOUNT 12345",
+	}
+	for recipient, want := range tests {
+		if got := merchantKey(recipient); got != want {
+			t.Errorf("merchantKey(%q) = %q, want %q", recipient, got, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"borehole/core/pkg/engine"
@@ -11,16 +12,25 @@ import (
 
 // MobileEngine is the JNI-compatible bridge for Android integration.
 type MobileEngine struct {
-	parser parser.Parser
+	parser         parser.Parser
+	scorePrecision int
 }
 
 // NewMobileEngine initializes the bridge. Engine is managed as a singleton.
 func NewMobileEngine() *MobileEngine {
 	return &MobileEngine{
-		parser: parser.NewParser(),
+		parser:         parser.NewParser(),
+		scorePrecision: engine.FullPrecision,
 	}
 }
 
+// SetScorePrecision sets the number of decimal places used when serializing
+// scores in CalculateBoreholeScore. A negative value keeps full precision.
+// Certificates are always issued over the score passed to GenerateSignedScore.
+func (m *MobileEngine) SetScorePrecision(precision int) {
+	m.scorePrecision = precision
+}
+
 // CalculateBoreholeScore orchestrates the full ETL and Inference pipeline.
 // Parser (ETL) -> Mapper (Transform) -> Engine (Inference) -> Result (Output).
 func (m *MobileEngine) CalculateBoreholeScore(jsonLogs string) string {
@@ -36,7 +46,7 @@ func (m *MobileEngine) CalculateBoreholeScore(jsonLogs string) string {
 		return fmt.Sprintf(`{"error": "parsing_failed", "details": "%v"}`, err)
 	}
 
-	// 2. Transform: Map transactions to 20-dimension feature vector
+	// 2. Transform: Map transactions to the feature vector (see engine.FeatureNames)
 	features := engine.MapFeatures(txns)
 
 	// 3. Inference: Get prediction from singleton ML engine
@@ -47,12 +57,18 @@ func (m *MobileEngine) CalculateBoreholeScore(jsonLogs string) string {
 	}
 
 	score := mlEngine.Predict(features)
+	stamp := mlEngine.Stamp()
 
 	// 4. Output: Package results for React Native
 	result := parser.ScoreResult{
-		Score:    score,
-		Features: features,
-		TxnCount: len(txns),
+		Score:             engine.RoundScore(score, m.scorePrecision),
+		Features:          features,
+		TxnCount:          len(txns),
+		Explanation:       engine.Explain(features),
+		EngineVersion:     stamp.EngineVersion,
+		ModelHash:         stamp.ModelHash,
+		CalibrationHash:   stamp.CalibrationHash,
+		FeatureSchemaHash: stamp.FeatureSchemaHash,
 	}
 
 	resBytes, _ := json.Marshal(result)
@@ -63,12 +79,16 @@ func (m *MobileEngine) CalculateBoreholeScore(jsonLogs string) string {
 // Returns a JSON string containing {payload, signature, public_key}.
 func (m *MobileEngine) GenerateSignedScore(score float64) string {
 	sec := engine.GetSecurityModule()
+	mlEngine, err := engine.GetEngine()
+	if err != nil {
+		return fmt.Sprintf(`{"error": "engine_initialization_failed", "details": "%v"}`, err)
+	}
 
 	// For MVP, we use a random Anonymous ID.
 	// In production, this would be a hash of the device ID or user ID.
 	uid := "anon_user_xyz"
 
-	payloadStr, signature, err := sec.IssueCertificate(score, uid)
+	payloadStr, signature, err := sec.IssueCertificate(score, uid, mlEngine.Stamp())
 	if err != nil {
 		return fmt.Sprintf(`{"error": "signing_failed", "details": "%v"}`, err)
 	}
@@ -82,3 +102,44 @@ func (m *MobileEngine) GenerateSignedScore(score float64) string {
 	bytes, _ := json.Marshal(response)
 	return string(bytes)
 }
+
+// VerifiedScore is the result of VerifySignedScore.
+type VerifiedScore struct {
+	Valid   bool    `json:"valid"`
+	Expired bool    `json:"expired"`
+	Score   float64 `json:"score"`
+}
+
+// VerifySignedScore checks a certificate produced by GenerateSignedScore, e.g.
+// before reusing a cached score. The signature is checked against the
+// engine's own key chosen by the payload's kid, never the public_key the
+// certificate carries, so a certificate signed with any other key is invalid.
+// Returns a JSON string containing {valid, expired, score}.
+func (m *MobileEngine) VerifySignedScore(jsonCert string) string {
+	var cert struct {
+		Payload   string `json:"payload"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal([]byte(jsonCert), &cert); err != nil {
+		return `{"error": "invalid_json_input"}`
+	}
+
+	valid, err := engine.GetSecurityModule().VerifyCertificate(cert.Payload, cert.Signature)
+	expired := errors.Is(err, engine.ErrCertificateExpired)
+	if errors.Is(err, engine.ErrUnknownKeyID) {
+		valid, err = false, nil // Signed by a key the engine does not trust
+	}
+	if err != nil && !expired {
+		return fmt.Sprintf(`{"error": "verification_failed", "details": "%v"}`, err)
+	}
+
+	// The score is only reported from a payload the key signed
+	result := VerifiedScore{Valid: valid, Expired: expired}
+	var payload engine.CertificatePayload
+	if (valid || expired) && json.Unmarshal([]byte(cert.Payload), &payload) == nil {
+		result.Score = payload.Score
+	}
+
+	resBytes, _ := json.Marshal(result)
+	return string(resBytes)
+}
@@ -0,0 +1,154 @@
+package mobile
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"borehole/core/pkg/engine"
+	"borehole/core/pkg/parser"
+)
+
+func TestCalculateBoreholeScore_Precision(t *testing.T) {
+	logs := []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+	}
+	input, _ := json.Marshal(logs)
+
+	// Full-precision reference score computed straight from the pipeline
+	txns, err := parser.NewParser().ParseLogs(context.Background(), logs)
+	if err != nil {
+		t.Fatalf("ParseLogs() error = %v", err)
+	}
+	mlEngine, err := engine.GetEngine()
+	if err != nil {
+		t.Fatalf("GetEngine() error = %v", err)
+	}
+	fullScore := mlEngine.Predict(engine.MapFeatures(txns))
+
+	m := NewMobileEngine()
+	m.SetScorePrecision(2)
+
+	var result parser.ScoreResult
+	if err := json.Unmarshal([]byte(m.CalculateBoreholeScore(string(input))), &result); err != nil {
+		t.Fatalf("invalid result JSON: %v", err)
+	}
+	if want := engine.RoundScore(fullScore, 2); result.Score != want {
+		t.Errorf("Score = %v, want %v", result.Score, want)
+	}
+
+	// The certificate signs whatever score it is handed, unrounded
+	var cert map[string]string
+	if err := json.Unmarshal([]byte(m.GenerateSignedScore(fullScore)), &cert); err != nil {
+		t.Fatalf("invalid certificate JSON: %v", err)
+	}
+	var payload engine.CertificatePayload
+	if err := json.Unmarshal([]byte(cert["payload"]), &payload); err != nil {
+		t.Fatalf("invalid payload JSON: %v", err)
+	}
+	if payload.Score != fullScore {
+		t.Errorf("signed Score = %v, want full precision %v", payload.Score, fullScore)
+	}
+}
+
+func TestCalculateBoreholeScore_VersionStamp(t *testing.T) {
+	var result parser.ScoreResult
+	if err := json.Unmarshal([]byte(NewMobileEngine().CalculateBoreholeScore(`["Fuliza M-PESA. You have borrowed Ksh2,000.00"]`)), &result); err != nil {
+		t.Fatalf("invalid result JSON: %v", err)
+	}
+
+	mlEngine, _ := engine.GetEngine()
+	stamp := mlEngine.Stamp()
+	if result.EngineVersion != stamp.EngineVersion || result.ModelHash != stamp.ModelHash ||
+		result.FeatureSchemaHash != stamp.FeatureSchemaHash {
+		t.Errorf("result stamp = {%q %q %q}, want %+v",
+			result.EngineVersion, result.ModelHash, result.FeatureSchemaHash, stamp)
+	}
+	if result.ModelHash == "" {
+		t.Error("ModelHash missing from result")
+	}
+}
+
+func TestCalculateBoreholeScore_DefaultFullPrecision(t *testing.T) {
+	m := NewMobileEngine()
+
+	var result parser.ScoreResult
+	if err := json.Unmarshal([]byte(m.CalculateBoreholeScore(`["Fuliza M-PESA. You have borrowed Ksh2,000.00"]`)), &result); err != nil {
+		t.Fatalf("invalid result JSON: %v", err)
+	}
+	if result.Score == engine.RoundScore(result.Score, 4) {
+		t.Errorf("Score = %v, expected unrounded value by default", result.Score)
+	}
+}
+
+func TestCalculateBoreholeScore_Explanation(t *testing.T) {
+	var result parser.ScoreResult
+	if err := json.Unmarshal([]byte(NewMobileEngine().CalculateBoreholeScore(`["Fuliza M-PESA. You have borrowed Ksh2,000.00"]`)), &result); err != nil {
+		t.Fatalf("invalid result JSON: %v", err)
+	}
+	if want := engine.Explain(result.Features); len(want) == 0 || !slices.Equal(result.Explanation, want) {
+		t.Errorf("Explanation = %q, want %q", result.Explanation, want)
+	}
+}
+
+func TestVerifySignedScore(t *testing.T) {
+	m := NewMobileEngine()
+	signed := m.GenerateSignedScore(0.72)
+
+	var result VerifiedScore
+	if err := json.Unmarshal([]byte(m.VerifySignedScore(signed)), &result); err != nil {
+		t.Fatalf("invalid result JSON: %v", err)
+	}
+	if want := (VerifiedScore{Valid: true, Score: 0.72}); result != want {
+		t.Errorf("VerifySignedScore() = %+v, want %+v", result, want)
+	}
+
+	// Swapping in another certificate's signature invalidates it
+	var cert, other map[string]string
+	if err := json.Unmarshal([]byte(signed), &cert); err != nil {
+		t.Fatalf("invalid certificate JSON: %v", err)
+	}
+	if err := json.Unmarshal([]byte(m.GenerateSignedScore(0.1)), &other); err != nil {
+		t.Fatalf("invalid certificate JSON: %v", err)
+	}
+	cert["signature"] = other["signature"]
+	tampered, _ := json.Marshal(cert)
+	result = VerifiedScore{}
+	if err := json.Unmarshal([]byte(m.VerifySignedScore(string(tampered))), &result); err != nil {
+		t.Fatalf("invalid result JSON: %v", err)
+	}
+	if result.Valid || result.Score != 0 {
+		t.Errorf("VerifySignedScore(tampered) = %+v, want invalid with no score", result)
+	}
+
+	// A certificate signed with another key does not verify, even though it
+	// carries that key
+	forger, err := engine.NewSecurityModuleFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	if err != nil {
+		t.Fatalf("NewSecurityModuleFromSeed() error = %v", err)
+	}
+	payload, sig, err := forger.IssueCertificate(0.99, "anon_user_xyz", engine.VersionStamp{})
+	if err != nil {
+		t.Fatalf("IssueCertificate() error = %v", err)
+	}
+	forged, _ := json.Marshal(map[string]string{
+		"payload":    payload,
+		"signature":  sig,
+		"public_key": forger.GetPublicKeyBase64(),
+	})
+	result = VerifiedScore{}
+	if err := json.Unmarshal([]byte(m.VerifySignedScore(string(forged))), &result); err != nil {
+		t.Fatalf("invalid result JSON: %v", err)
+	}
+	if result.Valid || result.Score != 0 {
+		t.Errorf("VerifySignedScore(self-signed) = %+v, want invalid with no score", result)
+	}
+
+	if got := m.VerifySignedScore("{"); got != `{"error": "invalid_json_input"}` {
+		t.Errorf("VerifySignedScore(malformed) = %s, want invalid_json_input", got)
+	}
+}
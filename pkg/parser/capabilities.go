@@ -0,0 +1,37 @@
+package parser
+
+// providerFamilies lists the providers the parser recognises, in display form,
+// grouped by the pattern that detects them. Keep it in step with patterns.go.
+var providerFamilies = []struct {
+	family string
+	names  []string
+}{
+	{"wallet", []string{"M-Pesa", "Fuliza", "T-Kash", "Airtel Money", "Hustler Fund", "Okoa Jahazi"}},
+	{"savings", []string{"M-Shwari", "KCB M-Pesa", "Mali", "Stawi"}},
+	{"lender", []string{"Tala", "Branch", "Zenka", "Zash", "Okolea", "Timiza", "Berry", "Kashway"}},
+	{"bank", []string{"KCB", "Equity", "Co-op", "NCBA", "Stanbic", "Absa", "DTB", "I&M", "Family Bank", "Bank of Africa"}},
+	{"remittance", []string{"WorldRemit", "Western Union", "Remitly", "Sendwave"}},
+	{"betting", []string{"Betika", "SportPesa", "Mozzart", "Odibets", "Betway", "1xBet", "Betin", "Dafabet", "22Bet", "Helabet"}},
+	{"utility", []string{"KPLC", "Kenya Power", "Nairobi Water", "Safaricom Home", "Zuku", "DSTV", "GOtv", "StarTimes"}},
+}
+
+// SupportedTypes returns the String form of every TransactionType the parser
+// can emit, excluding TxnUnknown.
+func SupportedTypes() []string {
+	types := make([]string, 0, numTransactionTypes-1)
+	for t := TxnUnknown + 1; t < numTransactionTypes; t++ {
+		types = append(types, t.String())
+	}
+	return types
+}
+
+// SupportedProviders returns the names of the mobile money wallets, savings
+// products, lenders, banks, remittance services, betting sites and utilities
+// the parser recognises.
+func SupportedProviders() []string {
+	var providers []string
+	for _, f := range providerFamilies {
+		providers = append(providers, f.names...)
+	}
+	return providers
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestSupportedTypes(t *testing.T) {
+	types := SupportedTypes()
+	if len(types) == 0 {
+		t.Fatal("SupportedTypes() is empty")
+	}
+	for _, want := range []string{"MPESA_RECEIVED", "FULIZA_LOAN", "AIRTIME_GIFT"} {
+		if !slices.Contains(types, want) {
+			t.Errorf("SupportedTypes() missing %q", want)
+		}
+	}
+	for _, got := range types {
+		if got == "UNKNOWN" {
+			t.Errorf("SupportedTypes() contains %q; every type needs a String case", got)
+		}
+	}
+}
+
+func TestSupportedProviders(t *testing.T) {
+	providers := SupportedProviders()
+	if len(providers) == 0 {
+		t.Fatal("SupportedProviders() is empty")
+	}
+	for _, want := range []string{"M-Pesa", "Airtel Money", "T-Kash", "Fuliza", "Hustler Fund", "Equity", "Tala", "Betika"} {
+		if !slices.Contains(providers, want) {
+			t.Errorf("SupportedProviders() missing %q", want)
+		}
+	}
+}
+
+// TestSupportedProviders_MatchPatterns keeps the advertised names in step with
+// the patterns that detect them.
+func TestSupportedProviders_MatchPatterns(t *testing.T) {
+	patterns := map[string]*regexp.Regexp{
+		"savings":    mmfPattern,
+		"lender":     digitalLenderPattern,
+		"bank":       bankTransferPattern,
+		"remittance": remittanceMentionPattern,
+		"betting":    gamblingPattern,
+		"utility":    utilityPattern,
+	}
+	for _, f := range providerFamilies {
+		re, ok := patterns[f.family]
+		if !ok {
+			continue
+		}
+		for _, name := range f.names {
+			if !re.MatchString(name) {
+				t.Errorf("%s provider %q is not matched by its pattern", f.family, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// statementDateLayouts are the date formats accepted in the CSV date column.
+var statementDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02/01/2006 15:04",
+	"02/01/2006",
+}
+
+// ParseCSVStatement parses a bank or telco statement export into transactions.
+//
+// Each record must have four columns, in this order:
+//
+//	date, description, amount, type
+//
+// date uses one of the layouts in statementDateLayouts (e.g. "2026-01-20").
+// amount accepts the same formats as SMS amounts ("Ksh1,500.00", "1500").
+// type is "credit"/"cr" for money in or "debit"/"dr" for money out. When type
+// is empty, a minus sign on the amount ("-500", "Ksh-500.00") marks a debit.
+// Amounts are always stored as magnitudes.
+// A first row whose date column reads "date" is treated as a header and skipped.
+//
+// Descriptions are classified with the same keyword routing used for SMS, so
+// "Hustler Fund repayment" becomes TxnHustlerRepay and "Betika deposit" TxnGamblingStake.
+func ParseCSVStatement(r io.Reader) ([]Transaction, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	var txns []Transaction
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading statement: %w", err)
+		}
+
+		if row == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+
+		txn, err := parseStatementRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("statement row %d: %w", row, err)
+		}
+		txns = append(txns, txn)
+	}
+
+	return txns, nil
+}
+
+// parseStatementRecord converts one date, description, amount, type record.
+func parseStatementRecord(record []string) (Transaction, error) {
+	dateStr := strings.TrimSpace(record[0])
+	description := strings.TrimSpace(record[1])
+	amountStr := strings.TrimSpace(record[2])
+	direction := strings.ToLower(strings.TrimSpace(record[3]))
+
+	timestamp, err := parseStatementDate(dateStr)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	amount, negative := parseSignedAmount(amountStr)
+	if amount == 0 && !isZeroAmount(amountStr) {
+		return Transaction{}, fmt.Errorf("invalid amount %q", record[2])
+	}
+
+	var credit bool
+	switch direction {
+	case "credit", "cr":
+		credit = true
+	case "debit", "dr":
+		credit = false
+	case "":
+		credit = !negative
+	default:
+		return Transaction{}, fmt.Errorf("unknown transaction type %q", record[3])
+	}
+
+	txn := Transaction{
+		Type:       classifyDescription(description, credit),
+		Amount:     amount,
+		Timestamp:  timestamp,
+		RawText:    description,
+		Confidence: ConfidenceExact,
+	}
+	if credit {
+		txn.Sender = description
+	} else {
+		txn.Recipient = description
+	}
+	if txn.Type == TxnDigitalLoan || txn.Type == TxnDigitalRepay {
+		txn.Lender = digitalLenderPattern.FindString(description)
+	}
+	if txn.Type == TxnKCBLoan || txn.Type == TxnKCBRepay {
+		txn.Lender = kcbLender
+	}
+
+	return txn, nil
+}
+
+// isZeroAmount reports whether s spells out zero ("0", "0.00", "Ksh 0")
+// rather than being unparseable.
+func isZeroAmount(s string) bool {
+	digits := strings.TrimLeft(strings.ToUpper(s), "KESH.- ")
+	return digits != "" && strings.Trim(digits, "0.,") == ""
+}
+
+// parseStatementDate tries each supported layout in turn.
+func parseStatementDate(s string) (time.Time, error) {
+	for _, layout := range statementDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+}
+
+// classifyDescription maps a statement description and its direction to a
+// TransactionType, reusing the SMS keyword routing.
+func classifyDescription(description string, credit bool) TransactionType {
+	pick := func(in, out TransactionType) TransactionType {
+		if credit {
+			return in
+		}
+		return out
+	}
+
+	descUpper := strings.ToUpper(description)
+	switch detectProvider(description, defaultVocabulary) {
+	case providerAirtel:
+		return pick(TxnAirtelReceived, TxnAirtelSent)
+	case providerHustler:
+		return pick(TxnHustlerLoan, TxnHustlerRepay)
+	case providerOkoa:
+		return pick(TxnOkoaReceived, TxnOkoaDebt)
+	case providerKCBLoan:
+		return pick(TxnKCBLoan, TxnKCBRepay)
+	case providerMMF:
+		return pick(TxnMMFWithdraw, TxnMMFDeposit)
+	case providerDigitalLender:
+		return pick(TxnDigitalLoan, TxnDigitalRepay)
+	case providerTKash:
+		return pick(TxnTKashReceived, TxnTKashSent)
+	case providerFuliza:
+		return pick(TxnFulizaLoan, TxnFulizaRepay)
+	}
+
+	switch {
+	case gamblingPattern.MatchString(description):
+		return pick(TxnGamblingWin, TxnGamblingStake)
+	case credit && remittanceMentionPattern.MatchString(description):
+		return TxnRemittanceReceived
+	case !credit && bankTransferPattern.MatchString(description) && bankLoanRepayPattern.MatchString(description):
+		return TxnBankLoanRepay
+	case bankTransferPattern.MatchString(description):
+		return pick(TxnBankWithdraw, TxnBankDeposit)
+	case !credit && (strings.Contains(descUpper, "PAYBILL") || strings.Contains(descUpper, "PAY BILL")):
+		return paybillType(description)
+	case strings.Contains(descUpper, "AGENT"): // "Customer Withdrawal At Agent Till" names a till too
+		return pick(TxnAgentDeposit, TxnAgentWithdraw)
+	case !credit && (strings.Contains(descUpper, "TILL") || strings.Contains(descUpper, "BUY GOODS")):
+		return TxnMPesaBuyGoods
+	default:
+		return pick(TxnMPesaReceived, TxnMPesaSent)
+	}
+}
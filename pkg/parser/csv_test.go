@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSVStatement(t *testing.T) {
+	statement := `date,description,amount,type
+2026-01-20,Salary from ACME LTD,"Ksh45,000.00",credit
+2026-01-21,Pay Bill KPLC 888880,1500,debit
+2026-01-22,Hustler Fund repayment,500,dr
+2026-01-23,Betika deposit,-200,
+2026-01-24,Loan from Tala,"5,000",cr
+2026-01-25,Sent to JANE DOE,Ksh0.00,debit
+2026-01-26,Sent to JOHN DOE,Ksh-750.00,
+2026-01-27,Customer Withdrawal At Agent Till 654321,"1,000",debit
+`
+
+	txns, err := ParseCSVStatement(strings.NewReader(statement))
+	if err != nil {
+		t.Fatalf("ParseCSVStatement() error = %v", err)
+	}
+
+	want := []struct {
+		txnType TransactionType
+		amount  float64
+	}{
+		{TxnMPesaReceived, 45000},
+		{TxnUtility, 1500},
+		{TxnHustlerRepay, 500},
+		{TxnGamblingStake, 200},
+		{TxnDigitalLoan, 5000},
+		{TxnMPesaSent, 0},
+		{TxnMPesaSent, 750},
+		{TxnAgentWithdraw, 1000},
+	}
+
+	if len(txns) != len(want) {
+		t.Fatalf("ParseCSVStatement() returned %d transactions, want %d", len(txns), len(want))
+	}
+	for i, w := range want {
+		if txns[i].Type != w.txnType {
+			t.Errorf("txns[%d].Type = %v, want %v", i, txns[i].Type, w.txnType)
+		}
+		if txns[i].Amount != w.amount {
+			t.Errorf("txns[%d].Amount = %v, want %v", i, txns[i].Amount, w.amount)
+		}
+	}
+
+	if wantDate := time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC); !txns[0].Timestamp.Equal(wantDate) {
+		t.Errorf("txns[0].Timestamp = %v, want %v", txns[0].Timestamp, wantDate)
+	}
+	if txns[4].Lender != "Tala" {
+		t.Errorf("txns[4].Lender = %q, want %q", txns[4].Lender, "Tala")
+	}
+}
+
+func TestParseCSVStatement_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+	}{
+		{"bad date", "yesterday,Sent to JOHN,100,debit\n"},
+		{"bad amount", "2026-01-20,Sent to JOHN,lots,debit\n"},
+		{"bad type", "2026-01-20,Sent to JOHN,100,sideways\n"},
+		{"wrong column count", "2026-01-20,Sent to JOHN,100\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCSVStatement(strings.NewReader(tt.statement)); err == nil {
+				t.Error("ParseCSVStatement() should return an error")
+			}
+		})
+	}
+}
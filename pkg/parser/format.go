@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NumberFormat describes how a market writes amounts in SMS.
+type NumberFormat struct {
+	Decimal   rune // Separates whole units from cents, e.g. '.' in "1,500.00"
+	Thousands rune // Groups digits in threes, e.g. ',' in "1,500.00"
+}
+
+// KenyanNumberFormat is the comma-thousands, dot-decimal layout used by
+// Safaricom, Airtel and Telkom Kenya ("Ksh1,500.00"). It is the default.
+var KenyanNumberFormat = NumberFormat{Decimal: '.', Thousands: ','}
+
+// orDefault returns f, or KenyanNumberFormat for the zero value.
+func (f NumberFormat) orDefault() NumberFormat {
+	if f.Decimal == 0 {
+		return KenyanNumberFormat
+	}
+	return f
+}
+
+// parseSignedAmount reads s in format f. See the package-level parseSignedAmount.
+func (f NumberFormat) parseSignedAmount(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	// Remove common prefixes, whitespace and the sign
+	s = strings.TrimSpace(normalizeText(s))
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	unprefixed := len(s)
+	s = strings.TrimPrefix(s, "Ksh")
+	s = strings.TrimPrefix(s, "ksh")
+	s = strings.TrimPrefix(s, "KES")
+	s = strings.TrimPrefix(s, "kes")
+	// The full stop in "KES. 5,000" ends the abbreviation; without a prefix
+	// a leading "." is a decimal point
+	if len(s) < unprefixed {
+		s = strings.TrimPrefix(s, ".")
+	}
+	s = strings.TrimSpace(s)
+	// Kenyan "no cents" suffixes: "5,000/=" and "1,000/-"
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(s, "/="), "/-"))
+	if !negative && strings.HasPrefix(s, "-") {
+		negative = true
+		s = s[1:]
+	}
+
+	// Drop the grouping and make the decimal separator one strconv understands
+	s = strings.ReplaceAll(s, string(f.Thousands), "")
+	if f.Decimal != '.' {
+		s = strings.ReplaceAll(s, string(f.Decimal), ".")
+	}
+
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil || amount < 0 {
+		return 0, false
+	}
+	return amount, negative
+}
+
+// amountPattern matches a currency-prefixed amount written in format f.
+func (f NumberFormat) amountPattern() *regexp.Regexp {
+	thousands := regexp.QuoteMeta(string(f.Thousands))
+	decimal := regexp.QuoteMeta(string(f.Decimal))
+	return regexp.MustCompile(
+		`(?i)((?:Ksh|KES)\s*-?)(\d{1,3}(?:` + thousands + `\d{3})+(?:` + decimal + `\d{1,2})?|\d+(?:` + decimal + `\d{1,2})?)`,
+	)
+}
+
+// amountRewriter rewrites the amounts in a message from one NumberFormat into
+// the Kenyan layout the SMS patterns are written for.
+type amountRewriter struct {
+	format  NumberFormat
+	pattern *regexp.Regexp
+}
+
+// newAmountRewriter returns a rewriter for f, or nil when f is already Kenyan.
+func newAmountRewriter(f NumberFormat) *amountRewriter {
+	f = f.orDefault()
+	if f == KenyanNumberFormat {
+		return nil
+	}
+	return &amountRewriter{format: f, pattern: f.amountPattern()}
+}
+
+// rewrite returns log with every currency-prefixed amount in plain Kenyan
+// form, e.g. "Ksh1.500,50" becomes "Ksh1500.5" under a dot-thousands format.
+func (w *amountRewriter) rewrite(log string) string {
+	return w.pattern.ReplaceAllStringFunc(log, func(m string) string {
+		sub := w.pattern.FindStringSubmatch(m)
+		amount, _ := w.format.parseSignedAmount(sub[2])
+		return sub[1] + strconv.FormatFloat(amount, 'f', -1, 64)
+	})
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"context"
+	"testing"
+)
+
+// tanzanianNumberFormat writes "1.500,00" for one thousand five hundred.
+var tanzanianNumberFormat = NumberFormat{Decimal: ',', Thousands: '.'}
+
+func TestNumberFormat_ParseSignedAmount(t *testing.T) {
+	tests := []struct {
+		input  string
+		format NumberFormat
+		want   float64
+	}{
+		{"1.500", KenyanNumberFormat, 1.5},
+		{"1.500", tanzanianNumberFormat, 1500},
+		{"1,500", KenyanNumberFormat, 1500},
+		{"1,500", tanzanianNumberFormat, 1.5},
+		{"Ksh12.345,67", tanzanianNumberFormat, 12345.67},
+		{"Ksh12,345.67", KenyanNumberFormat, 12345.67},
+		{"Ksh1 500,50", NumberFormat{Decimal: ',', Thousands: ' '}, 1500.5},
+	}
+
+	for _, tt := range tests {
+		got, _ := tt.format.parseSignedAmount(tt.input)
+		if got != tt.want {
+			t.Errorf("%+v.parseSignedAmount(%q) = %v, want %v", tt.format, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseLogs_NumberFormat(t *testing.T) {
+	ctx := context.Background()
+	kenyan := "UA34ABCDEF Confirmed. You have received Ksh1,500.50 from JOHN DOE 0712345678"
+	tanzanian := "UA34ABCDEF Confirmed. You have received Ksh1.500,50 from JOHN DOE 0712345678"
+
+	tests := []struct {
+		name string
+		cfg  ParserConfig
+		log  string
+	}{
+		{"default is Kenyan", ParserConfig{}, kenyan},
+		{"explicit Kenyan", ParserConfig{NumberFormat: KenyanNumberFormat}, kenyan},
+		{"Tanzanian", ParserConfig{NumberFormat: tanzanianNumberFormat}, tanzanian},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txns, err := NewParserWithConfig(tt.cfg).ParseLogs(ctx, []string{tt.log})
+			if err != nil || len(txns) != 1 {
+				t.Fatalf("ParseLogs() = %d txns, error %v", len(txns), err)
+			}
+			txn := txns[0]
+			if txn.Type != TxnMPesaReceived || txn.Amount != 1500.5 {
+				t.Errorf("got %v %v, want MPESA_RECEIVED 1500.5", txn.Type, txn.Amount)
+			}
+			if txn.RawText != tt.log {
+				t.Errorf("RawText = %q, want the original message", txn.RawText)
+			}
+		})
+	}
+}
@@ -3,15 +3,23 @@
 package parser
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // TransactionType represents the category of a mobile money transaction.
+// Values are stable: new types are appended at the end, never inserted, so a
+// stored value keeps its meaning across releases.
 type TransactionType int
 
 const (
@@ -46,10 +54,35 @@ const (
 	TxnBankDeposit
 	TxnBankWithdraw
 	// Other types
-	TxnGambling
-	TxnUtility
+	TxnGamblingStake // Bet placed or betting account deposit
+	TxnUtility       // Paybill payment to a known utility (KPLC, Nairobi Water, DSTV...)
+
+	// Types added since, in the order they were introduced
+	TxnLoanPending        // Informational: loan application received, nothing disbursed yet
+	TxnBongaRedeem        // Informational: loyalty points, not cash
+	TxnBankLoanRepay      // Loan instalment (EMI) debited by a bank
+	TxnFulizaLimitReached // Informational: payment failed with the Fuliza limit exhausted
+	TxnAirtime            // Airtime bought with M-Pesa, for the user or another number
+	TxnAirtimeGift        // Informational: airtime bought for the user by someone else
+	TxnReversalPending    // Informational: reversal requested, original still stands
+	TxnKCBLoan            // KCB M-PESA loan disbursed to the wallet
+	TxnKCBRepay           // KCB M-PESA loan repayment
+	TxnAgentDeposit       // Cash in: cash handed to an agent, credited to the wallet
+	TxnAgentWithdraw      // Cash out: wallet debited, cash collected from an agent
+	TxnGamblingWin        // Winnings or a withdrawal paid out by a betting platform
+	TxnBankReceived       // Money paid into the user's bank account or Equitel line by someone else
+	TxnBankSent           // Money paid out of the user's bank account or Equitel line to someone else
+	TxnRemittanceReceived // Money sent from abroad through WorldRemit, Western Union and the like
+
+	numTransactionTypes // Sentinel for iteration; keep last
 )
 
+// TxnGambling is the former name of TxnGamblingStake, from before winnings
+// were told apart from stakes.
+//
+// Deprecated: Use TxnGamblingStake, or TxnGamblingWin for winnings.
+const TxnGambling = TxnGamblingStake
+
 // String returns the string representation of a TransactionType.
 func (t TransactionType) String() string {
 	switch t {
@@ -65,6 +98,8 @@ func (t TransactionType) String() string {
 		return "FULIZA_LOAN"
 	case TxnFulizaRepay:
 		return "FULIZA_REPAY"
+	case TxnFulizaLimitReached:
+		return "FULIZA_LIMIT_REACHED"
 	case TxnTKashReceived:
 		return "TKASH_RECEIVED"
 	case TxnTKashSent:
@@ -85,42 +120,202 @@ func (t TransactionType) String() string {
 		return "DIGITAL_LOAN"
 	case TxnDigitalRepay:
 		return "DIGITAL_REPAY"
+	case TxnLoanPending:
+		return "LOAN_PENDING"
 	case TxnMMFDeposit:
 		return "MMF_DEPOSIT"
 	case TxnMMFWithdraw:
 		return "MMF_WITHDRAW"
+	case TxnKCBLoan:
+		return "KCB_LOAN"
+	case TxnKCBRepay:
+		return "KCB_REPAY"
+	case TxnAgentDeposit:
+		return "AGENT_DEPOSIT"
+	case TxnAgentWithdraw:
+		return "AGENT_WITHDRAW"
 	case TxnBankDeposit:
 		return "BANK_DEPOSIT"
 	case TxnBankWithdraw:
 		return "BANK_WITHDRAW"
-	case TxnGambling:
-		return "GAMBLING"
+	case TxnBankLoanRepay:
+		return "BANK_LOAN_REPAY"
+	case TxnBankReceived:
+		return "BANK_RECEIVED"
+	case TxnBankSent:
+		return "BANK_SENT"
+	case TxnRemittanceReceived:
+		return "REMITTANCE_RECEIVED"
+	case TxnGamblingStake:
+		return "GAMBLING_STAKE"
+	case TxnGamblingWin:
+		return "GAMBLING_WIN"
 	case TxnUtility:
 		return "UTILITY"
+	case TxnAirtime:
+		return "AIRTIME"
+	case TxnBongaRedeem:
+		return "BONGA_REDEEM"
+	case TxnAirtimeGift:
+		return "AIRTIME_GIFT"
+	case TxnReversalPending:
+		return "REVERSAL_PENDING"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// deprecatedTypeNames maps String forms that have since been renamed to the
+// type they now denote, so data written before the rename still reads back.
+var deprecatedTypeNames = map[string]TransactionType{
+	"GAMBLING": TxnGamblingStake, // See TxnGambling
+}
+
+// ParseTransactionType returns the TransactionType whose String form is s,
+// so that serialized transactions can be read back. Names are matched
+// exactly, and a few former names are still accepted, e.g. "GAMBLING" for
+// TxnGamblingStake; unknown names and "UNKNOWN" itself are an error.
+func ParseTransactionType(s string) (TransactionType, error) {
+	for t := TxnUnknown + 1; t < numTransactionTypes; t++ {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+	if t, ok := deprecatedTypeNames[s]; ok {
+		return t, nil
+	}
+	return TxnUnknown, fmt.Errorf("unknown transaction type %q", s)
+}
+
+// MarshalJSON encodes t as its String form, e.g. "MPESA_RECEIVED", so that
+// serialized transactions stay readable and survive reordering of the types.
+func (t TransactionType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON decodes the String form written by MarshalJSON. "UNKNOWN"
+// decodes to TxnUnknown; any other unrecognised name is an error.
+func (t *TransactionType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("transaction type must be a string: %w", err)
+	}
+	if s == TxnUnknown.String() {
+		*t = TxnUnknown
+		return nil
+	}
+	parsed, err := ParseTransactionType(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// IsInformational reports whether a type records an event that moved no cash,
+// such as a loan processing notice. Informational transactions must not feed
+// income, expense or amount statistics.
+func (t TransactionType) IsInformational() bool {
+	switch t {
+	case TxnFulizaLimitReached, TxnLoanPending, TxnBongaRedeem, TxnAirtimeGift, TxnReversalPending:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsOutbound reports whether a type spends wallet money on a transfer or purchase.
+func (t TransactionType) IsOutbound() bool {
+	switch t {
+	case TxnMPesaSent, TxnMPesaPaybill, TxnMPesaBuyGoods, TxnTKashSent, TxnAirtelSent, TxnGamblingStake, TxnAirtime, TxnUtility:
+		return true
+	default:
+		return false
+	}
+}
+
 // Transaction represents a parsed mobile money transaction.
 // Fields are optimized for zero-copy where possible.
 type Transaction struct {
 	Type      TransactionType
 	RefCode   string
 	Amount    float64
-	Balance   float64
+	Balance   float64 // Wallet balance after the transaction; debt balance for Hustler Fund and Okoa
+	Cost      float64 // M-Pesa fee charged on top of Amount for sends and payments; 0 when not quoted
 	Timestamp time.Time
 	Recipient string
 	Sender    string
+	Phone     string // Counterparty phone number split off Sender or Recipient, as written
 	Lender    string // For digital lender identification
 	RawText   string
+	// Institutional marks income paid by a business (B2C payouts, salaries)
+	// rather than by an individual.
+	Institutional bool
+	// Saved is the part of a Hustler Fund disbursement locked in savings
+	// rather than paid to the wallet; Amount holds the wallet part.
+	Saved float64
+	// Confidence is how sure the parser is of Type and Amount, from 0 to 1:
+	// ConfidenceExact for provider-specific patterns, ConfidenceGeneric for
+	// keyword fallbacks that take the first amount in the message.
+	Confidence float64
+	// Derived marks an aggregate entry expanded from a digest message that
+	// summarises several transactions; Amount is their total.
+	Derived bool
+	// FulizaFunded marks a payment or transfer that only went through because
+	// Fuliza covered a shortfall in the wallet.
+	FulizaFunded bool
+	// Reversal marks a completed corrective message that undoes an earlier
+	// transaction of the same Type; its Amount nets out of that flow. Reversals
+	// still in progress are parsed as TxnReversalPending instead.
+	Reversal bool
+	// Recurring marks a receipt that repeats roughly monthly for a similar
+	// amount, such as a salary. Only DetectRecurring sets it.
+	Recurring bool
+	// HasBalance marks a message that quoted Balance, so a zero Balance is an
+	// empty wallet or cleared debt rather than no balance reported.
+	HasBalance bool
 }
 
+// MarshalJSON encodes txn with its Type as a string and its Timestamp in
+// RFC 3339 to the second, leaving Timestamp out when it is zero. The default
+// decoding reads the result back.
+func (txn Transaction) MarshalJSON() ([]byte, error) {
+	type fields Transaction // Drops the methods, so Marshal doesn't recurse
+	var timestamp string
+	if !txn.Timestamp.IsZero() {
+		timestamp = txn.Timestamp.Format(time.RFC3339)
+	}
+	return json.Marshal(struct {
+		fields
+		Timestamp string `json:",omitempty"`
+	}{fields(txn), timestamp})
+}
+
+// Parse confidence levels reported in Transaction.Confidence.
+const (
+	// ConfidenceExact marks a message matched by a provider-specific pattern
+	// or a structured statement record.
+	ConfidenceExact = 1.0
+
+	// ConfidenceGeneric marks a generic fallback: the provider was recognised
+	// by keyword and the first amount taken, so direction or amount may be off.
+	ConfidenceGeneric = 0.5
+)
+
 // ScoreResult contains the credit scoring output.
 type ScoreResult struct {
 	Score    float64   `json:"score"`
 	Features []float64 `json:"features"`
 	TxnCount int       `json:"txn_count"`
+
+	// Explanation names the factors weighing most on the score, strongest first
+	Explanation []string `json:"explanation"`
+
+	// Version stamp of the engine that produced the score
+	EngineVersion     string `json:"engine_version"`
+	ModelHash         string `json:"model_hash"`
+	CalibrationHash   string `json:"calibration_hash"`
+	FeatureSchemaHash string `json:"feature_schema_hash"`
 }
 
 // Parser defines the interface for parsing SMS logs.
@@ -128,24 +323,95 @@ type Parser interface {
 	ParseLogs(ctx context.Context, logs []string) ([]Transaction, error)
 }
 
+// ParserConfig holds optional parser behaviour. The zero value matches NewParser.
+type ParserConfig struct {
+	// RedactRawText replaces Transaction.RawText with the hex SHA-256 of the
+	// message as soon as its fields are extracted, so message bodies are not
+	// retained downstream. Identical messages still share a RawText.
+	RedactRawText bool
+
+	// NumberFormat is how amounts are written in the messages. The zero value
+	// is KenyanNumberFormat; other formats are rewritten to it before matching,
+	// so the same patterns serve markets such as Tanzania ("Ksh1.500,00").
+	NumberFormat NumberFormat
+
+	// UnescapeJSON undoes an extra layer of JSON string escaping that some
+	// clients add when they double-encode message bodies, e.g. a literal \"
+	// or \u00a0. See unescapeJSON for when a message is left untouched.
+	UnescapeJSON bool
+
+	// ExtraLenders, ExtraGamblers and ExtraBanks add names to the built-in
+	// digital lender, betting platform and bank lists, e.g. "Zanifu" or
+	// "M-Kopa". Names match literally and case-insensitively.
+	ExtraLenders  []string
+	ExtraGamblers []string
+	ExtraBanks    []string
+
+	// MaxAmount is the largest transaction amount accepted as genuine. A
+	// message claiming more almost always means a pattern captured the wrong
+	// digits, such as an account or phone number, so its transactions are
+	// dropped; ParseLogsWithReport lists them as rejected. Zero means
+	// DefaultMaxAmount.
+	MaxAmount float64
+}
+
+// DefaultMaxAmount is the amount ceiling used when ParserConfig.MaxAmount is
+// zero: KES 10,000,000, far above any M-Pesa wallet or transaction limit.
+const DefaultMaxAmount = 10_000_000
+
 // DefaultParser implements the Parser interface with optimized parsing.
-type DefaultParser struct{}
+type DefaultParser struct {
+	cfg     ParserConfig
+	amounts *amountRewriter // nil for the Kenyan format
+	vocab   *vocabulary     // nil for the built-in provider lists
+}
 
 // NewParser creates a new Parser instance.
 func NewParser() Parser {
 	return &DefaultParser{}
 }
 
+// NewParserWithConfig creates a Parser with the given options.
+func NewParserWithConfig(cfg ParserConfig) Parser {
+	return &DefaultParser{
+		cfg:     cfg,
+		amounts: newAmountRewriter(cfg.NumberFormat),
+		vocab:   newVocabulary(cfg),
+	}
+}
+
+// ParseReport accounts for the logs passed to ParseLogsWithReport. Once
+// parsing completes, Parsed + Skipped + Rejected == Total.
+type ParseReport struct {
+	Total           int
+	Parsed          int   // Logs that yielded transactions; a digest counts once
+	Skipped         int   // Logs no pattern recognised
+	SkippedIndices  []int // Positions of the skipped logs in the input, ascending
+	Rejected        int   // Logs dropped for an amount over ParserConfig.MaxAmount
+	RejectedIndices []int // Positions of the rejected logs in the input, ascending
+}
+
 // ParseLogs parses a slice of SMS logs into transactions.
 // It uses context for cancellation support and pre-allocates slices
 // to minimize garbage collection on mobile devices.
+// Unrecognised logs are skipped; see ParseLogsWithReport to find out which.
 func (p *DefaultParser) ParseLogs(ctx context.Context, logs []string) ([]Transaction, error) {
+	txns, _, err := p.ParseLogsWithReport(ctx, logs)
+	return txns, err
+}
+
+// ParseLogsWithReport is ParseLogs, also reporting which logs were skipped
+// as unrecognised or rejected by sanitize. On cancellation the report covers
+// the logs seen so far.
+func (p *DefaultParser) ParseLogsWithReport(ctx context.Context, logs []string) ([]Transaction, ParseReport, error) {
+	report := ParseReport{Total: len(logs), SkippedIndices: []int{}, RejectedIndices: []int{}}
 	if len(logs) == 0 {
-		return []Transaction{}, nil
+		return []Transaction{}, report, nil
 	}
 
 	// Pre-allocate to minimize allocations
 	txns := make([]Transaction, 0, len(logs))
+	vocab := p.vocabulary()
 
 	for i, log := range logs {
 		// Check context cancellation every 100 logs to balance
@@ -153,67 +419,273 @@ func (p *DefaultParser) ParseLogs(ctx context.Context, logs []string) ([]Transac
 		if i%100 == 0 {
 			select {
 			case <-ctx.Done():
-				return nil, fmt.Errorf("parsing cancelled at log %d: %w", i, ctx.Err())
+				return nil, report, fmt.Errorf("parsing cancelled at log %d: %w", i, ctx.Err())
 			default:
 			}
 		}
 
-		txn, err := parseSingleLog(log)
-		if err != nil {
+		start := len(txns)
+		var err error
+		if txns, err = p.appendLog(txns, log, vocab); err != nil {
 			// Skip unparseable logs - common in real SMS data
+			report.Skipped++
+			report.SkippedIndices = append(report.SkippedIndices, i)
+			continue
+		}
+		var ok bool
+		if txns, ok = p.sanitize(txns, start); !ok {
+			report.Rejected++
+			report.RejectedIndices = append(report.RejectedIndices, i)
+			continue
+		}
+		report.Parsed++
+	}
+
+	return txns, report, nil
+}
+
+// ParseReader parses an SMS dump one line at a time without holding the whole
+// dump in memory, skipping blank and unrecognised lines like ParseLogs. Each
+// SMS must be on a single line; a message body containing newlines has to be
+// escaped (see ParserConfig.UnescapeJSON) or it is read as several messages.
+func (p *DefaultParser) ParseReader(ctx context.Context, r io.Reader) ([]Transaction, error) {
+	txns := []Transaction{}
+	vocab := p.vocabulary()
+
+	scanner := bufio.NewScanner(r)
+	for line := 0; scanner.Scan(); line++ {
+		if line%100 == 0 {
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("parsing cancelled at line %d: %w", line+1, ctx.Err())
+			default:
+			}
+		}
+
+		log := scanner.Text()
+		if strings.TrimSpace(log) == "" {
 			continue
 		}
+		// Unparseable lines are skipped and implausible ones dropped, as in ParseLogs
+		start := len(txns)
+		txns, _ = p.appendLog(txns, log, vocab)
+		txns, _ = p.sanitize(txns, start)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read SMS dump: %w", err)
+	}
+	return txns, nil
+}
+
+// sanitize checks the transactions one log appended to txns from start on.
+// If any claims an amount over the parser's ceiling the whole log is
+// untrustworthy, so they are all removed and ok is false.
+func (p *DefaultParser) sanitize(txns []Transaction, start int) (_ []Transaction, ok bool) {
+	ceiling := p.cfg.MaxAmount
+	if ceiling == 0 {
+		ceiling = DefaultMaxAmount
+	}
+	for _, txn := range txns[start:] {
+		if txn.Amount > ceiling {
+			return txns[:start], false
+		}
+	}
+	return txns, true
+}
+
+// vocabulary returns the parser's provider patterns, defaulting to the
+// built-in lists for a zero DefaultParser.
+func (p *DefaultParser) vocabulary() *vocabulary {
+	if p.vocab == nil {
+		return defaultVocabulary
+	}
+	return p.vocab
+}
+
+// appendLog applies the parser's options to one message, parses it and
+// appends the result to txns: one transaction, or several for a digest.
+// txns is returned unchanged with an error when nothing matched.
+func (p *DefaultParser) appendLog(txns []Transaction, log string, vocab *vocabulary) ([]Transaction, error) {
+	text := log
+	if p.cfg.UnescapeJSON {
+		text = unescapeJSON(text)
+	}
+	text = normalizeText(text)
+	if p.amounts != nil {
+		text = p.amounts.rewrite(text)
+	}
+
+	start := len(txns)
+	if digest := parseDigest(text); len(digest) > 0 {
+		txns = append(txns, digest...)
+	} else {
+		txn, err := parseMessage(text, vocab)
+		if err != nil {
+			return txns, err
+		}
 		txns = append(txns, txn)
 	}
 
+	rawText := log
+	if p.cfg.RedactRawText {
+		rawText = redact(log)
+	}
+	for i := start; i < len(txns); i++ {
+		txns[i].RawText = rawText
+	}
 	return txns, nil
 }
 
-// parseSingleLog parses a single SMS message into a Transaction.
-// Uses keyword-based fast path before regex matching for performance.
+// parseDigest expands a daily digest such as "Today: 3 deposits totaling
+// Ksh12,000, 2 withdrawals totaling Ksh4,000" into one aggregate entry per
+// clause, classified like a statement row. It returns nil for other messages.
+// Entries are Derived and only ConfidenceGeneric, as the individual amounts
+// and counterparties are unknown.
+func parseDigest(log string) []Transaction {
+	matches := digestEntryPattern.FindAllStringSubmatch(log, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	timestamp, _ := parseTimestamp(log)
+	txns := make([]Transaction, 0, len(matches))
+	for _, match := range matches {
+		kind := strings.ToUpper(getNamedGroup(digestEntryPattern, match, "kind"))
+		credit := strings.HasPrefix(kind, "DEPOSIT") || strings.HasPrefix(kind, "CREDIT") || strings.HasPrefix(kind, "RECEIPT")
+		txns = append(txns, Transaction{
+			Type:       classifyDescription(log, credit),
+			Amount:     parseAmount(getNamedGroup(digestEntryPattern, match, "amt")),
+			Timestamp:  timestamp,
+			RawText:    log,
+			Confidence: ConfidenceGeneric,
+			Derived:    true,
+		})
+	}
+	return txns
+}
+
+// ParseSingle parses one SMS message, e.g. as it arrives, with the default
+// parser options. Unlike ParseLogs it reports an error when no pattern
+// recognises the message. Digest messages are not expanded; use ParseLogs.
+func ParseSingle(log string) (Transaction, error) {
+	return parseSingleLog(log)
+}
+
+// parseSingleLog parses a single SMS message into a Transaction using the
+// built-in provider lists.
 func parseSingleLog(log string) (Transaction, error) {
+	txn, err := parseMessage(normalizeText(log), defaultVocabulary)
+	txn.RawText = log
+	return txn, err
+}
+
+// parseMessage parses a single SMS message into a Transaction, recognising
+// the lenders, betting platforms and banks in v.
+// Uses keyword-based fast path before regex matching for performance.
+func parseMessage(log string, v *vocabulary) (Transaction, error) {
 	txn := Transaction{
-		Type:    TxnUnknown,
-		RawText: log,
+		Type:       TxnUnknown,
+		RawText:    log,
+		Confidence: ConfidenceExact,
 	}
+	// Every provider dates its messages the same way, so the timestamp is
+	// read once here and carried into whichever parseXxx handles the message
+	txn.Timestamp, _ = parseTimestamp(log)
 
-	// Convert to uppercase once for keyword checking
-	logUpper := strings.ToUpper(log)
+	// A reversal still being processed may yet be declined, so it must not
+	// net out the original the way a completed reversal does
+	if reversalPendingPattern.MatchString(log) {
+		if match := amountPattern.FindStringSubmatch(log); match != nil {
+			txn.Type = TxnReversalPending
+			txn.Amount = parseAmount(getNamedGroup(amountPattern, match, "amt"))
+			return txn, nil
+		}
+	}
 
 	// Fast keyword-based routing to avoid unnecessary regex matching
-	switch {
-	case strings.Contains(logUpper, "AIRTEL") || strings.Contains(logUpper, "AM1"):
+	switch detectProvider(log, v) {
+	case providerAirtel:
 		return parseAirtel(log, txn)
-
-	case strings.Contains(logUpper, "HUSTLER"):
+	case providerHustler:
 		return parseHustler(log, txn)
-
-	case strings.Contains(logUpper, "OKOA"):
+	case providerOkoa:
 		return parseOkoa(log, txn)
-
-	case strings.Contains(logUpper, "M-SHWARI") || strings.Contains(logUpper, "MALI") ||
-		strings.Contains(logUpper, "STAWI") || strings.Contains(logUpper, "KCB M-PESA"):
+	case providerKCBLoan:
+		return parseKCBLoan(log, txn)
+	case providerMMF:
 		return parseMMF(log, txn)
+	case providerDigitalLender:
+		return parseDigitalLender(log, txn, v)
+	case providerTKash:
+		return parseTKash(log, txn)
+	case providerFuliza:
+		return parseFuliza(log, txn, v)
+	default:
+		// Fall through to M-Pesa and other patterns
+		return parseMPesaAndOthers(log, txn, v)
+	}
+}
 
-	case strings.Contains(logUpper, "TALA") || strings.Contains(logUpper, "BRANCH") ||
-		strings.Contains(logUpper, "ZENKA") || strings.Contains(logUpper, "ZASH") ||
-		strings.Contains(logUpper, "OKOLEA"):
-		return parseDigitalLender(log, txn)
+// provider identifies the family of messages a text belongs to.
+type provider int
 
-	case strings.Contains(logUpper, "T-KASH"):
-		return parseTKash(log, txn)
+const (
+	providerMPesa provider = iota // M-Pesa and everything not matched by a keyword
+	providerAirtel
+	providerHustler
+	providerOkoa
+	providerKCBLoan
+	providerMMF
+	providerDigitalLender
+	providerTKash
+	providerFuliza
+)
+
+// detectProvider routes text to a provider by keyword, ignoring case.
+// Order matters: earlier keywords win when a message mentions several.
+func detectProvider(log string, v *vocabulary) provider {
+	switch {
+	case containsFold(log, "AIRTEL") || containsFold(log, "AM1"):
+		return providerAirtel
+
+	case containsFold(log, "HUSTLER"):
+		return providerHustler
+
+	case containsFold(log, "OKOA"):
+		return providerOkoa
+
+	// KCB M-PESA is both a savings vault and a lender, so its loan messages
+	// are claimed before the savings keywords
+	case containsFold(log, "KCB") && containsFold(log, "LOAN") &&
+		(containsFold(log, "M-PESA") || containsFold(log, "MPESA")):
+		return providerKCBLoan
+
+	case containsFold(log, "M-SHWARI") || containsFold(log, "MALI") ||
+		containsFold(log, "STAWI") || containsFold(log, "KCB M-PESA"):
+		return providerMMF
+
+	case containsFold(log, "TALA") || containsFold(log, "BRANCH") ||
+		containsFold(log, "ZENKA") || containsFold(log, "ZASH") ||
+		containsFold(log, "OKOLEA") || v.mentionsExtraLender(log):
+		return providerDigitalLender
 
-	case strings.Contains(logUpper, "FULIZA"):
-		return parseFuliza(log, txn)
+	case containsFold(log, "T-KASH"):
+		return providerTKash
+
+	case containsFold(log, "FULIZA"):
+		return providerFuliza
 
 	default:
-		// Fall through to M-Pesa and other patterns
-		return parseMPesaAndOthers(log, txn)
+		return providerMPesa
 	}
 }
 
 // parseAirtel handles Airtel Money transactions.
 func parseAirtel(log string, txn Transaction) (Transaction, error) {
+	if rev, ok := parseWalletReversal(log, txn, TxnAirtelSent, TxnAirtelReceived); ok {
+		return rev, nil
+	}
+
 	if match := airtelReceivedPattern.FindStringSubmatch(log); match != nil {
 		txn.Type = TxnAirtelReceived
 		txn.RefCode = getNamedGroup(airtelReceivedPattern, match, "refcode")
@@ -235,6 +707,7 @@ func parseAirtel(log string, txn Transaction) (Transaction, error) {
 		if match := amountPattern.FindStringSubmatch(log); match != nil {
 			txn.Type = TxnAirtelReceived // Default to received
 			txn.Amount = parseAmount(getNamedGroup(amountPattern, match, "amt"))
+			txn.Confidence = ConfidenceGeneric
 			return txn, nil
 		}
 	}
@@ -242,8 +715,62 @@ func parseAirtel(log string, txn Transaction) (Transaction, error) {
 	return txn, fmt.Errorf("no Airtel pattern matched")
 }
 
+// parseWalletReversal recognises a reversed wallet transfer. The direction
+// comes from how the message names the original transfer ("sent to",
+// "received from"), else from which way the reversal moved the money
+// ("debited" undoes a receipt). All others undo a send, the usual case when
+// money goes to the wrong number. A bare "received" decides nothing: the
+// refund of a send is often worded as money received back.
+func parseWalletReversal(log string, txn Transaction, sent, received TransactionType) (Transaction, bool) {
+	if !reversalKeywordPattern.MatchString(log) {
+		return txn, false
+	}
+	match := amountPattern.FindStringSubmatch(log)
+	if match == nil {
+		return txn, false
+	}
+
+	txn.Type = sent
+	if origin := walletReversalOriginPattern.FindStringSubmatch(log); origin != nil {
+		if strings.HasPrefix(strings.ToLower(getNamedGroup(walletReversalOriginPattern, origin, "dir")), "received") {
+			txn.Type = received
+		}
+	} else if dir := reversalDirectionPattern.FindStringSubmatch(log); dir != nil {
+		switch strings.ToLower(getNamedGroup(reversalDirectionPattern, dir, "dir")) {
+		case "debited", "deducted":
+			txn.Type = received
+		}
+	}
+	txn.Reversal = true
+	txn.Amount = parseAmount(getNamedGroup(amountPattern, match, "amt"))
+	return txn, true
+}
+
 // parseHustler handles Hustler Fund transactions.
 func parseHustler(log string, txn Transaction) (Transaction, error) {
+	// Corrective messages undo an earlier disbursement or repayment
+	if reversalKeywordPattern.MatchString(log) {
+		if match := amountPattern.FindStringSubmatch(log); match != nil {
+			txn.Type = TxnHustlerLoan
+			if containsFold(log, "REPAY") {
+				txn.Type = TxnHustlerRepay
+			}
+			txn.Reversal = true
+			txn.Amount = parseAmount(getNamedGroup(amountPattern, match, "amt"))
+			txn.Lender = "Hustler Fund"
+			return txn, nil
+		}
+	}
+
+	// Disbursements split between the wallet and locked savings carry two amounts
+	if match := hustlerSplitPattern.FindStringSubmatch(log); match != nil {
+		txn.Type = TxnHustlerLoan
+		txn.Amount = parseAmount(getNamedGroup(hustlerSplitPattern, match, "amt"))
+		txn.Saved = parseAmount(getNamedGroup(hustlerSplitPattern, match, "saved"))
+		txn.Lender = "Hustler Fund"
+		return txn, nil
+	}
+
 	if match := hustlerLoanPattern.FindStringSubmatch(log); match != nil {
 		txn.Type = TxnHustlerLoan
 		txn.Amount = parseAmount(getNamedGroup(hustlerLoanPattern, match, "amt"))
@@ -261,6 +788,7 @@ func parseHustler(log string, txn Transaction) (Transaction, error) {
 	if match := hustlerBalancePattern.FindStringSubmatch(log); match != nil {
 		txn.Type = TxnHustlerLoan
 		txn.Balance = parseAmount(getNamedGroup(hustlerBalancePattern, match, "amt"))
+		txn.HasBalance = true
 		txn.Lender = "Hustler Fund"
 		return txn, nil
 	}
@@ -271,6 +799,16 @@ func parseHustler(log string, txn Transaction) (Transaction, error) {
 // parseOkoa handles Okoa Jahazi transactions.
 // Robustly extracts both disbursement amount and remaining debt balance.
 func parseOkoa(log string, txn Transaction) (Transaction, error) {
+	// A reversed top-up undoes the advance and the debt it created
+	if reversalKeywordPattern.MatchString(log) {
+		if match := amountPattern.FindStringSubmatch(log); match != nil {
+			txn.Type = TxnOkoaReceived
+			txn.Reversal = true
+			txn.Amount = parseAmount(getNamedGroup(amountPattern, match, "amt"))
+			return txn, nil
+		}
+	}
+
 	matched := false
 
 	if match := okoaReceivedPattern.FindStringSubmatch(log); match != nil {
@@ -284,6 +822,7 @@ func parseOkoa(log string, txn Transaction) (Transaction, error) {
 			txn.Type = TxnOkoaDebt
 		}
 		txn.Balance = parseAmount(getNamedGroup(okoaDebtPattern, match, "amt"))
+		txn.HasBalance = true
 		matched = true
 	}
 
@@ -346,6 +885,7 @@ func parseMMF(log string, txn Transaction) (Transaction, error) {
 		if match := amountPattern.FindStringSubmatch(log); match != nil {
 			txn.Type = TxnMMFDeposit
 			txn.Amount = parseAmount(getNamedGroup(amountPattern, match, "amt"))
+			txn.Confidence = ConfidenceGeneric
 			return txn, nil
 		}
 	}
@@ -353,35 +893,92 @@ func parseMMF(log string, txn Transaction) (Transaction, error) {
 	return txn, fmt.Errorf("no MMF pattern matched")
 }
 
+// kcbLender is the Lender recorded for KCB M-PESA loans.
+const kcbLender = "KCB M-Pesa"
+
+// parseKCBLoan handles KCB M-PESA loan disbursements and repayments.
+func parseKCBLoan(log string, txn Transaction) (Transaction, error) {
+	txn.Lender = kcbLender
+
+	// Disbursements often quote the amount to be paid back, so they are checked first
+	for _, re := range kcbLoanDisbursedPatterns {
+		if match := re.FindStringSubmatch(log); match != nil {
+			txn.Type = TxnKCBLoan
+			txn.Amount = parseAmount(getNamedGroup(re, match, "amt"))
+			return txn, nil
+		}
+	}
+	for _, re := range kcbLoanRepayPatterns {
+		if match := re.FindStringSubmatch(log); match != nil {
+			txn.Type = TxnKCBRepay
+			txn.Amount = parseAmount(getNamedGroup(re, match, "amt"))
+			return txn, nil
+		}
+	}
+
+	// Processing notices precede the real disbursement and carry no money
+	if loanPendingPattern.MatchString(log) {
+		txn.Type = TxnLoanPending
+		return txn, nil
+	}
+
+	// Limit and reminder notices quote an amount but move no money
+	return txn, fmt.Errorf("no KCB M-PESA loan pattern matched")
+}
+
 // parseDigitalLender handles digital loan app transactions (Tala, Branch, etc.).
-func parseDigitalLender(log string, txn Transaction) (Transaction, error) {
-	if match := loanDisbursementPattern.FindStringSubmatch(log); match != nil {
+func parseDigitalLender(log string, txn Transaction, v *vocabulary) (Transaction, error) {
+	// A failed repayment is refunded with a reversal, leaving the loan owed
+	if reversalKeywordPattern.MatchString(log) {
+		if match := amountPattern.FindStringSubmatch(log); match != nil {
+			txn.Type = TxnDigitalLoan
+			if containsFold(log, "REPAY") || containsFold(log, "PAYMENT") {
+				txn.Type = TxnDigitalRepay
+			}
+			txn.Reversal = true
+			txn.Amount = parseAmount(getNamedGroup(amountPattern, match, "amt"))
+			txn.Lender = v.lender.FindString(log)
+			return txn, nil
+		}
+	}
+
+	if match := v.loanDisbursement.FindStringSubmatch(log); match != nil {
 		txn.Type = TxnDigitalLoan
-		txn.Amount = parseAmount(getNamedGroup(loanDisbursementPattern, match, "amt"))
-		txn.Lender = getNamedGroup(loanDisbursementPattern, match, "lender")
+		txn.Amount = parseAmount(getNamedGroup(v.loanDisbursement, match, "amt"))
+		txn.Lender = getNamedGroup(v.loanDisbursement, match, "lender")
 		return txn, nil
 	}
 
-	if match := loanRepaymentPattern.FindStringSubmatch(log); match != nil {
+	if match := v.loanRepayment.FindStringSubmatch(log); match != nil {
 		txn.Type = TxnDigitalRepay
-		txn.Amount = parseAmount(getNamedGroup(loanRepaymentPattern, match, "amt"))
-		txn.Lender = getNamedGroup(loanRepaymentPattern, match, "lender")
+		txn.Amount = parseAmount(getNamedGroup(v.loanRepayment, match, "amt"))
+		txn.Lender = getNamedGroup(v.loanRepayment, match, "lender")
+		return txn, nil
+	}
+
+	// Processing notices precede the real disbursement and carry no money
+	if loanPendingPattern.MatchString(log) {
+		txn.Type = TxnLoanPending
+		txn.Lender = v.lender.FindString(log)
 		return txn, nil
 	}
 
 	// Generic lender detection
-	if digitalLenderPattern.MatchString(log) {
-		if match := amountPattern.FindStringSubmatch(log); match != nil {
-			// Infer loan or repay based on keywords
-			logUpper := strings.ToUpper(log)
-			if strings.Contains(logUpper, "REPAY") || strings.Contains(logUpper, "PAID") {
+	if v.lender.MatchString(log) {
+		if amt := findAmount(log); amt != "" {
+			// Infer loan or repay based on keywords. Only explicit credit
+			// wording counts as a loan inflow.
+			if containsFold(log, "REPAY") || containsFold(log, "PAID") {
 				txn.Type = TxnDigitalRepay
-			} else {
+			} else if loanCreditPattern.MatchString(log) {
 				txn.Type = TxnDigitalLoan
+			} else {
+				return txn, fmt.Errorf("no digital lender pattern matched")
 			}
-			txn.Amount = parseAmount(getNamedGroup(amountPattern, match, "amt"))
+			txn.Amount = parseAmount(amt)
+			txn.Confidence = ConfidenceGeneric
 			// Extract lender name
-			if lender := digitalLenderPattern.FindString(log); lender != "" {
+			if lender := v.lender.FindString(log); lender != "" {
 				txn.Lender = lender
 			}
 			return txn, nil
@@ -393,6 +990,10 @@ func parseDigitalLender(log string, txn Transaction) (Transaction, error) {
 
 // parseTKash handles T-Kash transactions.
 func parseTKash(log string, txn Transaction) (Transaction, error) {
+	if rev, ok := parseWalletReversal(log, txn, TxnTKashSent, TxnTKashReceived); ok {
+		return rev, nil
+	}
+
 	if match := tkashReceivedPattern.FindStringSubmatch(log); match != nil {
 		txn.Type = TxnTKashReceived
 		txn.Amount = parseAmount(getNamedGroup(tkashReceivedPattern, match, "amt"))
@@ -411,7 +1012,15 @@ func parseTKash(log string, txn Transaction) (Transaction, error) {
 }
 
 // parseFuliza handles Fuliza loan transactions.
-func parseFuliza(log string, txn Transaction) (Transaction, error) {
+func parseFuliza(log string, txn Transaction, v *vocabulary) (Transaction, error) {
+	// Payments completed with an overdraft are still payments
+	if fulizaFundedPattern.MatchString(log) {
+		if paid, err := parseMPesaAndOthers(log, txn, v); err == nil && paid.Type.IsOutbound() {
+			paid.FulizaFunded = true
+			return paid, nil
+		}
+	}
+
 	if match := fulizaLoanPattern.FindStringSubmatch(log); match != nil {
 		txn.Type = TxnFulizaLoan
 		txn.Amount = parseAmount(getNamedGroup(fulizaLoanPattern, match, "amt"))
@@ -424,47 +1033,261 @@ func parseFuliza(log string, txn Transaction) (Transaction, error) {
 		return txn, nil
 	}
 
+	// Payments refused at the limit mention amounts that never moved
+	if fulizaLimitPattern.MatchString(log) {
+		txn.Type = TxnFulizaLimitReached
+		return txn, nil
+	}
+
 	return txn, fmt.Errorf("no Fuliza pattern matched")
 }
 
 // parseMPesaAndOthers handles M-Pesa, gambling, and other patterns.
-func parseMPesaAndOthers(log string, txn Transaction) (Transaction, error) {
+func parseMPesaAndOthers(log string, txn Transaction, v *vocabulary) (Transaction, error) {
+	// Each branch is gated on a keyword its patterns cannot match without, so
+	// a message only pays for the regexes that could fit it. This roughly
+	// halves bulk-parse time on a mixed inbox (see BenchmarkParseLogs).
+	received := containsFold(log, "RECEIVED")
+	sent := containsFold(log, "SENT")
+	paid := containsFold(log, "PAID")
+
+	// Declined transactions moved no money
+	if containsFold(log, "FAILED") && failedTxnPattern.MatchString(log) {
+		return txn, fmt.Errorf("transaction failed")
+	}
+
+	// Bonga Points redemptions quote a Ksh value but are not cash income
+	if containsFold(log, "BONGA") && bongaRedeemPattern.MatchString(log) {
+		txn.Type = TxnBongaRedeem
+		if match := amountPattern.FindStringSubmatch(log); match != nil {
+			txn.Amount = parseAmount(getNamedGroup(amountPattern, match, "amt"))
+		}
+		return txn, nil
+	}
+
+	// Airtime bought by someone else is a gift, not cash
+	airtime := containsFold(log, "AIRTIME")
+	if match := matchIf(airtime, airtimeGiftPattern, log); match != nil {
+		txn.Type = TxnAirtimeGift
+		txn.Amount = parseAmount(getNamedGroup(airtimeGiftPattern, match, "amt"))
+		txn.Sender = strings.TrimSpace(getNamedGroup(airtimeGiftPattern, match, "sender"))
+		return txn, nil
+	}
+
+	if match := matchIf(airtime, airtimePurchasePattern, log); match != nil {
+		txn.Type = TxnAirtime
+		txn.RefCode = getNamedGroup(airtimePurchasePattern, match, "refcode")
+		txn.Amount = parseAmount(getNamedGroup(airtimePurchasePattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Phone = getNamedGroup(airtimePurchasePattern, match, "phone") // Set when bought for another number
+		txn.Cost = transactionCost(log)
+		return txn, nil
+	}
+
+	// Reversed bank transfers, till, paybill and utility payments undo an earlier expense
+	if containsFold(log, "REVERS") && reversalKeywordPattern.MatchString(log) {
+		// Failed transfers to a bank are refunded with a reversal
+		if match := v.bankReversal.FindStringSubmatch(log); match != nil {
+			if amt := amountPattern.FindStringSubmatch(log); amt != nil {
+				txn.Type = TxnBankDeposit
+				txn.Reversal = true
+				txn.Amount = parseAmount(getNamedGroup(amountPattern, amt, "amt"))
+				txn.Recipient = getNamedGroup(v.bankReversal, match, "bank")
+				return txn, nil
+			}
+		}
+
+		// Till reversals are checked first; "payment to till 123456" also fits the paybill wording
+		if match := tillReversalPattern.FindStringSubmatch(log); match != nil {
+			if amt := amountPattern.FindStringSubmatch(log); amt != nil {
+				txn.Type = TxnMPesaBuyGoods
+				txn.Reversal = true
+				txn.Amount = parseAmount(getNamedGroup(amountPattern, amt, "amt"))
+				txn.Recipient = getNamedGroup(tillReversalPattern, match, "till")
+				return txn, nil
+			}
+		}
+		if match := paybillReversalPattern.FindStringSubmatch(log); match != nil {
+			txn.Reversal = true
+			txn.Amount = parseAmount(getNamedGroup(paybillReversalPattern, match, "amt"))
+			txn.Recipient = getNamedGroup(paybillReversalPattern, match, "account")
+			txn.Type = paybillType(txn.Recipient)
+			return txn, nil
+		}
+
+		// Reversed transfers name the original transaction; the direction the
+		// money moved tells whether it undoes a receipt or a send
+		if match := mpesaReversalPattern.FindStringSubmatch(log); match != nil {
+			dir := reversalDirectionPattern.FindStringSubmatch(log)
+			amt := amountPattern.FindStringSubmatch(log)
+			if dir != nil && amt != nil {
+				switch strings.ToLower(getNamedGroup(reversalDirectionPattern, dir, "dir")) {
+				case "debited", "deducted":
+					txn.Type = TxnMPesaReceived
+				default:
+					txn.Type = TxnMPesaSent
+				}
+				txn.Reversal = true
+				txn.RefCode = getNamedGroup(mpesaReversalPattern, match, "refcode")
+				txn.Amount = parseAmount(getNamedGroup(amountPattern, amt, "amt"))
+				txn.Balance, txn.HasBalance = walletBalance(log)
+				return txn, nil
+			}
+		}
+	}
+
+	// Cash in and cash out at an agent move money between cash and the wallet;
+	// RefCode holds the agent number
+	if match := matchIf(containsFold(log, "CASH TO"), agentDepositPattern, log); match != nil {
+		txn.Type = TxnAgentDeposit
+		txn.RefCode = getNamedGroup(agentDepositPattern, match, "agent")
+		txn.Amount = parseAmount(getNamedGroup(agentDepositPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		return txn, nil
+	}
+
+	if match := matchIf(containsFold(log, "WITHDRAW"), agentWithdrawPattern, log); match != nil {
+		txn.Type = TxnAgentWithdraw
+		txn.RefCode = getNamedGroup(agentWithdrawPattern, match, "agent")
+		txn.Amount = parseAmount(getNamedGroup(agentWithdrawPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Cost = transactionCost(log)
+		return txn, nil
+	}
+
+	// Diaspora remittances arrive from the transfer service, not a person
+	if match := matchIf(received, remittancePattern, log); match != nil {
+		txn.Type = TxnRemittanceReceived
+		txn.RefCode = getNamedGroup(remittancePattern, match, "refcode")
+		txn.Amount = parseAmount(getNamedGroup(remittancePattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Sender = getNamedGroup(remittancePattern, match, "provider")
+		return txn, nil
+	}
+
 	// M-Pesa patterns
-	if match := mpesaReceivedPattern.FindStringSubmatch(log); match != nil {
+	if match := matchIf(received, mpesaReceivedPattern, log); match != nil {
 		txn.Type = TxnMPesaReceived
 		txn.RefCode = getNamedGroup(mpesaReceivedPattern, match, "refcode")
 		txn.Amount = parseAmount(getNamedGroup(mpesaReceivedPattern, match, "amt"))
-		txn.Sender = getNamedGroup(mpesaReceivedPattern, match, "sender")
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Sender, txn.Phone = splitPhone(getNamedGroup(mpesaReceivedPattern, match, "sender"))
+		txn.Institutional = isInstitutionalPayment(log, txn.Sender)
+		return txn, nil
+	}
+
+	// Businesses paying out through a paybill or till are income, not a paybill expense
+	if match := matchIf(received, c2bReceivedPattern, log); match != nil {
+		txn.Type = TxnMPesaReceived
+		txn.RefCode = getNamedGroup(c2bReceivedPattern, match, "refcode")
+		txn.Amount = parseAmount(getNamedGroup(c2bReceivedPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Sender, txn.Phone = splitPhone(getNamedGroup(c2bReceivedPattern, match, "sender"))
+		txn.Institutional = isInstitutionalPayment(log, txn.Sender)
 		return txn, nil
 	}
 
-	if match := mpesaSentPattern.FindStringSubmatch(log); match != nil {
+	// A minority of formats name the sender before the amount
+	if match := matchIf(received, mpesaReceivedAfterPattern, log); match != nil {
+		txn.Type = TxnMPesaReceived
+		txn.RefCode = getNamedGroup(mpesaReceivedAfterPattern, match, "refcode")
+		txn.Amount = parseAmount(getNamedGroup(mpesaReceivedAfterPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Sender, txn.Phone = splitPhone(getNamedGroup(mpesaReceivedAfterPattern, match, "sender"))
+		txn.Institutional = isInstitutionalPayment(log, txn.Sender)
+		return txn, nil
+	}
+
+	if match := matchIf(sent, mpesaSentPattern, log); match != nil {
 		txn.Type = TxnMPesaSent
 		txn.RefCode = getNamedGroup(mpesaSentPattern, match, "refcode")
 		txn.Amount = parseAmount(getNamedGroup(mpesaSentPattern, match, "amt"))
-		txn.Recipient = getNamedGroup(mpesaSentPattern, match, "recipient")
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Recipient, txn.Phone = splitPhone(getNamedGroup(mpesaSentPattern, match, "recipient"))
+		txn.Cost = transactionCost(log)
 		return txn, nil
 	}
 
-	if match := mpesaPaybillPattern.FindStringSubmatch(log); match != nil {
-		txn.Type = TxnMPesaPaybill
-		txn.RefCode = getNamedGroup(mpesaPaybillPattern, match, "refcode")
-		txn.Amount = parseAmount(getNamedGroup(mpesaPaybillPattern, match, "amt"))
-		txn.Recipient = getNamedGroup(mpesaPaybillPattern, match, "account")
+	if match := matchIf(sent, mpesaSentAfterPattern, log); match != nil {
+		txn.Type = TxnMPesaSent
+		txn.RefCode = getNamedGroup(mpesaSentAfterPattern, match, "refcode")
+		txn.Amount = parseAmount(getNamedGroup(mpesaSentAfterPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Recipient, txn.Phone = splitPhone(getNamedGroup(mpesaSentAfterPattern, match, "recipient"))
+		txn.Cost = transactionCost(log)
 		return txn, nil
 	}
 
-	if match := mpesaBuyGoodsPattern.FindStringSubmatch(log); match != nil {
+	// Till payments also read "paid to", so buy goods is checked before paybill
+	if match := matchIf(paid, mpesaBuyGoodsPattern, log); match != nil {
 		txn.Type = TxnMPesaBuyGoods
 		txn.RefCode = getNamedGroup(mpesaBuyGoodsPattern, match, "refcode")
 		txn.Amount = parseAmount(getNamedGroup(mpesaBuyGoodsPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
 		txn.Recipient = getNamedGroup(mpesaBuyGoodsPattern, match, "merchant")
+		txn.Cost = transactionCost(log)
+		return txn, nil
+	}
+
+	if match := matchIf(paid, mpesaPaybillPattern, log); match != nil {
+		txn.RefCode = getNamedGroup(mpesaPaybillPattern, match, "refcode")
+		txn.Amount = parseAmount(getNamedGroup(mpesaPaybillPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Recipient = getNamedGroup(mpesaPaybillPattern, match, "account")
+		txn.Type = paybillType(txn.Recipient)
+		txn.Cost = transactionCost(log)
+		return txn, nil
+	}
+
+	// Swahili messages use their own verbs: umepokea (received), umetuma (sent), umelipa (paid)
+	if match := matchIf(containsFold(log, "UMEPOKEA"), mpesaSwahiliReceivedPattern, log); match != nil {
+		txn.Type = TxnMPesaReceived
+		txn.RefCode = getNamedGroup(mpesaSwahiliReceivedPattern, match, "refcode")
+		txn.Amount = parseAmount(getNamedGroup(mpesaSwahiliReceivedPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Sender, txn.Phone = splitPhone(getNamedGroup(mpesaSwahiliReceivedPattern, match, "sender"))
+		txn.Institutional = isInstitutionalPayment(log, txn.Sender)
+		return txn, nil
+	}
+
+	if match := matchIf(containsFold(log, "UMETUMA"), mpesaSwahiliSentPattern, log); match != nil {
+		txn.Type = TxnMPesaSent
+		txn.RefCode = getNamedGroup(mpesaSwahiliSentPattern, match, "refcode")
+		txn.Amount = parseAmount(getNamedGroup(mpesaSwahiliSentPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Recipient, txn.Phone = splitPhone(getNamedGroup(mpesaSwahiliSentPattern, match, "recipient"))
+		txn.Cost = transactionCost(log)
+		return txn, nil
+	}
+
+	umelipa := containsFold(log, "UMELIPA")
+	if match := matchIf(umelipa, mpesaSwahiliBuyGoodsPattern, log); match != nil {
+		txn.Type = TxnMPesaBuyGoods
+		txn.RefCode = getNamedGroup(mpesaSwahiliBuyGoodsPattern, match, "refcode")
+		txn.Amount = parseAmount(getNamedGroup(mpesaSwahiliBuyGoodsPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Recipient = getNamedGroup(mpesaSwahiliBuyGoodsPattern, match, "merchant")
+		txn.Cost = transactionCost(log)
+		return txn, nil
+	}
+
+	if match := matchIf(umelipa, mpesaSwahiliPaybillPattern, log); match != nil {
+		txn.RefCode = getNamedGroup(mpesaSwahiliPaybillPattern, match, "refcode")
+		txn.Amount = parseAmount(getNamedGroup(mpesaSwahiliPaybillPattern, match, "amt"))
+		txn.Balance, txn.HasBalance = walletBalance(log)
+		txn.Recipient = getNamedGroup(mpesaSwahiliPaybillPattern, match, "account")
+		txn.Type = paybillType(txn.Recipient)
+		txn.Cost = transactionCost(log)
 		return txn, nil
 	}
 
 	// Check for gambling platforms
-	if gamblingPattern.MatchString(log) {
-		txn.Type = TxnGambling
+	if platform := v.gambling.FindString(log); platform != "" {
+		txn.Type = TxnGamblingStake
+		if gamblingWinPattern.MatchString(log) {
+			txn.Type = TxnGamblingWin
+		}
+		txn.Recipient = platform
 		if match := amountPattern.FindStringSubmatch(log); match != nil {
 			txn.Amount = parseAmount(getNamedGroup(amountPattern, match, "amt"))
 		}
@@ -472,47 +1295,223 @@ func parseMPesaAndOthers(log string, txn Transaction) (Transaction, error) {
 	}
 
 	// Check for bank transfers
-	if bankTransferPattern.MatchString(log) {
-		if match := bankDepositPattern.FindStringSubmatch(log); match != nil {
-			txn.Type = TxnBankDeposit
-			txn.Amount = parseAmount(getNamedGroup(bankDepositPattern, match, "amt"))
-			txn.Recipient = getNamedGroup(bankDepositPattern, match, "bank")
+	if v.bank.MatchString(log) {
+		return parseBank(log, txn, v)
+	}
+
+	return txn, fmt.Errorf("no pattern matched for log")
+}
+
+// parseBank parses a message that mentions a bank: instalments, transfers
+// between the bank and the wallet, and the banks' own received and sent
+// notices (Equity and Equitel, KCB, Co-op Kwa Jirani). Wallet patterns are
+// tried first, so it only sees what they leave unmatched.
+func parseBank(log string, txn Transaction, v *vocabulary) (Transaction, error) {
+	// Loan instalments are debt service, not ordinary bank activity
+	if bankLoanRepayPattern.MatchString(log) {
+		if amt := findAmount(log); amt != "" {
+			txn.Type = TxnBankLoanRepay
+			txn.Amount = parseAmount(amt)
+			txn.Recipient = v.bank.FindString(log)
+			txn.Lender = txn.Recipient
 			return txn, nil
 		}
-		if match := bankWithdrawPattern.FindStringSubmatch(log); match != nil {
-			txn.Type = TxnBankWithdraw
-			txn.Amount = parseAmount(getNamedGroup(bankWithdrawPattern, match, "amt"))
-			txn.Sender = getNamedGroup(bankWithdrawPattern, match, "bank")
+	}
+	if match := v.bankDeposit.FindStringSubmatch(log); match != nil {
+		txn.Type = TxnBankDeposit
+		txn.Amount = parseAmount(getNamedGroup(v.bankDeposit, match, "amt"))
+		txn.Recipient = getNamedGroup(v.bankDeposit, match, "bank")
+		return txn, nil
+	}
+	if match := v.bankWithdraw.FindStringSubmatch(log); match != nil {
+		txn.Type = TxnBankWithdraw
+		txn.Amount = parseAmount(getNamedGroup(v.bankWithdraw, match, "amt"))
+		txn.Sender = getNamedGroup(v.bankWithdraw, match, "bank")
+		return txn, nil
+	}
+
+	for _, re := range []*regexp.Regexp{bankReceivedPattern, bankCreditedPattern} {
+		if match := re.FindStringSubmatch(log); match != nil {
+			txn.Type = TxnBankReceived
+			txn.Amount = parseAmount(getNamedGroup(re, match, "amt"))
+			txn.Sender, txn.Phone = splitPhone(getNamedGroup(re, match, "sender"))
+			return txn, nil
+		}
+	}
+	for _, re := range []*regexp.Regexp{bankSentPattern, bankDebitedPattern} {
+		if match := re.FindStringSubmatch(log); match != nil {
+			txn.Type = TxnBankSent
+			txn.Amount = parseAmount(getNamedGroup(re, match, "amt"))
+			txn.Recipient, txn.Phone = splitPhone(getNamedGroup(re, match, "recipient"))
 			return txn, nil
 		}
 	}
 
-	return txn, fmt.Errorf("no pattern matched for log")
+	return txn, fmt.Errorf("no bank pattern matched")
+}
+
+// paybillType classifies a paybill payment: TxnUtility when the payee is a
+// known utility, TxnMPesaPaybill otherwise.
+func paybillType(account string) TransactionType {
+	if utilityPattern.MatchString(account) {
+		return TxnUtility
+	}
+	return TxnMPesaPaybill
+}
+
+// containsFold reports whether s contains upper, an upper-case ASCII keyword,
+// ignoring case. Unlike strings.Contains(strings.ToUpper(s), upper) it does not
+// allocate, which adds up when routing every message in a large batch.
+func containsFold(s, upper string) bool {
+	n := len(upper)
+	if n == 0 {
+		return true
+	}
+	first := upper[0]
+	for i := 0; i+n <= len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c == first && strings.EqualFold(s[i:i+n], upper) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIf returns re's submatches in log, skipping the regex when the
+// caller's keyword prefilter ok already rules a match out.
+func matchIf(ok bool, re *regexp.Regexp, log string) []string {
+	if !ok {
+		return nil
+	}
+	return re.FindStringSubmatch(log)
+}
+
+// isInstitutionalPayment reports whether a receipt was paid out by a business,
+// either via an explicit B2C marker in the message or a company-style sender name.
+func isInstitutionalPayment(log, sender string) bool {
+	return b2cMarkerPattern.MatchString(log) || businessSenderPattern.MatchString(sender)
+}
+
+// walletBalance extracts the post-transaction wallet balance quoted in a
+// message. ok is false when the message does not report one, so a quoted
+// Ksh0.00 is told apart from no balance at all.
+func walletBalance(log string) (balance float64, ok bool) {
+	if match := walletBalancePattern.FindStringSubmatch(log); match != nil {
+		return parseAmount(getNamedGroup(walletBalancePattern, match, "amt")), true
+	}
+	return 0, false
+}
+
+// splitPhone separates a trailing Kenyan phone number (07..., 01..., 254...)
+// from a captured counterparty, returning the bare name and the number. A
+// party without one is returned unchanged with an empty phone.
+func splitPhone(party string) (name, phone string) {
+	match := phoneSuffixPattern.FindStringSubmatch(party)
+	if match == nil {
+		return party, ""
+	}
+	return getNamedGroup(phoneSuffixPattern, match, "name"), getNamedGroup(phoneSuffixPattern, match, "phone")
+}
+
+// transactionCost extracts the fee M-Pesa charged for a send or payment, or 0
+// when the message does not quote one.
+func transactionCost(log string) float64 {
+	if match := txnCostPattern.FindStringSubmatch(log); match != nil {
+		return parseAmount(getNamedGroup(txnCostPattern, match, "amt"))
+	}
+	return 0
+}
+
+// maxUnescapeDepth bounds how many layers of escaping unescapeJSON removes.
+const maxUnescapeDepth = 2
+
+// unescapeJSON removes JSON string escaping from a doubly-encoded message,
+// with or without its surrounding quotes. It is conservative: a message is
+// only changed when it contains a backslash and decodes as a JSON string body,
+// so stray backslashes ("A\B"), which are not valid JSON escapes, and bare
+// quotes leave it as it was.
+func unescapeJSON(log string) string {
+	for i := 0; i < maxUnescapeDepth && strings.Contains(log, `\`); i++ {
+		quoted := log
+		if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+			quoted = `"` + quoted + `"`
+		}
+		var decoded string
+		if err := json.Unmarshal([]byte(quoted), &decoded); err != nil {
+			break
+		}
+		log = decoded
+	}
+	return log
+}
+
+// redact returns the hex SHA-256 digest of a message body.
+func redact(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
 }
 
 // parseAmount converts Kenyan SMS amount format to float64.
-// Handles formats like "Ksh1,500.00", "Ksh 1500", "KES 1,234.56"
+// Handles formats like "Ksh1,500.00", "Ksh 1500", "KES 1,234.56".
+// Amounts are magnitudes: a minus sign ("Ksh-500.00", "-500") is dropped, since
+// direction comes from the message wording and the engine assumes amounts are
+// non-negative. Callers that need the sign use parseSignedAmount. Messages in
+// other markets' formats are rewritten to this one first; see ParserConfig.NumberFormat.
 func parseAmount(s string) float64 {
-	if s == "" {
-		return 0
+	amount, _ := parseSignedAmount(s)
+	return amount
+}
+
+// findAmount returns the first amount in log for generic extraction: one with
+// a currency prefix ("Ksh500", "KES. 5,000") or, failing that, the bare
+// slash-equals form ("5,000/="). It returns "" when there is none.
+func findAmount(log string) string {
+	if match := amountPattern.FindStringSubmatch(log); match != nil {
+		return getNamedGroup(amountPattern, match, "amt")
+	}
+	if match := slashAmountPattern.FindStringSubmatch(log); match != nil {
+		return getNamedGroup(slashAmountPattern, match, "amt")
 	}
+	return ""
+}
 
-	// Remove common prefixes and whitespace
-	s = strings.TrimSpace(s)
-	s = strings.TrimPrefix(s, "Ksh")
-	s = strings.TrimPrefix(s, "ksh")
-	s = strings.TrimPrefix(s, "KES")
-	s = strings.TrimPrefix(s, "kes")
-	s = strings.TrimSpace(s)
+// parseSignedAmount is parseAmount that also reports whether the amount carried
+// a minus sign, before or after the currency prefix ("-Ksh500", "Ksh-500").
+func parseSignedAmount(s string) (float64, bool) {
+	return KenyanNumberFormat.parseSignedAmount(s)
+}
 
-	// Remove commas (Kenyan format uses commas for thousands)
-	s = strings.ReplaceAll(s, ",", "")
+// normalizeText maps Unicode whitespace (e.g. U+00A0 no-break space) to an
+// ASCII space and Unicode decimal digits (e.g. fullwidth "１") to ASCII, so
+// messages from unusual keyboards and gateways match the patterns like plain
+// ones. Plain ASCII text is returned without copying.
+func normalizeText(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r < utf8.RuneSelf:
+			return r
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsDigit(r):
+			return '0' + digitValue(r)
+		default:
+			return r
+		}
+	}, s)
+}
 
-	amount, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return 0
+// digitValue returns the value of a Unicode decimal digit. Unicode encodes
+// each script's digits as a contiguous run from 0 to 9, so the value is the
+// offset from the start of the run, modulo 10 for scripts with adjacent runs.
+func digitValue(r rune) rune {
+	start := r
+	for unicode.IsDigit(start - 1) {
+		start--
 	}
-	return amount
+	return (r - start) % 10
 }
 
 // getNamedGroup extracts a named capture group from regex match.
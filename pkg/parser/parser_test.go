@@ -2,7 +2,12 @@ package parser
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseAmount(t *testing.T) {
@@ -19,6 +24,17 @@ func TestParseAmount(t *testing.T) {
 		{"lowercase ksh", "ksh100", 100.00},
 		{"plain number", "5000.50", 5000.50},
 		{"number with comma", "10,000", 10000.00},
+		{"negative Ksh", "Ksh-500.00", 500.00},
+		{"negative plain", "-500", 500.00},
+		{"negative before prefix", "-KES 1,200", 1200.00},
+		{"no-break space after prefix", "Ksh\u00a01,500.00", 1500.00},
+		{"no-break space padding", "\u00a0KES 2,000\u00a0", 2000.00},
+		{"fullwidth digits", "Ksh\uff11,\uff15\uff10\uff10.\uff10\uff10", 1500.00},
+		{"arabic-indic digits", "\u0662\u0665\u0660", 250.00},
+		{"slash-equals suffix", "5,000/=", 5000.00},
+		{"slash-dash suffix", "Ksh1,000/-", 1000.00},
+		{"KES with full stop", "KES. 5,000", 5000.00},
+		{"leading decimal point", ".5", 0.5},
 		{"empty string", "", 0},
 		{"invalid", "abc", 0},
 	}
@@ -33,6 +49,30 @@ func TestParseAmount(t *testing.T) {
 	}
 }
 
+func TestParseSignedAmount(t *testing.T) {
+	tests := []struct {
+		input        string
+		wantAmount   float64
+		wantNegative bool
+	}{
+		{"Ksh-500.00", 500, true},
+		{"-500", 500, true},
+		{"-Ksh 1,000", 1000, true},
+		{"Ksh500.00", 500, false},
+		{"--500", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			amount, negative := parseSignedAmount(tt.input)
+			if amount != tt.wantAmount || negative != tt.wantNegative {
+				t.Errorf("parseSignedAmount(%q) = (%v, %v), want (%v, %v)",
+					tt.input, amount, negative, tt.wantAmount, tt.wantNegative)
+			}
+		})
+	}
+}
+
 func TestParseSingleLog_MPesa(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -43,24 +83,31 @@ func TestParseSingleLog_MPesa(t *testing.T) {
 	}{
 		{
 			name:        "M-Pesa received",
-			log:         "UA1234ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+			log:         "UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
 			wantType:    TxnMPesaReceived,
 			wantAmount:  1500.00,
-			wantRefCode: "UA1234ABCDEF",
+			wantRefCode: "UA34ABCDEF",
 		},
 		{
 			name:        "M-Pesa sent",
-			log:         "UA5678EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+			log:         "UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
 			wantType:    TxnMPesaSent,
 			wantAmount:  500.00,
-			wantRefCode: "UA5678EFGHIJ",
+			wantRefCode: "UA78EFGHIJ",
+		},
+		{
+			name:        "M-Pesa paybill to a utility",
+			log:         "UA99XYZABC Confirmed. Ksh1,000.00 paid to KPLC Account 12345",
+			wantType:    TxnUtility,
+			wantAmount:  1000.00,
+			wantRefCode: "UA99XYZABC",
 		},
 		{
 			name:        "M-Pesa paybill",
-			log:         "UA9999XYZABC Confirmed. Ksh1,000.00 paid to KPLC Account 12345",
+			log:         "UA99XYZABD Confirmed. Ksh1,000.00 paid to EQUITY SCHOOL Account 12345",
 			wantType:    TxnMPesaPaybill,
 			wantAmount:  1000.00,
-			wantRefCode: "UA9999XYZABC",
+			wantRefCode: "UA99XYZABD",
 		},
 		{
 			name:        "M-Pesa received (QKJ prefix)",
@@ -90,6 +137,258 @@ func TestParseSingleLog_MPesa(t *testing.T) {
 	}
 }
 
+func TestParseSingleLog_MPesaSwahili(t *testing.T) {
+	tests := []struct {
+		name          string
+		log           string
+		wantType      TransactionType
+		wantAmount    float64
+		wantParty     string
+		wantBalance   float64
+		wantRefCode   string
+		wantTimestamp time.Time
+	}{
+		{
+			name:          "received",
+			log:           "UA34ABCDEF Imethibitishwa. Umepokea Ksh1,500.00 kutoka JOHN DOE 0712345678 mnamo 20/1/26 saa 3:45 PM. Salio lako jipya la M-PESA ni Ksh2,000.00.",
+			wantType:      TxnMPesaReceived,
+			wantAmount:    1500,
+			wantParty:     "JOHN DOE",
+			wantBalance:   2000,
+			wantRefCode:   "UA34ABCDEF",
+			wantTimestamp: time.Date(2026, time.January, 20, 15, 45, 0, 0, eastAfricaTime),
+		},
+		{
+			name:        "sent",
+			log:         "UA78EFGHIJ Imethibitishwa. Umetuma Ksh500.00 kwa JANE DOE 0798765432. Salio lako jipya la M-PESA ni Ksh1,493.00. Gharama ya kutuma ni Ksh7.00.",
+			wantType:    TxnMPesaSent,
+			wantAmount:  500,
+			wantParty:   "JANE DOE",
+			wantBalance: 1493,
+			wantRefCode: "UA78EFGHIJ",
+		},
+		{
+			name:        "paybill",
+			log:         "UA00KPLC01 Imethibitishwa. Umelipa Ksh1,000.00 kwa KPLC akaunti 12345",
+			wantType:    TxnUtility,
+			wantAmount:  1000,
+			wantParty:   "KPLC akaunti 12345",
+			wantRefCode: "UA00KPLC01",
+		},
+		{
+			name:       "till",
+			log:        "Umelipa Ksh200.00 kwa SUPERMARKET Till Number 123456",
+			wantType:   TxnMPesaBuyGoods,
+			wantAmount: 200,
+			wantParty:  "SUPERMARKET",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType || txn.Amount != tt.wantAmount {
+				t.Errorf("got %v %v, want %v %v", txn.Type, txn.Amount, tt.wantType, tt.wantAmount)
+			}
+			party := txn.Recipient
+			if tt.wantType == TxnMPesaReceived {
+				party = txn.Sender
+			}
+			if party != tt.wantParty {
+				t.Errorf("counterparty = %q, want %q", party, tt.wantParty)
+			}
+			if txn.Balance != tt.wantBalance || txn.RefCode != tt.wantRefCode {
+				t.Errorf("balance, refcode = %v, %q, want %v, %q", txn.Balance, txn.RefCode, tt.wantBalance, tt.wantRefCode)
+			}
+			if !txn.Timestamp.Equal(tt.wantTimestamp) {
+				t.Errorf("Timestamp = %v, want %v", txn.Timestamp, tt.wantTimestamp)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_RefCodeSeries(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		wantType TransactionType
+		wantRef  string
+	}{
+		{"TD received", "TDK4ABC123 Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678", TxnMPesaReceived, "TDK4ABC123"},
+		{"TE received", "TEB7XYZ890 Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678", TxnMPesaReceived, "TEB7XYZ890"},
+		{"QK received", "QKJ3XPYC5T Confirmed. You have received Ksh3,000.00 from SARAH JANE", TxnMPesaReceived, "QKJ3XPYC5T"},
+		{"TD sent", "TDK4ABC124 Confirmed. Ksh500.00 sent to JANE DOE 0798765432", TxnMPesaSent, "TDK4ABC124"},
+		{"TE sent", "TEB7XYZ891 Confirmed. Ksh500.00 sent to JANE DOE 0798765432", TxnMPesaSent, "TEB7XYZ891"},
+		{"QK sent", "QKJ3XPYC5U Confirmed. Ksh500.00 sent to JANE DOE 0798765432", TxnMPesaSent, "QKJ3XPYC5U"},
+		{"TD paybill", "TDK4ABC125 Confirmed. Ksh1,000.00 paid to KPLC Account 12345", TxnUtility, "TDK4ABC125"},
+		{"TE paybill", "TEB7XYZ892 Confirmed. Ksh1,000.00 paid to KPLC Account 12345", TxnUtility, "TEB7XYZ892"},
+		{"QK paybill", "QKJ3XPYC5V Confirmed. Ksh1,000.00 paid to KPLC Account 12345", TxnUtility, "QKJ3XPYC5V"},
+		{"SLX sent", "SLX4ABC123 Confirmed. Ksh500.00 sent to JANE DOE 0798765432", TxnMPesaSent, "SLX4ABC123"},
+		{"TDK received", "TDKABC1234 Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678", TxnMPesaReceived, "TDKABC1234"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType || txn.RefCode != tt.wantRef {
+				t.Errorf("got %v %q, want %v %q", txn.Type, txn.RefCode, tt.wantType, tt.wantRef)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_RefCodeLength(t *testing.T) {
+	logs := []string{
+		"UA1234ABCDEF Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+		"UA1234ABCDE Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+		"UA1234ABC Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+		"UA12ABCD Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"U1234ABCDE Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+	}
+
+	for _, log := range logs {
+		if txn, err := parseSingleLog(log); err == nil {
+			t.Errorf("parseSingleLog(%q) = %v %q, want no match", log, txn.Type, txn.RefCode)
+		}
+	}
+}
+
+func TestParseSingleLog_AmountAfterCounterparty(t *testing.T) {
+	tests := []struct {
+		name        string
+		log         string
+		wantType    TransactionType
+		wantAmount  float64
+		wantParty   string
+		wantRefCode string
+	}{
+		{
+			name:       "received from JOHN DOE",
+			log:        "received from JOHN DOE Ksh1,500",
+			wantType:   TxnMPesaReceived,
+			wantAmount: 1500,
+			wantParty:  "JOHN DOE",
+		},
+		{
+			name:        "received with refcode and phone",
+			log:         "UA34ABCDEF Confirmed. You have received from JOHN DOE 0712345678 Ksh1,500.00 on 1/2/26",
+			wantType:    TxnMPesaReceived,
+			wantAmount:  1500,
+			wantParty:   "JOHN DOE",
+			wantRefCode: "UA34ABCDEF",
+		},
+		{
+			name:        "sent to JANE DOE",
+			log:         "UA78EFGHIJ Confirmed. Sent to JANE DOE 0798765432 Ksh500.00 on 1/2/26",
+			wantType:    TxnMPesaSent,
+			wantAmount:  500,
+			wantParty:   "JANE DOE",
+			wantRefCode: "UA78EFGHIJ",
+		},
+		{
+			name:        "primary order still wins",
+			log:         "UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+			wantType:    TxnMPesaReceived,
+			wantAmount:  1500,
+			wantParty:   "JOHN DOE",
+			wantRefCode: "UA34ABCDEF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType {
+				t.Errorf("Type = %v, want %v", txn.Type, tt.wantType)
+			}
+			if txn.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
+			}
+			party := txn.Sender
+			if tt.wantType == TxnMPesaSent {
+				party = txn.Recipient
+			}
+			if party != tt.wantParty {
+				t.Errorf("counterparty = %q, want %q", party, tt.wantParty)
+			}
+			if txn.RefCode != tt.wantRefCode {
+				t.Errorf("RefCode = %v, want %v", txn.RefCode, tt.wantRefCode)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_OkoaReversal(t *testing.T) {
+	txn, err := parseSingleLog("Your Okoa Jahazi advance of Ksh50 has been reversed")
+	if err != nil {
+		t.Fatalf("parseSingleLog() error = %v", err)
+	}
+	if txn.Type != TxnOkoaReceived || !txn.Reversal {
+		t.Errorf("Type = %v, Reversal = %v, want %v reversal", txn.Type, txn.Reversal, TxnOkoaReceived)
+	}
+	if txn.Amount != 50 {
+		t.Errorf("Amount = %v, want 50", txn.Amount)
+	}
+}
+
+func TestParseSingleLog_DigitalRepayReversal(t *testing.T) {
+	txn, err := parseSingleLog("Your payment of Ksh2,000.00 to Tala has been reversed")
+	if err != nil {
+		t.Fatalf("parseSingleLog() error = %v", err)
+	}
+	if txn.Type != TxnDigitalRepay || !txn.Reversal {
+		t.Errorf("Type = %v, Reversal = %v, want %v reversal", txn.Type, txn.Reversal, TxnDigitalRepay)
+	}
+	if txn.Amount != 2000 || txn.Lender != "Tala" {
+		t.Errorf("Amount, Lender = %v, %q, want 2000, %q", txn.Amount, txn.Lender, "Tala")
+	}
+}
+
+func TestParseSingleLog_FulizaFunded(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		wantType TransactionType
+	}{
+		{
+			name:     "paybill topped up",
+			log:      "UA00KPLC01 Confirmed. Ksh1,000.00 paid to KPLC. Fuliza M-PESA amount is Ksh300.00. Fee Ksh3.00",
+			wantType: TxnUtility,
+		},
+		{
+			name:     "send using Fuliza",
+			log:      "UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432 using Fuliza M-PESA",
+			wantType: TxnMPesaSent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType || !txn.FulizaFunded {
+				t.Errorf("Type = %v, FulizaFunded = %v, want %v funded", txn.Type, txn.FulizaFunded, tt.wantType)
+			}
+		})
+	}
+
+	loan, err := parseSingleLog("Fuliza M-PESA. You have borrowed Ksh2,000.00 from your limit")
+	if err != nil || loan.Type != TxnFulizaLoan || loan.FulizaFunded {
+		t.Errorf("plain Fuliza loan = %v funded %v, error %v, want FULIZA_LOAN unfunded", loan.Type, loan.FulizaFunded, err)
+	}
+}
+
 func TestParseSingleLog_Fuliza(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -371,23 +670,63 @@ func TestParseSingleLog_Gambling(t *testing.T) {
 	tests := []struct {
 		name       string
 		log        string
+		wantType   TransactionType
 		wantAmount float64
 	}{
 		{
 			name:       "Betika",
 			log:        "Betika: Your bet of Ksh100.00 has been placed",
+			wantType:   TxnGamblingStake,
 			wantAmount: 100.00,
 		},
 		{
 			name:       "SportPesa",
 			log:        "SportPesa: Win! You have received Ksh500.00",
+			wantType:   TxnGamblingWin,
 			wantAmount: 500.00,
 		},
 		{
 			name:       "Mozzart",
 			log:        "Mozzart Bet: Deposit of Ksh200.00 confirmed",
+			wantType:   TxnGamblingStake,
 			wantAmount: 200.00,
 		},
+		{
+			name:       "Odibets won",
+			log:        "Odibets: Congratulations! You have won Ksh1,250.00",
+			wantType:   TxnGamblingWin,
+			wantAmount: 1250.00,
+		},
+		{
+			name:       "Betika withdrawal",
+			log:        "Betika: Your withdrawal of Ksh3,000.00 has been sent to your M-Pesa",
+			wantType:   TxnGamblingWin,
+			wantAmount: 3000.00,
+		},
+		{
+			name:       "Betika deposit received",
+			log:        "Betika: Your deposit of Ksh500.00 has been received.",
+			wantType:   TxnGamblingStake,
+			wantAmount: 500.00,
+		},
+		{
+			name:       "SportPesa deposit received",
+			log:        "SportPesa: We have received your deposit of Ksh1,000.00. Good luck!",
+			wantType:   TxnGamblingStake,
+			wantAmount: 1000.00,
+		},
+		{
+			name:       "Odibets account credited",
+			log:        "Odibets: Your account has been credited with Ksh300.00 deposit",
+			wantType:   TxnGamblingStake,
+			wantAmount: 300.00,
+		},
+		{
+			name:       "Betika possible win",
+			log:        "Betika: Your bet of Ksh50.00 has been placed. Possible Win Ksh2,000.00",
+			wantType:   TxnGamblingStake,
+			wantAmount: 50.00,
+		},
 	}
 
 	for _, tt := range tests {
@@ -396,8 +735,8 @@ func TestParseSingleLog_Gambling(t *testing.T) {
 			if err != nil {
 				t.Fatalf("parseSingleLog() error = %v", err)
 			}
-			if txn.Type != TxnGambling {
-				t.Errorf("Type = %v, want %v", txn.Type, TxnGambling)
+			if txn.Type != tt.wantType {
+				t.Errorf("Type = %v, want %v", txn.Type, tt.wantType)
 			}
 			if txn.Amount != tt.wantAmount {
 				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
@@ -411,7 +750,7 @@ func TestParseLogs(t *testing.T) {
 	ctx := context.Background()
 
 	logs := []string{
-		"UA1234ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
 		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
 		"Invalid log message that won't match",
 		"Hustler Fund. You have been disbursed Ksh500.00",
@@ -450,7 +789,7 @@ func TestParseLogs_ContextCancellation(t *testing.T) {
 
 	logs := make([]string, 200) // Large enough to trigger check
 	for i := range logs {
-		logs[i] = "UA1234ABCDEF Confirmed. You have received Ksh100.00 from TEST"
+		logs[i] = "UA34ABCDEF Confirmed. You have received Ksh100.00 from TEST"
 	}
 
 	_, err := parser.ParseLogs(ctx, logs)
@@ -459,27 +798,1530 @@ func TestParseLogs_ContextCancellation(t *testing.T) {
 	}
 }
 
-func TestTransactionType_String(t *testing.T) {
+func TestParseLogsWithReport(t *testing.T) {
+	logs := []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"Your OTP is 123456. Do not share it with anyone.",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+		"Equity: Today: 3 withdrawals totaling Ksh12,000, 1 deposit totaling Ksh4,000",
+		"Safaricom: Get 1GB for Ksh99 today only. Dial *544#",
+	}
+
+	p := NewParser().(*DefaultParser)
+	txns, report, err := p.ParseLogsWithReport(context.Background(), logs)
+	if err != nil {
+		t.Fatalf("ParseLogsWithReport() error = %v", err)
+	}
+	if len(txns) != 4 {
+		t.Errorf("got %d transactions, want 4", len(txns))
+	}
+	if report.Total != 5 || report.Parsed != 3 || report.Skipped != 2 {
+		t.Errorf("report = %+v, want total 5, parsed 3, skipped 2", report)
+	}
+	if !slices.Equal(report.SkippedIndices, []int{1, 4}) {
+		t.Errorf("SkippedIndices = %v, want [1 4]", report.SkippedIndices)
+	}
+
+	plain, err := p.ParseLogs(context.Background(), logs)
+	if err != nil || len(plain) != len(txns) {
+		t.Errorf("ParseLogs() = %d txns, error %v, want the same %d", len(plain), err, len(txns))
+	}
+}
+
+func TestParseLogsWithReport_AmountCeiling(t *testing.T) {
+	logs := []string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		// A garbled export spliced a phone number into the amount
+		"UA00SEND01 Confirmed. Ksh712,345,678.00 sent to JANE DOE 0798765432",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+	}
+
+	txns, report, err := NewParser().(*DefaultParser).ParseLogsWithReport(context.Background(), logs)
+	if err != nil {
+		t.Fatalf("ParseLogsWithReport() error = %v", err)
+	}
+	for _, txn := range txns {
+		if txn.Amount > DefaultMaxAmount {
+			t.Errorf("kept %v of %v, want it excluded", txn.Type, txn.Amount)
+		}
+	}
+	if len(txns) != 2 {
+		t.Errorf("got %d transactions, want 2", len(txns))
+	}
+	if report.Parsed != 2 || report.Rejected != 1 || !slices.Equal(report.RejectedIndices, []int{1}) {
+		t.Errorf("report = %+v, want parsed 2, rejected [1]", report)
+	}
+
+	// A lower ceiling rejects the loan as well
+	p := NewParserWithConfig(ParserConfig{MaxAmount: 1500}).(*DefaultParser)
+	if _, report, _ := p.ParseLogsWithReport(context.Background(), logs); !slices.Equal(report.RejectedIndices, []int{1, 2}) {
+		t.Errorf("RejectedIndices = %v with MaxAmount 1500, want [1 2]", report.RejectedIndices)
+	}
+}
+
+func TestParseLogs_UnicodeSpacesAndDigits(t *testing.T) {
+	logs := []string{
+		"UA34ABCDEF Confirmed.\u00a0You have received Ksh\u00a01,500.00 from JOHN DOE 0712345678",
+		"UA78EFGHIJ Confirmed. Ksh\uff15\uff10\uff10.\uff10\uff10 sent to JANE\u2009DOE 0798765432 on 3/2/26",
+	}
+
+	txns, err := NewParser().ParseLogs(context.Background(), logs)
+	if err != nil {
+		t.Fatalf("ParseLogs() error = %v", err)
+	}
+	if len(txns) != 2 {
+		t.Fatalf("got %d transactions, want 2", len(txns))
+	}
+	if txns[0].Type != TxnMPesaReceived || txns[0].Amount != 1500 || txns[0].Sender != "JOHN DOE" {
+		t.Errorf("txns[0] = %v %v from %q, want MPESA_RECEIVED 1500 from JOHN DOE", txns[0].Type, txns[0].Amount, txns[0].Sender)
+	}
+	if txns[1].Type != TxnMPesaSent || txns[1].Amount != 500 || txns[1].Recipient != "JANE DOE" || txns[1].Timestamp.IsZero() {
+		t.Errorf("txns[1] = %v %v to %q at %v, want a dated MPESA_SENT 500 to JANE DOE", txns[1].Type, txns[1].Amount, txns[1].Recipient, txns[1].Timestamp)
+	}
+	for i, txn := range txns {
+		if txn.RawText != logs[i] {
+			t.Errorf("txns[%d] RawText = %q, want the original message", i, txn.RawText)
+		}
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	dump := strings.Join([]string{
+		"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+		"",
+		"Your OTP is 123456. Do not share it with anyone.",
+		"Fuliza M-PESA. You have borrowed Ksh2,000.00",
+		"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+	}, "\n")
+
+	p := NewParser().(*DefaultParser)
+	got, err := p.ParseReader(context.Background(), strings.NewReader(dump))
+	if err != nil {
+		t.Fatalf("ParseReader() error = %v", err)
+	}
+	want, _ := p.ParseLogs(context.Background(), strings.Split(dump, "\n"))
+	if len(got) != 3 || len(got) != len(want) {
+		t.Fatalf("ParseReader() = %d txns, want 3 like ParseLogs (%d)", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].Type != want[i].Type || got[i].Amount != want[i].Amount || got[i].RawText != want[i].RawText {
+			t.Errorf("txn %d = %v %v, want %v %v", i, got[i].Type, got[i].Amount, want[i].Type, want[i].Amount)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.ParseReader(ctx, strings.NewReader(dump)); !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseReader() with cancelled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestParseSingle(t *testing.T) {
+	txn, err := ParseSingle("UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678")
+	if err != nil {
+		t.Fatalf("ParseSingle() error = %v", err)
+	}
+	if txn.Type != TxnMPesaReceived || txn.Amount != 1500 {
+		t.Errorf("got %v %v, want MPESA_RECEIVED 1500", txn.Type, txn.Amount)
+	}
+
+	for _, garbage := range []string{"", "hello there", "Your OTP is 123456. Do not share it with anyone."} {
+		if txn, err := ParseSingle(garbage); err == nil {
+			t.Errorf("ParseSingle(%q) = %v, want an error", garbage, txn.Type)
+		}
+	}
+}
+
+func TestParseLogs_RedactRawText(t *testing.T) {
+	log := "UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678"
+	ctx := context.Background()
+
+	plain, err := NewParser().ParseLogs(ctx, []string{log})
+	if err != nil || len(plain) != 1 {
+		t.Fatalf("ParseLogs() = %d txns, error %v", len(plain), err)
+	}
+	if plain[0].RawText != log {
+		t.Errorf("RawText = %q by default, want original message", plain[0].RawText)
+	}
+
+	redacted, err := NewParserWithConfig(ParserConfig{RedactRawText: true}).ParseLogs(ctx, []string{log})
+	if err != nil || len(redacted) != 1 {
+		t.Fatalf("ParseLogs() = %d txns, error %v", len(redacted), err)
+	}
+	txn := redacted[0]
+	if strings.Contains(txn.RawText, "JOHN DOE") || txn.RawText == log {
+		t.Errorf("RawText = %q, want message body redacted", txn.RawText)
+	}
+	if len(txn.RawText) != 64 {
+		t.Errorf("RawText = %q, want hex SHA-256 digest", txn.RawText)
+	}
+
+	// Extracted fields are unaffected by redaction
+	want := plain[0]
+	want.RawText = txn.RawText
+	if txn != want {
+		t.Errorf("redacted txn = %+v, want %+v", txn, want)
+	}
+}
+
+func TestUnescapeJSON(t *testing.T) {
 	tests := []struct {
-		txnType  TransactionType
-		expected string
+		name string
+		in   string
+		want string
 	}{
-		{TxnMPesaReceived, "MPESA_RECEIVED"},
-		{TxnAirtelSent, "AIRTEL_SENT"},
-		{TxnHustlerLoan, "HUSTLER_LOAN"},
-		{TxnOkoaReceived, "OKOA_RECEIVED"},
-		{TxnMMFDeposit, "MMF_DEPOSIT"},
-		{TxnDigitalLoan, "DIGITAL_LOAN"},
-		{TxnBankDeposit, "BANK_DEPOSIT"},
-		{TxnGambling, "GAMBLING"},
-		{TxnUnknown, "UNKNOWN"},
+		{"escaped quotes and space", `Ksh1,500.00 from \"JOHN DOE\"\u00a0ok`, "Ksh1,500.00 from \"JOHN DOE\"\u00a0ok"},
+		{"quoted body", `"UA34ABCDEF Confirmed.\nBalance"`, "UA34ABCDEF Confirmed.\nBalance"},
+		{"escaped twice", `say \\\"hi\\\"`, `say "hi"`},
+		{"plain message", "Ksh1,500.00 sent to JANE", "Ksh1,500.00 sent to JANE"},
+		{"legitimate backslash", `Account A\B12 paid`, `Account A\B12 paid`},
+		{"bare quote", `He said "hi" \n`, `He said "hi" \n`},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.expected, func(t *testing.T) {
-			if got := tt.txnType.String(); got != tt.expected {
-				t.Errorf("String() = %v, want %v", got, tt.expected)
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unescapeJSON(tt.in); got != tt.want {
+				t.Errorf("unescapeJSON(%q) = %q, want %q", tt.in, got, tt.want)
 			}
 		})
 	}
 }
+
+func TestParseLogs_UnescapeJSON(t *testing.T) {
+	log := `UA34ABCDEF Confirmed.\tYou have received\tKsh1,500.00 from JOHN DOE 0712345678 on 1\/2\/26.\nNew M-PESA balance is Ksh2,000.00`
+	ctx := context.Background()
+
+	if plain, _ := NewParser().ParseLogs(ctx, []string{log}); len(plain) != 0 {
+		t.Fatalf("ParseLogs() without UnescapeJSON = %+v, want the escaped message unparsed", plain)
+	}
+
+	txns, err := NewParserWithConfig(ParserConfig{UnescapeJSON: true}).ParseLogs(ctx, []string{log})
+	if err != nil || len(txns) != 1 {
+		t.Fatalf("ParseLogs() = %d txns, error %v", len(txns), err)
+	}
+	txn := txns[0]
+	if txn.Type != TxnMPesaReceived || txn.Amount != 1500 || txn.Balance != 2000 {
+		t.Errorf("got %v %v balance %v, want MPESA_RECEIVED 1500 balance 2000", txn.Type, txn.Amount, txn.Balance)
+	}
+	if txn.Sender != "JOHN DOE" {
+		t.Errorf("Sender = %q, want %q", txn.Sender, "JOHN DOE")
+	}
+	if txn.RawText != log {
+		t.Errorf("RawText = %q, want the message as received", txn.RawText)
+	}
+}
+
+func TestParseLogs_Digest(t *testing.T) {
+	log := "Equity: Today: 3 deposits totaling Ksh12,000, 2 withdrawals totaling Ksh4,000. Thank you."
+	txns, err := NewParser().ParseLogs(context.Background(), []string{log})
+	if err != nil {
+		t.Fatalf("ParseLogs() error = %v", err)
+	}
+
+	want := []struct {
+		typ    TransactionType
+		amount float64
+	}{
+		{TxnBankWithdraw, 12000}, // Money into the bank account is income
+		{TxnBankDeposit, 4000},
+	}
+	if len(txns) != len(want) {
+		t.Fatalf("ParseLogs() = %d txns, want %d", len(txns), len(want))
+	}
+	for i, w := range want {
+		txn := txns[i]
+		if txn.Type != w.typ || txn.Amount != w.amount {
+			t.Errorf("txns[%d] = %v %v, want %v %v", i, txn.Type, txn.Amount, w.typ, w.amount)
+		}
+		if !txn.Derived || txn.Confidence != ConfidenceGeneric {
+			t.Errorf("txns[%d] Derived = %v, Confidence = %v, want derived at %v", i, txn.Derived, txn.Confidence, ConfidenceGeneric)
+		}
+		if txn.RawText != log {
+			t.Errorf("txns[%d] RawText = %q, want the digest", i, txn.RawText)
+		}
+	}
+}
+
+func TestTransactionType_StableValues(t *testing.T) {
+	// Values are stored and exchanged; new types must be appended, not inserted
+	tests := []struct {
+		txnType TransactionType
+		want    int
+	}{
+		{TxnUnknown, 0},
+		{TxnMPesaReceived, 1},
+		{TxnFulizaRepay, 6},
+		{TxnDigitalRepay, 16},
+		{TxnBankWithdraw, 20},
+		{TxnGamblingStake, 21},
+		{TxnUtility, 22},
+		{TxnLoanPending, 23},
+	}
+	for _, tt := range tests {
+		if int(tt.txnType) != tt.want {
+			t.Errorf("%v = %d, want %d", tt.txnType, tt.txnType, tt.want)
+		}
+	}
+}
+
+func TestTransactionType_String(t *testing.T) {
+	tests := []struct {
+		txnType  TransactionType
+		expected string
+	}{
+		{TxnMPesaReceived, "MPESA_RECEIVED"},
+		{TxnAirtelSent, "AIRTEL_SENT"},
+		{TxnHustlerLoan, "HUSTLER_LOAN"},
+		{TxnOkoaReceived, "OKOA_RECEIVED"},
+		{TxnMMFDeposit, "MMF_DEPOSIT"},
+		{TxnDigitalLoan, "DIGITAL_LOAN"},
+		{TxnBankDeposit, "BANK_DEPOSIT"},
+		{TxnBankLoanRepay, "BANK_LOAN_REPAY"},
+		{TxnGamblingStake, "GAMBLING_STAKE"},
+		{TxnGamblingWin, "GAMBLING_WIN"},
+		{TxnUnknown, "UNKNOWN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := tt.txnType.String(); got != tt.expected {
+				t.Errorf("String() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTransactionType(t *testing.T) {
+	for typ := TxnUnknown + 1; typ < numTransactionTypes; typ++ {
+		got, err := ParseTransactionType(typ.String())
+		if err != nil || got != typ {
+			t.Errorf("ParseTransactionType(%q) = %v, %v, want %v", typ.String(), got, err, typ)
+		}
+	}
+	if got, err := ParseTransactionType("GAMBLING"); err != nil || got != TxnGamblingStake {
+		t.Errorf("ParseTransactionType(%q) = %v, %v, want %v", "GAMBLING", got, err, TxnGamblingStake)
+	}
+	for _, s := range []string{"UNKNOWN", "mpesa_received", ""} {
+		if _, err := ParseTransactionType(s); err == nil {
+			t.Errorf("ParseTransactionType(%q) error = nil, want an error", s)
+		}
+	}
+}
+
+func TestTransaction_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(Transaction{Type: TxnMPesaReceived})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `{"Type":"MPESA_RECEIVED",`) {
+		t.Errorf("Marshal() = %s, want the type as a string", data)
+	}
+	if strings.Contains(string(data), "Timestamp") {
+		t.Errorf("Marshal() = %s, want no Timestamp when it is zero", data)
+	}
+
+	want := Transaction{
+		Type:      TxnFulizaLoan,
+		Amount:    2000,
+		Timestamp: time.Date(2026, time.January, 28, 13, 5, 0, 0, eastAfricaTime),
+		Reversal:  true,
+	}
+	if data, err = json.Marshal(want); err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"Timestamp":"2026-01-28T13:05:00+03:00"`) {
+		t.Errorf("Marshal() = %s, want an RFC 3339 Timestamp", data)
+	}
+
+	var got Transaction
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", data, err)
+	}
+	if !got.Timestamp.Equal(want.Timestamp) {
+		t.Errorf("Timestamp = %v, want %v", got.Timestamp, want.Timestamp)
+	}
+	got.Timestamp = want.Timestamp
+	if got != want {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+
+	if err := json.Unmarshal([]byte(`{"Type":"NOT_A_TYPE"}`), &got); err == nil {
+		t.Error("Unmarshal() of an unknown type error = nil, want an error")
+	}
+}
+
+func TestParseSingleLog_LoanPending(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantType   TransactionType
+		wantAmount float64
+	}{
+		{
+			name:     "Tala processing notice",
+			log:      "Tala: Your loan application is being processed. We will notify you shortly",
+			wantType: TxnLoanPending,
+		},
+		{
+			name:     "Branch pending approval",
+			log:      "Branch: Your loan request of Ksh3,000.00 is pending approval",
+			wantType: TxnLoanPending,
+		},
+		{
+			name:       "Tala disbursement",
+			log:        "You have received Ksh5,000.00 from Tala",
+			wantType:   TxnDigitalLoan,
+			wantAmount: 5000.00,
+		},
+		{
+			name:       "disbursement mentioning a pending repayment",
+			log:        "You have received Ksh5,000.00 from Tala. Your loan repayment of Ksh5,500.00 is pending, due 12/3/26",
+			wantType:   TxnDigitalLoan,
+			wantAmount: 5000.00,
+		},
+		{
+			name:       "repayment mentioning a pending balance",
+			log:        "Ksh2,000.00 received by Branch. Your loan balance of Ksh1,000.00 is pending",
+			wantType:   TxnDigitalRepay,
+			wantAmount: 2000.00,
+		},
+		{
+			name:       "KCB disbursement mentioning a pending request",
+			log:        "KCB M-PESA loan of Ksh3,000 disbursed to your M-PESA account. Your top-up loan request is pending",
+			wantType:   TxnKCBLoan,
+			wantAmount: 3000.00,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType {
+				t.Errorf("Type = %v, want %v", txn.Type, tt.wantType)
+			}
+			if txn.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_LenderWithoutCreditWording(t *testing.T) {
+	// Marketing copy mentions a lender and an amount but no disbursement
+	_, err := parseSingleLog("Zenka: Get up to Ksh10,000 today. Apply now!")
+	if err == nil {
+		t.Error("parseSingleLog() should not treat a lender advert as a loan")
+	}
+}
+
+func TestParseSingleLog_Institutional(t *testing.T) {
+	tests := []struct {
+		name              string
+		log               string
+		wantAmount        float64
+		wantInstitutional bool
+	}{
+		{
+			name:              "B2C from company",
+			log:               "UA77BIZPAY Confirmed. You have received Ksh20,000.00 from SAFARICOM LIMITED 123456",
+			wantAmount:        20000.00,
+			wantInstitutional: true,
+		},
+		{
+			name:              "Business payment via API",
+			log:               "UB34APIPAY Confirmed. You have received Ksh3,500.00 from EQUITY via API",
+			wantAmount:        3500.00,
+			wantInstitutional: true,
+		},
+		{
+			name:       "P2P receipt",
+			log:        "UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678",
+			wantAmount: 1500.00,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != TxnMPesaReceived {
+				t.Errorf("Type = %v, want %v", txn.Type, TxnMPesaReceived)
+			}
+			if txn.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
+			}
+			if txn.Institutional != tt.wantInstitutional {
+				t.Errorf("Institutional = %v, want %v", txn.Institutional, tt.wantInstitutional)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_BongaRedeem(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantAmount float64
+	}{
+		{
+			name:       "Ksh worth",
+			log:        "You have redeemed Ksh100 worth of Bonga Points. Your new Bonga balance is 250",
+			wantAmount: 100,
+		},
+		{
+			name: "Bundle redemption",
+			log:  "You have redeemed 500 Bonga Points for 1GB data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != TxnBongaRedeem {
+				t.Errorf("Type = %v, want %v", txn.Type, TxnBongaRedeem)
+			}
+			if txn.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
+			}
+			if !txn.Type.IsInformational() {
+				t.Error("Bonga redemption should be informational")
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_HustlerSplit(t *testing.T) {
+	txn, err := parseSingleLog("You have received Ksh950.00 as Hustler Fund loan disbursed to your M-PESA and Ksh50.00 to your savings.")
+	if err != nil {
+		t.Fatalf("parseSingleLog() error = %v", err)
+	}
+	if txn.Type != TxnHustlerLoan {
+		t.Errorf("Type = %v, want %v", txn.Type, TxnHustlerLoan)
+	}
+	if txn.Amount != 950 {
+		t.Errorf("Amount = %v, want 950", txn.Amount)
+	}
+	if txn.Saved != 50 {
+		t.Errorf("Saved = %v, want 50", txn.Saved)
+	}
+}
+
+func TestParseSingleLog_HustlerReversal(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantType   TransactionType
+		wantAmount float64
+	}{
+		{
+			name:       "Disbursement reversed",
+			log:        "Hustler Fund: Your loan disbursement of Ksh500.00 has been reversed.",
+			wantType:   TxnHustlerLoan,
+			wantAmount: 500.00,
+		},
+		{
+			name:       "Repayment reversed",
+			log:        "Hustler Fund: Reversal of your repayment of Ksh200.00 completed.",
+			wantType:   TxnHustlerRepay,
+			wantAmount: 200.00,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType {
+				t.Errorf("Type = %v, want %v", txn.Type, tt.wantType)
+			}
+			if !txn.Reversal {
+				t.Error("Reversal = false, want true")
+			}
+			if txn.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_PaybillReversal(t *testing.T) {
+	txn, err := parseSingleLog("Your payment to NAIROBI WATER of Ksh800 has been reversed")
+	if err != nil {
+		t.Fatalf("parseSingleLog() error = %v", err)
+	}
+	if txn.Type != TxnUtility {
+		t.Errorf("Type = %v, want %v", txn.Type, TxnUtility)
+	}
+	if !txn.Reversal {
+		t.Error("Reversal = false, want true")
+	}
+	if txn.Amount != 800 {
+		t.Errorf("Amount = %v, want 800", txn.Amount)
+	}
+	if txn.Recipient != "NAIROBI WATER" {
+		t.Errorf("Recipient = %q, want %q", txn.Recipient, "NAIROBI WATER")
+	}
+}
+
+func TestParseSingleLog_ReversalPending(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     string
+		pending bool
+	}{
+		{"being processed", "Reversal of your payment to NAIROBI WATER of Ksh800 is being processed", true},
+		{"will be reversed", "Your payment to NAIROBI WATER of Ksh800 will be reversed within 24 hours", true},
+		{"pending airtel", "Airtel Money: Reversal of Ksh300 sent to JOHN is pending", true},
+		{"completed", "Your payment to NAIROBI WATER of Ksh800 has been reversed", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if got := txn.Type == TxnReversalPending; got != tt.pending {
+				t.Errorf("Type = %v, pending %v, want pending %v", txn.Type, got, tt.pending)
+			}
+			if txn.Reversal == tt.pending {
+				t.Errorf("Reversal = %v, want %v", txn.Reversal, !tt.pending)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_Confidence(t *testing.T) {
+	tests := []struct {
+		log  string
+		want float64
+	}{
+		{"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678", ConfidenceExact},
+		{"Airtel Money: Your transaction of Ksh200.00 was successful", ConfidenceGeneric},
+		{"M-Shwari Lock Savings Ksh300.00", ConfidenceGeneric},
+		{"Tala: Ksh2,000.00 has been credited to your M-PESA", ConfidenceGeneric},
+	}
+
+	for _, tt := range tests {
+		txn, err := parseSingleLog(tt.log)
+		if err != nil {
+			t.Fatalf("parseSingleLog(%q) error = %v", tt.log, err)
+		}
+		if txn.Confidence != tt.want {
+			t.Errorf("parseSingleLog(%q).Confidence = %v, want %v", tt.log, txn.Confidence, tt.want)
+		}
+	}
+}
+
+func TestParseSingleLog_WalletReversal(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantType   TransactionType
+		wantAmount float64
+	}{
+		{
+			name:       "Airtel send reversed",
+			log:        "Airtel Money: Your transaction of Ksh500.00 sent to JANE DOE has been reversed.",
+			wantType:   TxnAirtelSent,
+			wantAmount: 500,
+		},
+		{
+			name:       "Airtel receipt reversed",
+			log:        "Airtel Money: Ksh1,000.00 received from JOHN DOE has been reversed.",
+			wantType:   TxnAirtelReceived,
+			wantAmount: 1000,
+		},
+		{
+			name:       "T-Kash send reversed",
+			log:        "T-Kash: Reversal of Ksh300.00 sent to JANE DOE completed.",
+			wantType:   TxnTKashSent,
+			wantAmount: 300,
+		},
+		{
+			name:       "T-Kash receipt reversed",
+			log:        "T-Kash: Ksh1,000.00 received from JOHN DOE has been reversed.",
+			wantType:   TxnTKashReceived,
+			wantAmount: 1000,
+		},
+		{
+			name:       "Airtel send reversed, refund received",
+			log:        "Airtel Money: Your transaction of Ksh500.00 sent to JANE DOE has been reversed. You have received Ksh500.00 back.",
+			wantType:   TxnAirtelSent,
+			wantAmount: 500,
+		},
+		{
+			name:       "T-Kash reversal received in wallet",
+			log:        "T-Kash: Reversal of Ksh300.00 received in your wallet. Ksh300.00 credited back.",
+			wantType:   TxnTKashSent,
+			wantAmount: 300,
+		},
+		{
+			name:       "T-Kash receipt reversed, money debited",
+			log:        "T-Kash: Reversal of Ksh700.00 completed. Ksh700.00 has been debited from your wallet.",
+			wantType:   TxnTKashReceived,
+			wantAmount: 700,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType {
+				t.Errorf("Type = %v, want %v", txn.Type, tt.wantType)
+			}
+			if !txn.Reversal {
+				t.Error("Reversal = false, want true")
+			}
+			if txn.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_C2BReceived(t *testing.T) {
+	txn, err := parseSingleLog("UA76ZYXWVU Confirmed. Ksh5,000.00 received from PAYBILL 400200 SALARY")
+	if err != nil {
+		t.Fatalf("parseSingleLog() error = %v", err)
+	}
+	if txn.Type != TxnMPesaReceived {
+		t.Errorf("Type = %v, want %v", txn.Type, TxnMPesaReceived)
+	}
+	if txn.Amount != 5000 {
+		t.Errorf("Amount = %v, want 5000", txn.Amount)
+	}
+	if txn.Sender != "PAYBILL 400200 SALARY" {
+		t.Errorf("Sender = %q, want %q", txn.Sender, "PAYBILL 400200 SALARY")
+	}
+	if txn.RefCode != "UA76ZYXWVU" {
+		t.Errorf("RefCode = %q, want %q", txn.RefCode, "UA76ZYXWVU")
+	}
+	if !txn.Institutional {
+		t.Error("Institutional = false, want true for a paybill payout")
+	}
+}
+
+func TestParseSingleLog_BankTransferReversal(t *testing.T) {
+	txn, err := parseSingleLog("Your transfer to EQUITY of Ksh10,000 failed and was reversed")
+	if err != nil {
+		t.Fatalf("parseSingleLog() error = %v", err)
+	}
+	if txn.Type != TxnBankDeposit {
+		t.Errorf("Type = %v, want %v", txn.Type, TxnBankDeposit)
+	}
+	if !txn.Reversal {
+		t.Error("Reversal = false, want true")
+	}
+	if txn.Amount != 10000 {
+		t.Errorf("Amount = %v, want 10000", txn.Amount)
+	}
+	if txn.Recipient != "EQUITY" {
+		t.Errorf("Recipient = %q, want %q", txn.Recipient, "EQUITY")
+	}
+}
+
+func TestParseSingleLog_MPesaReversal(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		wantType TransactionType
+	}{
+		{
+			name:     "receipt clawed back",
+			log:      "UA9REV0001 Confirmed. Transaction UA34ABCDEF has been reversed. Ksh500.00 has been debited from your M-PESA account. New M-PESA balance is Ksh1,000.00.",
+			wantType: TxnMPesaReceived,
+		},
+		{
+			name:     "send refunded",
+			log:      "UA9REV0002 Confirmed. Transaction UA78EFGHIJ has been reversed. Ksh500.00 is credited to your M-PESA account. New M-PESA balance is Ksh1,500.00.",
+			wantType: TxnMPesaSent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType || !txn.Reversal || txn.Amount != 500 {
+				t.Errorf("got %v %v reversal %v, want %v 500 reversal true", txn.Type, txn.Amount, txn.Reversal, tt.wantType)
+			}
+		})
+	}
+
+	// Without debit or credit wording the reversed flow is unknown
+	if _, err := parseSingleLog("Confirmed. Transaction UA34ABCDEF has been reversed. Ksh500.00"); err == nil {
+		t.Error("parseSingleLog() accepted a reversal without a direction")
+	}
+}
+
+func TestParseSingleLog_FailedSkipped(t *testing.T) {
+	logs := []string{
+		"Failed. You do not have enough money in your M-PESA account to send Ksh500.00 to JANE DOE 0798765432.",
+		"UA34ABCDEF Failed. Ksh1,000.00 paid to KPLC Account 12345 could not be completed.",
+	}
+	for _, log := range logs {
+		if txn, err := parseSingleLog(log); err == nil {
+			t.Errorf("parseSingleLog(%q) = %v %v, want an error", log, txn.Type, txn.Amount)
+		}
+	}
+}
+
+func TestParseSingleLog_TillReversal(t *testing.T) {
+	txn, err := parseSingleLog("Your payment of Ksh200.00 to till 123456 has been reversed")
+	if err != nil {
+		t.Fatalf("parseSingleLog() error = %v", err)
+	}
+	if txn.Type != TxnMPesaBuyGoods {
+		t.Errorf("Type = %v, want %v", txn.Type, TxnMPesaBuyGoods)
+	}
+	if !txn.Reversal {
+		t.Error("Reversal = false, want true")
+	}
+	if txn.Amount != 200 {
+		t.Errorf("Amount = %v, want 200", txn.Amount)
+	}
+	if txn.Recipient != "123456" {
+		t.Errorf("Recipient = %q, want %q", txn.Recipient, "123456")
+	}
+}
+
+func TestParseSingleLog_BuyGoodsNotPaybill(t *testing.T) {
+	txn, err := parseSingleLog("UA34ABCDEF Confirmed. Ksh200.00 paid to SUPERMARKET Till Number 123456 on 1/2/26")
+	if err != nil {
+		t.Fatalf("parseSingleLog() error = %v", err)
+	}
+	if txn.Type != TxnMPesaBuyGoods {
+		t.Errorf("Type = %v, want %v", txn.Type, TxnMPesaBuyGoods)
+	}
+}
+
+func TestParseSingleLog_AmountAdjacentToBalance(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantAmount float64
+	}{
+		{
+			// The old [\d,]+ group read this as 20001620
+			name:       "comma-joined balance",
+			log:        "Fuliza M-PESA. You have borrowed Ksh2,000,1,620.00 M-PESA balance",
+			wantAmount: 2000,
+		},
+		{
+			name:       "digits after decimals",
+			log:        "Fuliza M-PESA. You have borrowed Ksh2,000.001620.00 M-PESA balance",
+			wantAmount: 2000,
+		},
+		{
+			name:       "plain amount",
+			log:        "Fuliza M-PESA. You have borrowed Ksh2000.50",
+			wantAmount: 2000.50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_MPesaBalance(t *testing.T) {
+	tests := []struct {
+		name        string
+		log         string
+		wantType    TransactionType
+		wantBalance float64
+		noBalance   bool
+	}{
+		{
+			name:        "received",
+			log:         "UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678 on 20/1/26 at 3:45 PM. New M-PESA balance is Ksh4,500.00.",
+			wantType:    TxnMPesaReceived,
+			wantBalance: 4500,
+		},
+		{
+			name:        "sent",
+			log:         "UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432 on 20/1/26. New M-PESA balance is Ksh4,000.00. Transaction cost, Ksh7.00.",
+			wantType:    TxnMPesaSent,
+			wantBalance: 4000,
+		},
+		{
+			name:        "paybill",
+			log:         "UA00KPLC01 Confirmed. Ksh1,000.00 paid to KPLC Account 12345. New M-PESA balance is Ksh2,993.00.",
+			wantType:    TxnUtility,
+			wantBalance: 2993,
+		},
+		{
+			name:        "buy goods",
+			log:         "UA34ABCDEF Confirmed. Ksh200.00 paid to SUPERMARKET Till Number 123456. New M-PESA balance is Ksh2,793.00.",
+			wantType:    TxnMPesaBuyGoods,
+			wantBalance: 2793,
+		},
+		{
+			name:     "empty wallet",
+			log:      "UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432 on 20/1/26. New M-PESA balance is Ksh0.00.",
+			wantType: TxnMPesaSent,
+		},
+		{
+			name:      "no balance clause",
+			log:       "UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+			wantType:  TxnMPesaSent,
+			noBalance: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType || txn.Balance != tt.wantBalance {
+				t.Errorf("got %v balance %v, want %v balance %v", txn.Type, txn.Balance, tt.wantType, tt.wantBalance)
+			}
+			if txn.HasBalance == tt.noBalance {
+				t.Errorf("HasBalance = %v, want %v", txn.HasBalance, !tt.noBalance)
+			}
+		})
+	}
+}
+
+func TestSplitPhone(t *testing.T) {
+	tests := []struct {
+		party     string
+		wantName  string
+		wantPhone string
+	}{
+		{"JOHN DOE 0712345678", "JOHN DOE", "0712345678"},
+		{"JANE DOE 254798765432", "JANE DOE", "254798765432"},
+		{"JANE DOE +254798765432", "JANE DOE", "+254798765432"},
+		{"MAMA MBOGA 0112345678", "MAMA MBOGA", "0112345678"},
+		{"JOHN DOE", "JOHN DOE", ""},
+		{"SAFARICOM LIMITED 123456", "SAFARICOM LIMITED 123456", ""}, // Paybill number, not a phone
+		{"JOHN DOE 0812345678", "JOHN DOE 0812345678", ""},
+	}
+
+	for _, tt := range tests {
+		name, phone := splitPhone(tt.party)
+		if name != tt.wantName || phone != tt.wantPhone {
+			t.Errorf("splitPhone(%q) = %q, %q, want %q, %q", tt.party, name, phone, tt.wantName, tt.wantPhone)
+		}
+	}
+}
+
+func TestParseSingleLog_Phone(t *testing.T) {
+	tests := []struct {
+		name      string
+		log       string
+		wantParty string
+		wantPhone string
+	}{
+		{
+			name:      "received, 07 number",
+			log:       "UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678 on 20/1/26 at 3:45 PM.",
+			wantParty: "JOHN DOE",
+			wantPhone: "0712345678",
+		},
+		{
+			name:      "sent, 254 number",
+			log:       "UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 254798765432 on 20/1/26.",
+			wantParty: "JANE DOE",
+			wantPhone: "254798765432",
+		},
+		{
+			name:      "sent, 01 number",
+			log:       "UA78EFGHIJ Confirmed. Ksh500.00 sent to MAMA MBOGA 0112345678 on 20/1/26.",
+			wantParty: "MAMA MBOGA",
+			wantPhone: "0112345678",
+		},
+		{
+			name:      "no number",
+			log:       "QKJ3XPYC5T Confirmed. You have received Ksh3,000.00 from SARAH JANE",
+			wantParty: "SARAH JANE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			party := txn.Sender + txn.Recipient
+			if party != tt.wantParty || txn.Phone != tt.wantPhone {
+				t.Errorf("party, phone = %q, %q, want %q, %q", party, txn.Phone, tt.wantParty, tt.wantPhone)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_TransactionCost(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		wantType TransactionType
+		wantCost float64
+	}{
+		{
+			name:     "send with comma",
+			log:      "UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432 on 20/1/26 at 3:45 PM. New M-PESA balance is Ksh1,845.00. Transaction cost, Ksh23.00.",
+			wantType: TxnMPesaSent,
+			wantCost: 23,
+		},
+		{
+			name:     "free paybill without comma",
+			log:      "UA00KPLC01 Confirmed. Ksh1,000.00 paid to KPLC Account 12345. New M-PESA balance is Ksh845.00. Transaction cost Ksh 0.00",
+			wantType: TxnUtility,
+			wantCost: 0,
+		},
+		{
+			name:     "till payment",
+			log:      "UA34ABCDEF Confirmed. Ksh200.00 paid to SUPERMARKET Till Number 123456. Transaction cost, Ksh15.00.",
+			wantType: TxnMPesaBuyGoods,
+			wantCost: 15,
+		},
+		{
+			name:     "not quoted",
+			log:      "UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+			wantType: TxnMPesaSent,
+			wantCost: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType || txn.Cost != tt.wantCost {
+				t.Errorf("got %v cost %v, want %v cost %v", txn.Type, txn.Cost, tt.wantType, tt.wantCost)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_BankReceivedSent(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantType   TransactionType
+		wantAmount float64
+		wantParty  string
+		wantPhone  string
+	}{
+		{
+			name:       "Equitel received",
+			log:        "Equitel: You have received Ksh5,000.00 from JOHN DOE 0712345678",
+			wantType:   TxnBankReceived,
+			wantAmount: 5000.00,
+			wantParty:  "JOHN DOE",
+			wantPhone:  "0712345678",
+		},
+		{
+			name:       "Equitel sent",
+			log:        "Equitel: Ksh5,000.00 sent to JOHN DOE 0712345678",
+			wantType:   TxnBankSent,
+			wantAmount: 5000.00,
+			wantParty:  "JOHN DOE",
+			wantPhone:  "0712345678",
+		},
+		{
+			name:       "KCB credited",
+			log:        "KCB: Ksh3,000.00 has been credited to your account ending 1234 from JOHN DOE",
+			wantType:   TxnBankReceived,
+			wantAmount: 3000.00,
+			wantParty:  "JOHN DOE",
+		},
+		{
+			name:       "KCB debited",
+			log:        "KCB: Ksh2,000.00 has been debited from your account ending 1234 to JANE DOE",
+			wantType:   TxnBankSent,
+			wantAmount: 2000.00,
+			wantParty:  "JANE DOE",
+		},
+		{
+			name:       "Co-op Kwa Jirani received",
+			log:        "Co-op Bank: Kwa Jirani. You have received Ksh1,500.00 from JOHN DOE",
+			wantType:   TxnBankReceived,
+			wantAmount: 1500.00,
+			wantParty:  "JOHN DOE",
+		},
+		{
+			name:       "Co-op Kwa Jirani sent",
+			log:        "Co-op Bank: Kwa Jirani. Ksh1,000.00 sent to JANE DOE",
+			wantType:   TxnBankSent,
+			wantAmount: 1000.00,
+			wantParty:  "JANE DOE",
+		},
+		{
+			name:       "received from the bank itself stays a withdrawal",
+			log:        "You have received Ksh10,000.00 from Equity Bank account 0123456789",
+			wantType:   TxnBankWithdraw,
+			wantAmount: 10000.00,
+			wantParty:  "Equity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType {
+				t.Errorf("Type = %v, want %v", txn.Type, tt.wantType)
+			}
+			if txn.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
+			}
+			party := txn.Sender
+			if tt.wantType == TxnBankSent {
+				party = txn.Recipient
+			}
+			if party != tt.wantParty || txn.Phone != tt.wantPhone {
+				t.Errorf("party, Phone = %q, %q, want %q, %q", party, txn.Phone, tt.wantParty, tt.wantPhone)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_Remittance(t *testing.T) {
+	tests := []struct {
+		name         string
+		log          string
+		wantAmount   float64
+		wantProvider string
+		wantRefCode  string
+		wantBalance  float64
+	}{
+		{
+			name:         "WorldRemit",
+			log:          "UA34ABCDEF Confirmed. You have received Ksh50,000.00 from WorldRemit on 5/2/26 at 10:15 AM. New M-PESA balance is Ksh52,300.00.",
+			wantAmount:   50000.00,
+			wantProvider: "WorldRemit",
+			wantRefCode:  "UA34ABCDEF",
+			wantBalance:  52300.00,
+		},
+		{
+			name:         "Western Union without a receipt code",
+			log:          "You have received KES 20,000.00 from WESTERN UNION via M-PESA Global",
+			wantAmount:   20000.00,
+			wantProvider: "WESTERN UNION",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != TxnRemittanceReceived {
+				t.Errorf("Type = %v, want %v", txn.Type, TxnRemittanceReceived)
+			}
+			if txn.Amount != tt.wantAmount || txn.Sender != tt.wantProvider {
+				t.Errorf("Amount, Sender = %v, %q, want %v, %q", txn.Amount, txn.Sender, tt.wantAmount, tt.wantProvider)
+			}
+			if txn.RefCode != tt.wantRefCode || txn.Balance != tt.wantBalance {
+				t.Errorf("RefCode, Balance = %q, %v, want %q, %v", txn.RefCode, txn.Balance, tt.wantRefCode, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_BankLoanRepay(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantAmount float64
+		wantLender string
+	}{
+		{
+			name:       "KCB instalment deducted",
+			log:        "KCB loan instalment of Ksh5,000 deducted from your account",
+			wantAmount: 5000.00,
+			wantLender: "KCB",
+		},
+		{
+			name:       "Equity loan repayment",
+			log:        "Equity Bank: Loan repayment of Ksh2,500.00 received. Thank you",
+			wantAmount: 2500.00,
+			wantLender: "Equity",
+		},
+		{
+			name:       "slash-equals amount",
+			log:        "Equity Bank: Your loan instalment of 5,000/= has been recovered",
+			wantAmount: 5000.00,
+			wantLender: "Equity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != TxnBankLoanRepay {
+				t.Errorf("Type = %v, want %v", txn.Type, TxnBankLoanRepay)
+			}
+			if txn.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
+			}
+			if txn.Lender != tt.wantLender {
+				t.Errorf("Lender = %q, want %q", txn.Lender, tt.wantLender)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_LenderAmountNotations(t *testing.T) {
+	tests := []struct {
+		log        string
+		wantAmount float64
+	}{
+		{"Tala: KES. 5,000 has been credited to your M-PESA", 5000.00},
+		{"Tala: Ksh1,000/- has been credited to your M-PESA", 1000.00},
+		{"Tala: 5,000/= has been credited to your M-PESA", 5000.00},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.log, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != TxnDigitalLoan || txn.Amount != tt.wantAmount {
+				t.Errorf("Type, Amount = %v, %v, want %v, %v", txn.Type, txn.Amount, TxnDigitalLoan, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_FulizaLimitReached(t *testing.T) {
+	logs := []string{
+		"Transaction failed. Fuliza limit reached. Please repay your outstanding Fuliza M-PESA",
+		"Failed. You do not have enough money in your M-PESA account to pay Ksh1,200.00 to JANE DOE. Your Fuliza M-PESA limit is Ksh500.00",
+	}
+
+	for _, log := range logs {
+		txn, err := parseSingleLog(log)
+		if err != nil {
+			t.Fatalf("parseSingleLog(%q) error = %v", log, err)
+		}
+		if txn.Type != TxnFulizaLimitReached {
+			t.Errorf("Type = %v, want %v", txn.Type, TxnFulizaLimitReached)
+		}
+		if !txn.Type.IsInformational() {
+			t.Errorf("%v should be informational", txn.Type)
+		}
+		if txn.Amount != 0 {
+			t.Errorf("Amount = %v, want 0 for a failed payment", txn.Amount)
+		}
+	}
+}
+
+func TestParseSingleLog_FulizaNotLimitReached(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantType   TransactionType
+		wantAmount float64
+	}{
+		{
+			name:       "loan covering insufficient funds",
+			log:        "Fuliza M-PESA. You have borrowed Ksh300.00 to complete your payment as you had insufficient funds in your M-PESA account",
+			wantType:   TxnFulizaLoan,
+			wantAmount: 300.00,
+		},
+		{
+			name:       "repayment after a failed transaction",
+			log:        "Fuliza M-PESA. You have repaid Ksh2,000.00. Your earlier transaction failed as you do not have enough money",
+			wantType:   TxnFulizaRepay,
+			wantAmount: 2000.00,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType || txn.Amount != tt.wantAmount {
+				t.Errorf("got %v %v, want %v %v", txn.Type, txn.Amount, tt.wantType, tt.wantAmount)
+			}
+		})
+	}
+
+	// A generic failure notice is not a limit notice just for naming Fuliza
+	if txn, err := parseSingleLog("Failed. Insufficient funds in your M-PESA account. Opt in to Fuliza M-PESA to complete payments"); err == nil && txn.Type == TxnFulizaLimitReached {
+		t.Errorf("generic failure parsed as %v", txn.Type)
+	}
+}
+
+func TestParseSingleLog_AirtimeGift(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantAmount float64
+		wantSender string
+	}{
+		{
+			name:       "Bought for you",
+			log:        "JOHN DOE has bought you Ksh100 airtime",
+			wantAmount: 100,
+			wantSender: "JOHN DOE",
+		},
+		{
+			name:       "Received airtime",
+			log:        "You have received Ksh50.00 airtime from MARY WANJIKU 0712345678",
+			wantAmount: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != TxnAirtimeGift {
+				t.Errorf("Type = %v, want %v", txn.Type, TxnAirtimeGift)
+			}
+			if txn.Amount != tt.wantAmount {
+				t.Errorf("Amount = %v, want %v", txn.Amount, tt.wantAmount)
+			}
+			if txn.Sender != tt.wantSender {
+				t.Errorf("Sender = %q, want %q", txn.Sender, tt.wantSender)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_AirtimePurchase(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantAmount float64
+		wantPhone  string
+	}{
+		{
+			name:       "Bought for self",
+			log:        "Bought Ksh100.00 of airtime",
+			wantAmount: 100,
+		},
+		{
+			name:       "Bought for another number",
+			log:        "You have bought Ksh50.00 airtime for 0712345678",
+			wantAmount: 50,
+			wantPhone:  "0712345678",
+		},
+		{
+			name:       "M-Pesa confirmation",
+			log:        "UA34ABCDEF Confirmed. You bought Ksh20.00 of airtime on 20/1/26 at 3:45 PM. New M-PESA balance is Ksh480.00.",
+			wantAmount: 20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != TxnAirtime || txn.Amount != tt.wantAmount {
+				t.Errorf("got %v %v, want %v %v", txn.Type, txn.Amount, TxnAirtime, tt.wantAmount)
+			}
+			if txn.Phone != tt.wantPhone {
+				t.Errorf("Phone = %q, want %q", txn.Phone, tt.wantPhone)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_KCBLoan(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        string
+		wantType   TransactionType
+		wantAmount float64
+		wantLender string
+	}{
+		{
+			name:       "Savings deposit",
+			log:        "KCB M-PESA. You have deposited Ksh2,000.00",
+			wantType:   TxnMMFDeposit,
+			wantAmount: 2000,
+		},
+		{
+			name:       "Loan disbursed",
+			log:        "KCB M-PESA loan of Ksh3,000 disbursed to your M-PESA account. Amount to be paid Ksh3,258 by 20/2/26.",
+			wantType:   TxnKCBLoan,
+			wantAmount: 3000,
+			wantLender: "KCB M-Pesa",
+		},
+		{
+			name:       "Loan repaid",
+			log:        "You have repaid Ksh1,000.00 of your KCB M-PESA loan. Outstanding loan balance is Ksh2,258.00.",
+			wantType:   TxnKCBRepay,
+			wantAmount: 1000,
+			wantLender: "KCB M-Pesa",
+		},
+		{
+			name:       "Loan repaid with the balance first",
+			log:        "KCB M-PESA loan balance is Ksh2,000.00. You have repaid Ksh1,000.00 of your loan.",
+			wantType:   TxnKCBRepay,
+			wantAmount: 1000,
+			wantLender: "KCB M-Pesa",
+		},
+		{
+			name:       "Loan disbursed with the amount due first",
+			log:        "KCB M-PESA: Amount due Ksh3,258.00 by 20/2/26. Your loan of Ksh3,000.00 has been credited to your M-PESA account",
+			wantType:   TxnKCBLoan,
+			wantAmount: 3000,
+			wantLender: "KCB M-Pesa",
+		},
+		{
+			name:       "Loan pending",
+			log:        "Your KCB M-PESA loan request is being processed",
+			wantType:   TxnLoanPending,
+			wantLender: "KCB M-Pesa",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType || txn.Amount != tt.wantAmount {
+				t.Errorf("got %v %v, want %v %v", txn.Type, txn.Amount, tt.wantType, tt.wantAmount)
+			}
+			if txn.Lender != tt.wantLender {
+				t.Errorf("Lender = %q, want %q", txn.Lender, tt.wantLender)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_Agent(t *testing.T) {
+	tests := []struct {
+		name        string
+		log         string
+		wantType    TransactionType
+		wantAmount  float64
+		wantAgent   string
+		wantBalance float64
+	}{
+		{
+			name:       "Cash in",
+			log:        "Give Ksh2,000.00 cash to agent 123456",
+			wantType:   TxnAgentDeposit,
+			wantAmount: 2000,
+			wantAgent:  "123456",
+		},
+		{
+			name:       "Cash out",
+			log:        "Withdraw Ksh1,000.00 from agent 654321",
+			wantType:   TxnAgentWithdraw,
+			wantAmount: 1000,
+			wantAgent:  "654321",
+		},
+		{
+			name:        "Cash out confirmation",
+			log:         "UA34ABCDEF Confirmed.on 1/2/26 at 1:00 PMWithdraw Ksh1,000.00 from 654321 - MAMA MBOGA SHOP New M-PESA balance is Ksh4,500.00. Transaction cost, Ksh29.00.",
+			wantType:    TxnAgentWithdraw,
+			wantAmount:  1000,
+			wantAgent:   "654321",
+			wantBalance: 4500,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if txn.Type != tt.wantType || txn.Amount != tt.wantAmount {
+				t.Errorf("got %v %v, want %v %v", txn.Type, txn.Amount, tt.wantType, tt.wantAmount)
+			}
+			if txn.RefCode != tt.wantAgent {
+				t.Errorf("RefCode = %q, want agent number %q", txn.RefCode, tt.wantAgent)
+			}
+			if txn.Balance != tt.wantBalance {
+				t.Errorf("Balance = %v, want %v", txn.Balance, tt.wantBalance)
+			}
+		})
+	}
+}
+
+// benchmarkCorpus is a mixed inbox: mostly M-Pesa traffic, which reaches the
+// longest pattern chain, plus the other providers and unparseable noise.
+var benchmarkCorpus = []string{
+	"UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678 on 1/2/26 at 1:00 PM. New M-PESA balance is Ksh2,345.00.",
+	"UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432 on 1/2/26 at 2:00 PM. New M-PESA balance is Ksh1,845.00.",
+	"UA00KPLC01 Confirmed. Ksh1,000.00 paid to KPLC. Account Number 12345 on 2/2/26. New M-PESA balance is Ksh845.00.",
+	"UA34ABCDEF Confirmed. Ksh200.00 paid to SUPERMARKET Till Number 123456 on 1/2/26",
+	"Betika: Your bet of Ksh200.00 has been placed",
+	"You have transferred Ksh2,000.00 to Equity account 1234",
+	"Fuliza M-PESA. You have borrowed Ksh2,000.00 from your limit",
+	"Transaction ID: AM12345678. You have received Ksh1,000.00 from JOHN DOE",
+	"M-Shwari. You have deposited Ksh1,000.00 to your savings",
+	"You have received Ksh5,000.00 from Tala",
+	"Your payment to NAIROBI WATER of Ksh800 has been reversed",
+	"Safaricom: Get 1GB for Ksh99 today only. Dial *544#",
+	"Your OTP is 123456. Do not share it with anyone.",
+}
+
+func BenchmarkParseLogs(b *testing.B) {
+	logs := make([]string, 0, 100*len(benchmarkCorpus))
+	for i := 0; i < 100; i++ {
+		logs = append(logs, benchmarkCorpus...)
+	}
+	p := NewParser()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.ParseLogs(ctx, logs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseSingleLog(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, log := range benchmarkCorpus {
+			parseSingleLog(log)
+		}
+	}
+}
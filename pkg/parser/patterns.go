@@ -6,29 +6,120 @@ import "regexp"
 // These are global but immutable, safe for concurrent use.
 // Named capture groups are used for readable extraction.
 
+// amountGroup captures an amount as the named group "amt". It is bounded to 12
+// integer digits and two decimals, either comma-grouped ("1,500.00") or plain
+// ("1500"), so a balance printed right after the amount ("Ksh2,000,1,620.00")
+// cannot be swallowed into it.
+const amountGroup = `(?P<amt>` + amountDigits + `)`
+
+// amountDigits is the unnamed body of amountGroup, for patterns that capture a
+// second amount under another name.
+const amountDigits = `\d{1,3}(?:,\d{3}){1,3}(?:\.\d{1,2})?|\d{1,12}(?:\.\d{1,2})?`
+
+// refCode matches an M-Pesa transaction code: a series prefix of two or three
+// letters, which Safaricom rotates (QK, SL, TD, TE, UA, ...), then letters and
+// digits, 10 characters in all. The leading \b stops it matching the tail of a
+// longer token.
+const refCode = `\b(?:[A-Z]{2}[A-Z0-9]{8}|[A-Z]{3}[A-Z0-9]{7})`
+
 // =============================================================================
 // M-Pesa 2026 UA series patterns
 // =============================================================================
 var (
 	// mpesaReceivedPattern matches: "UA1234ABCD Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678..."
-	// Broadened to any 8-12 char alphanumeric refcode (to support test codes)
 	mpesaReceivedPattern = regexp.MustCompile(
-		`(?i)(?P<refcode>[A-Z0-9]{8,12})\s+[Cc]onfirmed\.?\s+[Yy]ou\s+have\s+received\s+Ksh\s*(?P<amt>[\d,]+\.?\d*)\s+from\s+(?P<sender>[A-Z\s]+\d*)`,
+		`(?i)(?P<refcode>` + refCode + `)\s+[Cc]onfirmed\.?\s+[Yy]ou\s+have\s+received\s+Ksh\s*` + amountGroup + `\s+from\s+(?P<sender>[A-Z\s]+\d*)`,
+	)
+
+	// mpesaReceivedAfterPattern matches the counterparty-first order:
+	// "UA1234ABCD Confirmed. You have received from JOHN DOE 0712345678 Ksh1,500.00..."
+	mpesaReceivedAfterPattern = regexp.MustCompile(
+		`(?i)(?:(?P<refcode>` + refCode + `)\s+[Cc]onfirmed\.?\s+)?(?:[Yy]ou\s+have\s+)?received\s+from\s+(?P<sender>[A-Z][A-Z ]*[A-Z](?:\s+\d+)?)\s+(?:Ksh|KES)\s*` + amountGroup,
+	)
+
+	// c2bReceivedPattern matches business payouts routed through a biller channel:
+	// "UA1234ABCD Confirmed. Ksh5,000.00 received from PAYBILL 400200 SALARY..."
+	c2bReceivedPattern = regexp.MustCompile(
+		`(?i)(?:(?P<refcode>` + refCode + `)\s+[Cc]onfirmed\.?\s+)?(?:Ksh|KES)\s*` + amountGroup + `\s+received\s+from\s+(?P<sender>[A-Z][A-Z0-9 ]*[A-Z0-9])`,
+	)
+
+	// b2cMarkerPattern matches wording Safaricom uses for business-to-customer payouts
+	b2cMarkerPattern = regexp.MustCompile(
+		`(?i)(?:business\s+payment|via\s+API|\bB2C\b)`,
+	)
+
+	// businessSenderPattern matches sender names that belong to organisations, e.g. "SAFARICOM LIMITED" or "PAYBILL 400200"
+	businessSenderPattern = regexp.MustCompile(
+		`(?i)\b(?:LIMITED|LTD|PLC|INC|CORP(?:ORATION)?|COMPANY|SACCO|BANK|UNIVERSITY|COUNTY|PAYBILL|TILL)\b`,
 	)
 
 	// mpesaSentPattern matches: "UA1234ABCD Confirmed. Ksh500.00 sent to JANE DOE 0798765432..."
 	mpesaSentPattern = regexp.MustCompile(
-		`(?i)(?P<refcode>[A-Z0-9]{10,12})\s+[Cc]onfirmed\.?\s+Ksh\s*(?P<amt>[\d,]+\.?\d*)\s+sent\s+to\s+(?P<recipient>[A-Z\s]+\d*)`,
+		`(?i)(?P<refcode>` + refCode + `)\s+[Cc]onfirmed\.?\s+Ksh\s*` + amountGroup + `\s+sent\s+to\s+(?P<recipient>[A-Z\s]+\d*)`,
+	)
+
+	// mpesaSentAfterPattern matches the counterparty-first order:
+	// "UA1234ABCD Confirmed. Sent to JANE DOE 0798765432 Ksh500.00..."
+	mpesaSentAfterPattern = regexp.MustCompile(
+		`(?i)(?:(?P<refcode>` + refCode + `)\s+[Cc]onfirmed\.?\s+)?sent\s+to\s+(?P<recipient>[A-Z][A-Z ]*[A-Z](?:\s+\d+)?)\s+(?:Ksh|KES)\s*` + amountGroup,
 	)
 
 	// mpesaPaybillPattern matches: "UA1234ABCD Confirmed. Ksh1,000.00 paid to KPLC. Account Number 12345..."
 	mpesaPaybillPattern = regexp.MustCompile(
-		`(?i)(?P<refcode>[A-Z0-9]{10,12})\s+[Cc]onfirmed\.?\s+Ksh\s*(?P<amt>[\d,]+\.?\d*)\s+paid\s+to\s+(?P<account>[A-Z0-9\s]+)`,
+		`(?i)(?P<refcode>` + refCode + `)\s+[Cc]onfirmed\.?\s+Ksh\s*` + amountGroup + `\s+paid\s+to\s+(?P<account>[A-Z0-9\s]+)`,
+	)
+
+	// walletBalancePattern matches the trailer: "...New M-PESA balance is Ksh2,345.00..."
+	// or in Swahili "...Salio lako jipya la M-PESA ni Ksh2,345.00..."
+	walletBalancePattern = regexp.MustCompile(
+		`(?i)(?:balance\s+(?:is|was)|\bsalio\b[^.]*?\bni)\s+(?:Ksh|KES)\s*` + amountGroup,
+	)
+
+	// txnCostPattern matches the fee trailer: "...Transaction cost, Ksh23.00." or "Transaction cost Ksh 0.00",
+	// or in Swahili "...Gharama ya kutuma ni Ksh7.00."
+	txnCostPattern = regexp.MustCompile(
+		`(?i)(?:transaction\s+cost,?|gharama\s+ya\s+\w+\s+ni)\s*(?:Ksh|KES)\s*` + amountGroup,
 	)
 
 	// mpesaBuyGoodsPattern matches: "UA1234ABCD Confirmed. Ksh200.00 paid to SUPERMARKET Till Number 123456..."
 	mpesaBuyGoodsPattern = regexp.MustCompile(
-		`(?i)(?P<refcode>[A-Z0-9]{10,12})\s+[Cc]onfirmed\.?\s+Ksh\s*(?P<amt>[\d,]+\.?\d*)\s+paid\s+to\s+(?P<merchant>[A-Z\s]+)\s*[Tt]ill`,
+		`(?i)(?P<refcode>` + refCode + `)\s+[Cc]onfirmed\.?\s+Ksh\s*` + amountGroup + `\s+paid\s+to\s+(?P<merchant>[A-Z\s]+)\s*[Tt]ill`,
+	)
+)
+
+// =============================================================================
+// Counterparty patterns
+// =============================================================================
+var (
+	// phoneSuffixPattern splits a captured party into name and Kenyan phone number:
+	// "JOHN DOE 0712345678", "JANE DOE 254798765432", "SHOP 0112345678"
+	phoneSuffixPattern = regexp.MustCompile(
+		`^(?P<name>.*?)\s*(?P<phone>(?:\+?254|0)[17]\d{8})\s*$`,
+	)
+)
+
+// =============================================================================
+// M-Pesa Swahili patterns
+// =============================================================================
+var (
+	// mpesaSwahiliReceivedPattern matches: "UA1234ABCD Imethibitishwa. Umepokea Ksh1,500.00 kutoka JOHN DOE 0712345678..."
+	mpesaSwahiliReceivedPattern = regexp.MustCompile(
+		`(?i)(?:(?P<refcode>` + refCode + `)\s+Imethibitishwa\.?\s+)?umepokea\s+(?:Ksh|KES)\s*` + amountGroup + `\s+kutoka\s+(?P<sender>[A-Z][A-Z ]*[A-Z](?:\s+\d+)?)`,
+	)
+
+	// mpesaSwahiliSentPattern matches: "UA5678EFGH Imethibitishwa. Umetuma Ksh500.00 kwa JANE DOE 0798765432..."
+	mpesaSwahiliSentPattern = regexp.MustCompile(
+		`(?i)(?:(?P<refcode>` + refCode + `)\s+Imethibitishwa\.?\s+)?umetuma\s+(?:Ksh|KES)\s*` + amountGroup + `\s+kwa\s+(?P<recipient>[A-Z][A-Z ]*[A-Z](?:\s+\d+)?)`,
+	)
+
+	// mpesaSwahiliBuyGoodsPattern matches: "...Umelipa Ksh200.00 kwa SUPERMARKET Till Number 123456..."
+	mpesaSwahiliBuyGoodsPattern = regexp.MustCompile(
+		`(?i)(?:(?P<refcode>` + refCode + `)\s+Imethibitishwa\.?\s+)?umelipa\s+(?:Ksh|KES)\s*` + amountGroup + `\s+kwa\s+(?P<merchant>[A-Z][A-Z ]*?)\s*till`,
+	)
+
+	// mpesaSwahiliPaybillPattern matches: "UA0000KPLC Imethibitishwa. Umelipa Ksh1,000.00 kwa KPLC akaunti 12345..."
+	mpesaSwahiliPaybillPattern = regexp.MustCompile(
+		`(?i)(?:(?P<refcode>` + refCode + `)\s+Imethibitishwa\.?\s+)?umelipa\s+(?:Ksh|KES)\s*` + amountGroup + `\s+kwa\s+(?P<account>[A-Z0-9][A-Z0-9 ]*[A-Z0-9])`,
 	)
 )
 
@@ -38,12 +129,29 @@ var (
 var (
 	// fulizaLoanPattern matches: "Fuliza M-PESA. You have borrowed Ksh2,000.00..."
 	fulizaLoanPattern = regexp.MustCompile(
-		`(?i)Fuliza.*[Yy]ou\s+have\s+borrowed\s+Ksh\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)Fuliza.*[Yy]ou\s+have\s+borrowed\s+Ksh\s*` + amountGroup,
 	)
 
 	// fulizaRepayPattern matches: "Fuliza M-PESA. You have repaid Ksh500.00..."
 	fulizaRepayPattern = regexp.MustCompile(
-		`(?i)Fuliza.*[Yy]ou\s+have\s+repaid\s+Ksh\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)Fuliza.*[Yy]ou\s+have\s+repaid\s+Ksh\s*` + amountGroup,
+	)
+
+	// fulizaFundedPattern matches payments topped up by Fuliza:
+	// "...Ksh1,000.00 paid to KPLC. Fuliza M-PESA amount is Ksh300.00", "...sent using Fuliza"
+	fulizaFundedPattern = regexp.MustCompile(
+		`(?i)(?:using|via)\s+Fuliza|Fuliza\s+M-?PESA\s+amount\s+is`,
+	)
+
+	// fulizaLimitPattern matches a payment refused because the Fuliza limit is
+	// used up: "Transaction failed. Fuliza limit reached", or a shortfall
+	// quoting the limit ("You do not have enough money ... Your Fuliza M-PESA
+	// limit is Ksh500.00"). Failure or insufficient-funds wording alone does
+	// not count.
+	fulizaLimitPattern = regexp.MustCompile(
+		`(?i)Fuliza(?:\s+M-?PESA)?\s+limit\s+(?:reached|exceeded|exhausted)` +
+			`|(?:reached|exceeded|exhausted)\s+your\s+Fuliza(?:\s+M-?PESA)?\s+limit` +
+			`|(?:do\s+not\s+have\s+enough|insufficient\s+funds)\b.*\bFuliza(?:\s+M-?PESA)?\s+limit\s+is\b`,
 	)
 )
 
@@ -53,12 +161,12 @@ var (
 var (
 	// tkashReceivedPattern matches: "T-Kash: You have received Ksh1,000.00 from JOHN DOE..."
 	tkashReceivedPattern = regexp.MustCompile(
-		`(?i)T-Kash.*[Yy]ou\s+have\s+received\s+Ksh\s*(?P<amt>[\d,]+\.?\d*)\s+from\s+(?P<sender>[A-Z\s]+)`,
+		`(?i)T-Kash.*[Yy]ou\s+have\s+received\s+Ksh\s*` + amountGroup + `\s+from\s+(?P<sender>[A-Z\s]+)`,
 	)
 
 	// tkashSentPattern matches: "T-Kash: Ksh500.00 sent to JANE DOE..."
 	tkashSentPattern = regexp.MustCompile(
-		`(?i)T-Kash.*Ksh\s*(?P<amt>[\d,]+\.?\d*)\s+sent\s+to\s+(?P<recipient>[A-Z\s]+)`,
+		`(?i)T-Kash.*Ksh\s*` + amountGroup + `\s+sent\s+to\s+(?P<recipient>[A-Z\s]+)`,
 	)
 )
 
@@ -68,12 +176,12 @@ var (
 var (
 	// airtelReceivedPattern matches: "Transaction ID: AM12345678. You have received Ksh1,000.00 from..."
 	airtelReceivedPattern = regexp.MustCompile(
-		`(?i)Transaction\s+ID[:\s]*(?P<refcode>AM[A-Z0-9]+).*[Yy]ou\s+have\s+received\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)\s+from\s+(?P<sender>[A-Z\s]+)`,
+		`(?i)Transaction\s+ID[:\s]*(?P<refcode>AM[A-Z0-9]+).*[Yy]ou\s+have\s+received\s+(?:Ksh|KES)\s*` + amountGroup + `\s+from\s+(?P<sender>[A-Z\s]+)`,
 	)
 
 	// airtelSentPattern matches: "Transaction ID: AM12345678. Ksh500.00 sent to..."
 	airtelSentPattern = regexp.MustCompile(
-		`(?i)Transaction\s+ID[:\s]*(?P<refcode>AM[A-Z0-9]+).*(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)\s+sent\s+to\s+(?P<recipient>[A-Z\s]+)`,
+		`(?i)Transaction\s+ID[:\s]*(?P<refcode>AM[A-Z0-9]+).*(?:Ksh|KES)\s*` + amountGroup + `\s+sent\s+to\s+(?P<recipient>[A-Z\s]+)`,
 	)
 
 	// airtelGenericPattern matches generic Airtel Money keyword
@@ -86,17 +194,23 @@ var (
 var (
 	// hustlerLoanPattern matches: "Hustler Fund. You have been disbursed Ksh500.00..."
 	hustlerLoanPattern = regexp.MustCompile(
-		`(?i)Hustler\s+Fund.*(?:disbursed|received)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)Hustler\s+Fund.*(?:disbursed|received)\s+(?:Ksh|KES)\s*` + amountGroup,
+	)
+
+	// hustlerSplitPattern matches disbursements split between wallet and locked savings:
+	// "You have received Ksh950.00 as Hustler Fund loan disbursed to your M-PESA and Ksh50.00 to your savings"
+	hustlerSplitPattern = regexp.MustCompile(
+		`(?i)(?:received|disbursed)\s+(?:Ksh|KES)\s*` + amountGroup + `.*?(?:Ksh|KES)\s*(?P<saved>` + amountDigits + `)\s+(?:(?:has\s+been\s+)?saved\s+)?(?:to|into|in)\s+(?:your\s+)?(?:Hustler\s+)?savings`,
 	)
 
 	// hustlerRepayPattern matches: "Hustler Fund. You have repaid Ksh200.00..." or "sent Ksh2,00.00 to Hustler Fund"
 	hustlerRepayPattern = regexp.MustCompile(
-		`(?i)(?:Hustler\s+Fund.*(?:repaid|sent)|(?:repaid|sent)).*(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*).*(?:Hustler\s+Fund)?`,
+		`(?i)(?:Hustler\s+Fund.*(?:repaid|sent)|(?:repaid|sent)).*(?:Ksh|KES)\s*` + amountGroup + `.*(?:Hustler\s+Fund)?`,
 	)
 
 	// hustlerBalancePattern matches: "Hustler Fund. Your loan balance is Ksh300.00..."
 	hustlerBalancePattern = regexp.MustCompile(
-		`(?i)Hustler\s+Fund.*(?:balance|limit)\s+(?:is\s+)?(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)Hustler\s+Fund.*(?:balance|limit)\s+(?:is\s+)?(?:Ksh|KES)\s*` + amountGroup,
 	)
 )
 
@@ -106,37 +220,67 @@ var (
 var (
 	// okoaReceivedPattern matches: "You have received Ksh50 Okoa Jahazi..."
 	okoaReceivedPattern = regexp.MustCompile(
-		`(?i)(?:received|got)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)\s+Okoa\s+Jahazi`,
+		`(?i)(?:received|got)\s+(?:Ksh|KES)\s*` + amountGroup + `\s+Okoa\s+Jahazi`,
 	)
 
 	// okoaDebtPattern matches: "Your Okoa debt is Ksh50..."
 	okoaDebtPattern = regexp.MustCompile(
-		`(?i)Okoa\s+(?:Jahazi\s+)?debt\s+(?:is\s+)?(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)Okoa\s+(?:Jahazi\s+)?debt\s+(?:is\s+)?(?:Ksh|KES)\s*` + amountGroup,
 	)
 
 	// okoaRepayPattern matches: "Okoa Jahazi. You have repaid Ksh50..."
 	okoaRepayPattern = regexp.MustCompile(
-		`(?i)Okoa\s+(?:Jahazi)?.*(?:repaid|fulfilled|debt\s+of)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)Okoa\s+(?:Jahazi)?.*(?:repaid|fulfilled|debt\s+of)\s+(?:Ksh|KES)\s*` + amountGroup,
 	)
 )
 
 // =============================================================================
 // Digital Lenders patterns (Tala, Branch, Zenka, etc.)
 // =============================================================================
+
+// lenderNames alternates the lenders whose disbursement and repayment wording
+// is recognised; lenderMentionNames adds those only detected by name.
+const (
+	lenderNames        = `Tala|Branch|Zenka|Zash|Okolea`
+	lenderMentionNames = lenderNames + `|KCB-MPESA|Fuliza|Timiza|Berry|Kashway`
+)
+
+// newLoanDisbursementPattern builds loanDisbursementPattern over a lender alternation.
+func newLoanDisbursementPattern(names string) *regexp.Regexp {
+	return regexp.MustCompile(
+		`(?i)(?:received|disbursed)\s+(?:Ksh|KES)\s*` + amountGroup + `\s+(?:from\s+)?(?P<lender>` + names + `)`,
+	)
+}
+
+// newLoanRepaymentPattern builds loanRepaymentPattern over a lender alternation.
+func newLoanRepaymentPattern(names string) *regexp.Regexp {
+	return regexp.MustCompile(
+		`(?i)(?:Ksh|KES)\s*` + amountGroup + `\s+(?:paid|received\s+by)\s+(?P<lender>` + names + `)`,
+	)
+}
+
 var (
 	// digitalLenderPattern matches SMS from major Kenyan digital lenders
-	digitalLenderPattern = regexp.MustCompile(
-		`(?i)(Tala|Branch|Zenka|Zash|Okolea|KCB-MPESA|Fuliza|Timiza|Berry|Kashway)`,
-	)
+	digitalLenderPattern = newMentionPattern(lenderMentionNames)
 
 	// loanDisbursementPattern matches: "You have received Ksh5,000.00 from Tala..."
-	loanDisbursementPattern = regexp.MustCompile(
-		`(?i)(?:received|disbursed)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)\s+(?:from\s+)?(?P<lender>Tala|Branch|Zenka|Zash|Okolea)`,
-	)
+	loanDisbursementPattern = newLoanDisbursementPattern(lenderNames)
 
 	// loanRepaymentPattern matches: "Ksh1,000.00 received by Tala..."
-	loanRepaymentPattern = regexp.MustCompile(
-		`(?i)(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)\s+(?:paid|received\s+by)\s+(?P<lender>Tala|Branch|Zenka|Zash|Okolea)`,
+	loanRepaymentPattern = newLoanRepaymentPattern(lenderNames)
+
+	// loanPendingPattern matches a loan application still being decided:
+	// "Your loan application is being processed", "Your loan request of
+	// Ksh3,000.00 is pending approval". A bare "pending" elsewhere in a
+	// disbursement or repayment message does not count.
+	loanPendingPattern = regexp.MustCompile(
+		`(?i)\bloan\s+(?:application|request)(?:\s+of\s+(?:Ksh|KES)\.?\s*` + amountGroup + `)?\s+` +
+			`(?:is\s+being\s+processed|is\s+processing|is\s+pending|is\s+under\s+review|is\s+awaiting\s+approval|has\s+been\s+received)`,
+	)
+
+	// loanCreditPattern matches wording that confirms money actually reached the borrower
+	loanCreditPattern = regexp.MustCompile(
+		`(?i)(?:disbursed|credited|received|deposited|sent\s+to\s+your)`,
 	)
 )
 
@@ -146,27 +290,27 @@ var (
 var (
 	// mshwariDepositPattern matches: "M-Shwari. You have deposited Ksh1,000.00..."
 	mshwariDepositPattern = regexp.MustCompile(
-		`(?i)M-Shwari.*(?:deposited|saved|transferred)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)M-Shwari.*(?:deposited|saved|transferred)\s+(?:Ksh|KES)\s*` + amountGroup,
 	)
 
 	// mshwariWithdrawPattern matches: "M-Shwari. You have withdrawn Ksh500.00..."
 	mshwariWithdrawPattern = regexp.MustCompile(
-		`(?i)M-Shwari.*(?:withdrawn|transferred)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)M-Shwari.*(?:withdrawn|transferred)\s+(?:Ksh|KES)\s*` + amountGroup,
 	)
 
 	// kcbMpesaPattern matches KCB M-Pesa savings
 	kcbMpesaSavePattern = regexp.MustCompile(
-		`(?i)KCB\s*M-?PESA.*(?:deposited|saved|transferred)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)KCB\s*M-?PESA.*(?:deposited|saved|transferred)\s+(?:Ksh|KES)\s*` + amountGroup,
 	)
 
 	// maliPattern matches Mali (Safaricom MMF)
 	maliSavePattern = regexp.MustCompile(
-		`(?i)Mali.*(?:deposited|invested|saved)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)Mali.*(?:deposited|invested|saved)\s+(?:Ksh|KES)\s*` + amountGroup,
 	)
 
 	// stawiPattern matches Stawi (NCBA-Safaricom)
 	stawiSavePattern = regexp.MustCompile(
-		`(?i)Stawi.*(?:deposited|saved)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?i)Stawi.*(?:deposited|saved)\s+(?:Ksh|KES)\s*` + amountGroup,
 	)
 
 	// genericMMFPattern matches any MMF-related keywords
@@ -175,38 +319,247 @@ var (
 	)
 )
 
+// =============================================================================
+// KCB M-PESA loan patterns
+// =============================================================================
+// The amount is taken from the one the verb applies to, since these messages
+// also quote balances and amounts due.
+var (
+	// kcbLoanDisbursedPatterns match disbursements: "KCB M-PESA loan of Ksh3,000
+	// disbursed", "You have been credited with Ksh3,000.00"
+	kcbLoanDisbursedPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(?:Ksh|KES)\.?\s*` + amountGroup + `\s+(?:has\s+been\s+)?(?:disbursed|credited\s+to|deposited\s+to)\b`),
+		regexp.MustCompile(`(?i)\b(?:disbursed|credited)\s+(?:with\s+)?(?:Ksh|KES)\.?\s*` + amountGroup),
+	}
+
+	// kcbLoanRepayPatterns match repayments: "You have repaid Ksh1,000.00 of
+	// your KCB M-PESA loan", "Ksh1,000.00 paid to KCB M-PESA"
+	kcbLoanRepayPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(?:repaid|repayment\s+of|paid)\s+(?:Ksh|KES)\.?\s*` + amountGroup),
+		regexp.MustCompile(`(?i)(?:Ksh|KES)\.?\s*` + amountGroup + `\s+(?:has\s+been\s+)?(?:repaid|paid)\b`),
+	}
+)
+
+// =============================================================================
+// M-Pesa agent patterns
+// =============================================================================
+var (
+	// agentDepositPattern matches cash in: "Give Ksh2,000.00 cash to agent 123456"
+	agentDepositPattern = regexp.MustCompile(
+		`(?i)give\s+(?:Ksh|KES)\s*` + amountGroup + `\s+cash\s+to\s+(?:agent\s+)?(?P<agent>\d{5,7})\b`,
+	)
+
+	// agentWithdrawPattern matches cash out: "Withdraw Ksh1,000.00 from agent 654321"
+	agentWithdrawPattern = regexp.MustCompile(
+		`(?i)withdraw\s+(?:Ksh|KES)\s*` + amountGroup + `\s+from\s+(?:agent\s+)?(?P<agent>\d{5,7})\b`,
+	)
+)
+
 // =============================================================================
 // Bank Transfer patterns
 // =============================================================================
 var (
 	// bankTransferPattern matches transfers to/from banks
-	bankTransferPattern = regexp.MustCompile(
-		`(?i)(KCB|Equity|Co-?op(?:erative)?|NCBA|Stanbic|Absa|DTB|I&M|Family\s+Bank|Bank\s+of\s+Africa)`,
-	)
+	bankTransferPattern = newMentionPattern(bankMentionNames)
 
 	// bankDepositPattern matches: "Deposited Ksh5,000.00 to Equity Bank..."
-	bankDepositPattern = regexp.MustCompile(
-		`(?i)(?:deposited|transferred|sent)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)\s+(?:to\s+)?(?P<bank>KCB|Equity|Co-?op|NCBA|Stanbic|Absa)`,
+	bankDepositPattern = newBankDepositPattern(bankNames)
+
+	// bankLoanRepayPattern matches EMI wording: "KCB loan instalment of Ksh5,000 deducted"
+	bankLoanRepayPattern = regexp.MustCompile(
+		`(?i)(?:instal(?:l)?ment|loan\s+repayment)`,
 	)
 
 	// bankWithdrawPattern matches: "Withdrawn Ksh2,000.00 from Equity Bank..."
-	bankWithdrawPattern = regexp.MustCompile(
-		`(?i)(?:withdrawn|received)\s+(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)\s+(?:from\s+)?(?P<bank>KCB|Equity|Co-?op|NCBA|Stanbic|Absa)`,
+	bankWithdrawPattern = newBankWithdrawPattern(bankNames)
+
+	// bankReceivedPattern matches a bank's own receipt notice:
+	// "Equitel: You have received Ksh5,000.00 from JOHN DOE 0712345678"
+	bankReceivedPattern = regexp.MustCompile(
+		`(?i)received\s+(?:Ksh|KES)\.?\s*` + amountGroup + `\s+from\s+(?P<sender>[A-Z][A-Z ]*[A-Z](?:\s+\d+)?)`,
+	)
+
+	// bankCreditedPattern matches: "KCB: Ksh3,000.00 has been credited to your
+	// account ending 1234 from JOHN DOE"
+	bankCreditedPattern = regexp.MustCompile(
+		`(?i)(?:Ksh|KES)\.?\s*` + amountGroup + `\s+(?:has\s+been\s+)?credited\s+to\s+your\s+account\b.*?\bfrom\s+(?P<sender>[A-Z][A-Z ]*[A-Z])`,
 	)
+
+	// bankSentPattern matches: "Co-op Bank: Kwa Jirani. Ksh1,000.00 sent to JANE DOE"
+	bankSentPattern = regexp.MustCompile(
+		`(?i)(?:Ksh|KES)\.?\s*` + amountGroup + `\s+sent\s+to\s+(?P<recipient>[A-Z][A-Z ]*[A-Z](?:\s+\d+)?)`,
+	)
+
+	// bankDebitedPattern matches: "KCB: Ksh2,000.00 has been debited from your
+	// account ending 1234 to JANE DOE"
+	bankDebitedPattern = regexp.MustCompile(
+		`(?i)(?:Ksh|KES)\.?\s*` + amountGroup + `\s+(?:has\s+been\s+)?debited\s+from\s+your\s+account\b.*?\bto\s+(?P<recipient>[A-Z][A-Z ]*[A-Z])`,
+	)
+)
+
+// bankNames alternates the banks whose deposit, withdrawal and reversal wording
+// is recognised; bankMentionNames adds those only detected by name.
+const (
+	bankNames        = `KCB|Equity|Co-?op|NCBA|Stanbic|Absa`
+	bankMentionNames = `KCB|Equity|Equitel|Co-?op(?:erative)?|NCBA|Stanbic|Absa|DTB|I&M|Family\s+Bank|Bank\s+of\s+Africa`
+)
+
+// newBankDepositPattern builds bankDepositPattern over a bank alternation.
+func newBankDepositPattern(names string) *regexp.Regexp {
+	return regexp.MustCompile(
+		`(?i)(?:deposited|transferred|sent)\s+(?:Ksh|KES)\s*` + amountGroup + `\s+(?:to\s+)?(?P<bank>` + names + `)`,
+	)
+}
+
+// newBankWithdrawPattern builds bankWithdrawPattern over a bank alternation.
+func newBankWithdrawPattern(names string) *regexp.Regexp {
+	return regexp.MustCompile(
+		`(?i)(?:withdrawn|received)\s+(?:Ksh|KES)\s*` + amountGroup + `\s+(?:from\s+)?(?P<bank>` + names + `)`,
+	)
+}
+
+// newBankReversalPattern builds bankReversalPattern over a bank alternation.
+func newBankReversalPattern(names string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)transfer.*?\b(?P<bank>` + names + `)\b`)
+}
+
+// =============================================================================
+// International remittance patterns
+// =============================================================================
+
+// remittanceNames alternates the money transfer services that pay diaspora
+// remittances into M-Pesa through M-Pesa Global.
+const remittanceNames = `World\s*Remit|Western\s+Union|Remitly|Send\s*wave`
+
+var (
+	// remittancePattern matches money sent from abroad, which names the service
+	// rather than the person who sent it:
+	// "UA1234ABCD Confirmed. You have received Ksh50,000.00 from WorldRemit..."
+	remittancePattern = regexp.MustCompile(
+		`(?i)(?:(?P<refcode>` + refCode + `)\s+[Cc]onfirmed\.?\s+)?(?:[Yy]ou\s+have\s+)?received\s+(?:Ksh|KES)\.?\s*` + amountGroup + `\s+from\s+(?P<provider>` + remittanceNames + `)\b`,
+	)
+
+	// remittanceMentionPattern matches any mention of a remittance service,
+	// as in statement descriptions
+	remittanceMentionPattern = newMentionPattern(remittanceNames)
 )
 
 // =============================================================================
 // Gambling platform patterns
 // =============================================================================
+
+// gamblingNames alternates the betting platforms the parser recognises.
+const gamblingNames = `Betika|SportPesa|Mozzart|Odibets|Betway|1xBet|Betin|Dafabet|22Bet|Helabet`
+
+// newMentionPattern matches any mention of a name in an alternation, as the
+// lender, bank and betting platform patterns do.
+func newMentionPattern(names string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(` + names + `)`)
+}
+
 var (
 	// gamblingPattern matches any mention of major Kenyan betting platforms
-	gamblingPattern = regexp.MustCompile(
-		`(?i)(Betika|SportPesa|Mozzart|Odibets|Betway|1xBet|Betin|Dafabet|22Bet|Helabet)`,
-	)
+	gamblingPattern = newMentionPattern(gamblingNames)
 
-	// amountPattern is a generic pattern to extract amounts from any SMS
+	// gamblingWinPattern marks a betting message as money paid to the user
+	// ("Win! You have received", "you have won", "withdrawal of Ksh..."). A
+	// bare "win" is not enough: bet confirmations quote a "Possible Win" for
+	// the slip. Nor is "received" or "credited", which deposit confirmations
+	// use too ("Your deposit of Ksh500.00 has been received").
+	gamblingWinPattern = regexp.MustCompile(`(?i)\bwin!|\b(?:won|winnings|jackpot|payout)\b|\bwithdrawal\s+of\s+(?:Ksh|KES)`)
+
+	// amountPattern is a generic pattern to extract amounts from any SMS,
+	// including the "KES. 5,000" some lenders and banks write
 	amountPattern = regexp.MustCompile(
-		`(?:Ksh|KES)\s*(?P<amt>[\d,]+\.?\d*)`,
+		`(?:Ksh|KES)\.?\s*` + amountGroup,
+	)
+
+	// slashAmountPattern matches the bare "5,000/=" (or "5,000/-") notation
+	// some banks use instead of a currency prefix
+	slashAmountPattern = regexp.MustCompile(
+		`\b` + amountGroup + `\s*/[=-]`,
+	)
+)
+
+// =============================================================================
+// Digest patterns
+// =============================================================================
+var (
+	// digestEntryPattern matches one clause of a daily digest:
+	// "3 deposits totaling Ksh12,000", "2 withdrawals totalling KES 4,000.00"
+	digestEntryPattern = regexp.MustCompile(
+		`(?i)\b\d+\s+(?P<kind>deposits?|credits?|receipts?|withdrawals?|debits?|payments?)\s+(?:totall?ing|worth|amounting\s+to)\s+(?:Ksh|KES)\s*` + amountGroup,
+	)
+)
+
+// =============================================================================
+// Reversal patterns
+// =============================================================================
+var (
+	// reversalKeywordPattern matches corrective wording: "...has been reversed", "Reversal of..."
+	reversalKeywordPattern = regexp.MustCompile(`(?i)\brevers(?:ed|al)\b`)
+
+	// reversalPendingPattern matches reversals not yet completed:
+	// "Reversal of Ksh500.00 is being processed", "Ksh500 will be reversed within 24 hours"
+	reversalPendingPattern = regexp.MustCompile(
+		`(?i)\b(?:will\s+be|is\s+being)\s+reversed\b|\breversal\b.*\b(?:pending|being\s+processed|in\s+progress|initiated)\b`,
+	)
+
+	// bankReversalPattern matches: "Your transfer to EQUITY of Ksh10,000 failed and was reversed"
+	bankReversalPattern = newBankReversalPattern(bankNames)
+
+	// tillReversalPattern matches: "Your payment of Ksh200.00 to till 123456 has been reversed"
+	tillReversalPattern = regexp.MustCompile(
+		`(?i)payment.*\btill\s+(?:no\.?\s+|number\s+)?(?P<till>\d{5,7})`,
+	)
+
+	// paybillReversalPattern matches: "Your payment to NAIROBI WATER of Ksh800 has been reversed"
+	paybillReversalPattern = regexp.MustCompile(
+		`(?i)payment\s+to\s+(?P<account>[A-Z0-9][A-Z0-9\s]*?)\s+(?:of|for)\s+(?:Ksh|KES)\s*` + amountGroup,
+	)
+
+	// mpesaReversalPattern matches: "UA9REV0001 Confirmed. Transaction UA34ABCDEF has been reversed."
+	mpesaReversalPattern = regexp.MustCompile(
+		`(?i)(?:(?P<refcode>` + refCode + `)\s+[Cc]onfirmed\.?\s+)?transaction\s+` + refCode + `\s+(?:has\s+been|was)\s+(?:successfully\s+)?reversed`,
+	)
+
+	// reversalDirectionPattern matches which way a reversal moved the money:
+	// "Ksh500.00 has been debited from your account" undoes a receipt,
+	// "Ksh500.00 is credited to your account" undoes a send
+	reversalDirectionPattern = regexp.MustCompile(
+		`(?i)\b(?P<dir>debited|deducted|credited|refunded|returned)\b`,
+	)
+
+	// walletReversalOriginPattern matches how a reversal describes the transfer
+	// it undoes: "Ksh500.00 sent to JANE DOE has been reversed" undoes a send,
+	// "Ksh1,000.00 received from JOHN DOE has been reversed" a receipt
+	walletReversalOriginPattern = regexp.MustCompile(
+		`(?i)\b(?P<dir>sent\s+to|received\s+from)\b`,
+	)
+
+	// failedTxnPattern matches a transaction M-Pesa declined outright:
+	// "Failed. You do not have enough money in your M-PESA account..."
+	failedTxnPattern = regexp.MustCompile(`(?i)^\s*(?:` + refCode + `\s+)?failed\b`)
+)
+
+// =============================================================================
+// Safaricom loyalty patterns
+// =============================================================================
+var (
+	// bongaRedeemPattern matches: "You have redeemed Ksh100 worth of Bonga Points..."
+	bongaRedeemPattern = regexp.MustCompile(
+		`(?i)redeemed.*Bonga\s+Points`,
+	)
+
+	// airtimePurchasePattern matches: "UA1234ABCD Confirmed. You bought Ksh100.00 of airtime..."
+	// or "You have bought Ksh50.00 airtime for 0712345678"
+	airtimePurchasePattern = regexp.MustCompile(
+		`(?i)(?:(?P<refcode>` + refCode + `)\s+[Cc]onfirmed\.?\s+)?bought\s+(?:Ksh|KES)\s*` + amountGroup + `\s+(?:of\s+)?airtime(?:\s+for\s+(?P<phone>(?:\+?254|0)[17]\d{8}))?`,
+	)
+
+	// airtimeGiftPattern matches: "JOHN DOE has bought you Ksh100 airtime" or "You have received Ksh50 airtime from..."
+	airtimeGiftPattern = regexp.MustCompile(
+		`(?i)(?:(?P<sender>[A-Z][A-Z ]*?)\s+has\s+bought\s+you|received)\s+(?:Ksh|KES)\s*` + amountGroup + `\s+(?:of\s+)?airtime`,
 	)
 )
 
@@ -219,3 +572,15 @@ var (
 		`(?i)(KPLC|Kenya\s+Power|Nairobi\s+Water|Safaricom\s+Home|Zuku|DSTV|GOtv|StarTimes)`,
 	)
 )
+
+// =============================================================================
+// Date patterns
+// =============================================================================
+var (
+	// timestampPattern matches the date a message was sent, day first, with an
+	// optional time: "on 20/1/26 at 3:45 PM", "on 20/01/2026.", "20/1/26 15:45",
+	// "mnamo 20/1/26 saa 3:45 PM"
+	timestampPattern = regexp.MustCompile(
+		`(?i)\b(?P<day>\d{1,2})/(?P<month>\d{1,2})/(?P<year>\d{4}|\d{2})\b(?:\s+(?:(?:at|saa)\s+)?(?P<hour>\d{1,2}):(?P<minute>\d{2})(?:\s*(?P<ampm>[AP]M)\b)?)?`,
+	)
+)
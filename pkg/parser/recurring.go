@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Recurring income tolerances. Two receipts recur when they arrive a month
+// apart give or take recurringSlack, and neither amount differs from the
+// other by more than recurringAmountTolerance of the larger one.
+const (
+	recurringPeriod          = 30 * 24 * time.Hour
+	recurringSlack           = 5 * 24 * time.Hour
+	recurringAmountTolerance = 0.10
+)
+
+// RecurringWindow is the widest gap at which two receipts can still recur, so
+// a receipt further than this from every other one is never flagged.
+const RecurringWindow = recurringPeriod + recurringSlack
+
+// DetectRecurring returns a copy of txns with Recurring set on receipts that
+// repeat roughly monthly for a similar amount, such as a salary: one received
+// 25 to 35 days before or after another within 10% of its amount. Only dated
+// receipts into the wallet or a bank account are considered; digest totals
+// and reversals never recur.
+func DetectRecurring(txns []Transaction) []Transaction {
+	out := make([]Transaction, len(txns))
+	copy(out, txns)
+
+	var receipts []int
+	for i, txn := range out {
+		if IsRecurringCandidate(txn) {
+			receipts = append(receipts, i)
+		}
+	}
+	sort.SliceStable(receipts, func(a, b int) bool {
+		return out[receipts[a]].Timestamp.Before(out[receipts[b]].Timestamp)
+	})
+
+	for a, i := range receipts {
+		for _, j := range receipts[a+1:] {
+			if out[j].Timestamp.Sub(out[i].Timestamp) > RecurringWindow {
+				break
+			}
+			if Recurs(out[i], out[j]) {
+				out[i].Recurring = true
+				out[j].Recurring = true
+			}
+		}
+	}
+	return out
+}
+
+// Recurs reports whether receipts a and b, in either order, recur with each
+// other by the rules of DetectRecurring. It lets callers that see receipts one
+// at a time flag them without rescanning the whole history.
+func Recurs(a, b Transaction) bool {
+	if !IsRecurringCandidate(a) || !IsRecurringCandidate(b) {
+		return false
+	}
+	gap := b.Timestamp.Sub(a.Timestamp)
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap >= recurringPeriod-recurringSlack && gap <= RecurringWindow && similarAmounts(a.Amount, b.Amount)
+}
+
+// IsRecurringCandidate reports whether txn is money received that could be
+// part of a regular income stream.
+func IsRecurringCandidate(txn Transaction) bool {
+	if txn.Timestamp.IsZero() || txn.Amount <= 0 || txn.Derived || txn.Reversal {
+		return false
+	}
+	switch txn.Type {
+	case TxnMPesaReceived, TxnTKashReceived, TxnAirtelReceived, TxnBankWithdraw, TxnBankReceived,
+		TxnRemittanceReceived:
+		return true
+	default:
+		return false
+	}
+}
+
+// similarAmounts reports whether a and b are within recurringAmountTolerance
+// of the larger of the two.
+func similarAmounts(a, b float64) bool {
+	return math.Abs(a-b) <= recurringAmountTolerance*math.Max(a, b)
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectRecurring_MonthlySalary(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 9, 0, 0, 0, eastAfricaTime)
+	}
+	txns := []Transaction{
+		{Type: TxnMPesaReceived, Amount: 30000, Timestamp: day(time.January, 28)},
+		{Type: TxnMPesaSent, Amount: 30000, Timestamp: day(time.February, 1)},
+		{Type: TxnMPesaReceived, Amount: 1200, Timestamp: day(time.February, 10)},
+		{Type: TxnMPesaReceived, Amount: 30000, Timestamp: day(time.February, 27)},
+		{Type: TxnMPesaReceived, Amount: 30000, Timestamp: day(time.March, 30)},
+	}
+
+	got := DetectRecurring(txns)
+	want := []bool{true, false, false, true, true}
+	for i := range want {
+		if got[i].Recurring != want[i] {
+			t.Errorf("txns[%d] (%v %v) Recurring = %v, want %v", i, got[i].Type, got[i].Amount, got[i].Recurring, want[i])
+		}
+	}
+	if txns[0].Recurring {
+		t.Error("DetectRecurring() modified its input")
+	}
+}
+
+func TestDetectRecurring_Tolerances(t *testing.T) {
+	start := time.Date(2026, time.January, 1, 9, 0, 0, 0, eastAfricaTime)
+	tests := []struct {
+		name   string
+		days   int
+		amount float64
+		want   bool
+	}{
+		{"five days early", 25, 30000, true},
+		{"five days late", 35, 30000, true},
+		{"too soon", 24, 30000, false},
+		{"too late", 36, 30000, false},
+		{"ten percent less", 30, 27000, true},
+		{"amount differs too much", 30, 26000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectRecurring([]Transaction{
+				{Type: TxnMPesaReceived, Amount: 30000, Timestamp: start},
+				{Type: TxnBankWithdraw, Amount: tt.amount, Timestamp: start.AddDate(0, 0, tt.days)},
+			})
+			if got[0].Recurring != tt.want || got[1].Recurring != tt.want {
+				t.Errorf("Recurring = %v, %v, want %v", got[0].Recurring, got[1].Recurring, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectRecurring_Undated(t *testing.T) {
+	got := DetectRecurring([]Transaction{
+		{Type: TxnMPesaReceived, Amount: 30000},
+		{Type: TxnMPesaReceived, Amount: 30000},
+	})
+	if got[0].Recurring || got[1].Recurring {
+		t.Error("undated receipts flagged recurring")
+	}
+}
+
+func TestRecurs_EitherOrder(t *testing.T) {
+	start := time.Date(2026, time.January, 28, 9, 0, 0, 0, eastAfricaTime)
+	salary := Transaction{Type: TxnMPesaReceived, Amount: 30000, Timestamp: start}
+	next := Transaction{Type: TxnMPesaReceived, Amount: 29000, Timestamp: start.AddDate(0, 1, 0)}
+
+	if !Recurs(salary, next) || !Recurs(next, salary) {
+		t.Error("Recurs() = false for monthly salaries, want true in either order")
+	}
+	sent := next
+	sent.Type = TxnMPesaSent
+	if Recurs(salary, sent) {
+		t.Error("Recurs() = true for a payment, want false")
+	}
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eastAfricaTime is the zone Kenyan operators stamp their messages in.
+var eastAfricaTime = time.FixedZone("EAT", 3*60*60)
+
+// parseTimestamp extracts the date and time a message was sent, as written by
+// M-Pesa ("on 20/1/26 at 3:45 PM"), Fuliza and Hustler Fund ("on 20/1/26.").
+// Dates are day first with a two- or four-digit year; without a time of day
+// the result is midnight. It returns false when the message carries no valid
+// date, e.g. "31/2/26" or "at 13:00 PM".
+func parseTimestamp(log string) (time.Time, bool) {
+	match := timestampPattern.FindStringSubmatch(log)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	day, _ := strconv.Atoi(getNamedGroup(timestampPattern, match, "day"))
+	month, _ := strconv.Atoi(getNamedGroup(timestampPattern, match, "month"))
+	year, _ := strconv.Atoi(getNamedGroup(timestampPattern, match, "year"))
+	if year < 100 {
+		year += 2000
+	}
+
+	hour, minute := 0, 0
+	if h := getNamedGroup(timestampPattern, match, "hour"); h != "" {
+		hour, _ = strconv.Atoi(h)
+		minute, _ = strconv.Atoi(getNamedGroup(timestampPattern, match, "minute"))
+		if ampm := strings.ToUpper(getNamedGroup(timestampPattern, match, "ampm")); ampm != "" {
+			if hour < 1 || hour > 12 {
+				return time.Time{}, false
+			}
+			hour %= 12
+			if ampm == "PM" {
+				hour += 12
+			}
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, hour, minute, 0, 0, eastAfricaTime)
+	// time.Date normalises out-of-range fields, so a changed field means the
+	// message held an impossible date or time
+	if t.Day() != day || int(t.Month()) != month || t.Hour() != hour || t.Minute() != minute {
+		return time.Time{}, false
+	}
+	return t, true
+}
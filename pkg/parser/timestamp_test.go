@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		name   string
+		log    string
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "M-Pesa with afternoon time",
+			log:    "UA34ABCDEF Confirmed. You have received Ksh1,500.00 from JOHN DOE 0712345678 on 20/1/26 at 3:45 PM. New M-PESA balance is Ksh2,000.00.",
+			want:   time.Date(2026, time.January, 20, 15, 45, 0, 0, eastAfricaTime),
+			wantOK: true,
+		},
+		{
+			name:   "just after midnight",
+			log:    "Ksh500.00 sent to JANE DOE on 5/2/26 at 12:05 AM",
+			want:   time.Date(2026, time.February, 5, 0, 5, 0, 0, eastAfricaTime),
+			wantOK: true,
+		},
+		{
+			name:   "date only",
+			log:    "Fuliza M-PESA. You have borrowed Ksh2,000.00 on 20/1/26.",
+			want:   time.Date(2026, time.January, 20, 0, 0, 0, 0, eastAfricaTime),
+			wantOK: true,
+		},
+		{
+			name:   "four-digit year, two-digit month",
+			log:    "Hustler Fund: You have repaid Ksh500 on 03/11/2025",
+			want:   time.Date(2025, time.November, 3, 0, 0, 0, 0, eastAfricaTime),
+			wantOK: true,
+		},
+		{
+			name:   "24-hour time",
+			log:    "Paid Ksh100 on 1/2/26 18:30",
+			want:   time.Date(2026, time.February, 1, 18, 30, 0, 0, eastAfricaTime),
+			wantOK: true,
+		},
+		{name: "no date", log: "Fuliza M-PESA. You have borrowed Ksh2,000.00"},
+		{name: "impossible date", log: "Received Ksh100 on 31/2/26"},
+		{name: "impossible time", log: "Received Ksh100 on 1/2/26 at 13:00 PM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTimestamp(tt.log)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("parseTimestamp() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseSingleLog_Timestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want time.Time
+	}{
+		{
+			name: "M-Pesa",
+			log:  "UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432 on 20/1/26 at 3:45 PM.",
+			want: time.Date(2026, time.January, 20, 15, 45, 0, 0, eastAfricaTime),
+		},
+		{
+			name: "Fuliza",
+			log:  "Fuliza M-PESA. You have borrowed Ksh2,000.00 on 20/1/26.",
+			want: time.Date(2026, time.January, 20, 0, 0, 0, 0, eastAfricaTime),
+		},
+		{
+			name: "undated",
+			log:  "UA78EFGHIJ Confirmed. Ksh500.00 sent to JANE DOE 0798765432",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := parseSingleLog(tt.log)
+			if err != nil {
+				t.Fatalf("parseSingleLog() error = %v", err)
+			}
+			if !txn.Timestamp.Equal(tt.want) {
+				t.Errorf("Timestamp = %v, want %v", txn.Timestamp, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// vocabulary holds the patterns built from provider name lists. A parser
+// configured with extra lenders, betting platforms or banks gets its own
+// vocabulary; everything else shares defaultVocabulary.
+type vocabulary struct {
+	extraLenders     []string // Uppercased, for keyword routing
+	lender           *regexp.Regexp
+	loanDisbursement *regexp.Regexp
+	loanRepayment    *regexp.Regexp
+	gambling         *regexp.Regexp
+	bank             *regexp.Regexp
+	bankDeposit      *regexp.Regexp
+	bankWithdraw     *regexp.Regexp
+	bankReversal     *regexp.Regexp
+}
+
+// defaultVocabulary recognises the built-in provider lists.
+var defaultVocabulary = &vocabulary{
+	lender:           digitalLenderPattern,
+	loanDisbursement: loanDisbursementPattern,
+	loanRepayment:    loanRepaymentPattern,
+	gambling:         gamblingPattern,
+	bank:             bankTransferPattern,
+	bankDeposit:      bankDepositPattern,
+	bankWithdraw:     bankWithdrawPattern,
+	bankReversal:     bankReversalPattern,
+}
+
+// newVocabulary compiles the provider patterns with cfg's extra names added to
+// the built-in lists. It returns defaultVocabulary when there are none.
+func newVocabulary(cfg ParserConfig) *vocabulary {
+	lenders := alternation(cfg.ExtraLenders)
+	gamblers := alternation(cfg.ExtraGamblers)
+	banks := alternation(cfg.ExtraBanks)
+	if lenders == "" && gamblers == "" && banks == "" {
+		return defaultVocabulary
+	}
+
+	v := &vocabulary{
+		lender:           newMentionPattern(lenderMentionNames + lenders),
+		loanDisbursement: newLoanDisbursementPattern(lenderNames + lenders),
+		loanRepayment:    newLoanRepaymentPattern(lenderNames + lenders),
+		gambling:         newMentionPattern(gamblingNames + gamblers),
+		bank:             newMentionPattern(bankMentionNames + banks),
+		bankDeposit:      newBankDepositPattern(bankNames + banks),
+		bankWithdraw:     newBankWithdrawPattern(bankNames + banks),
+		bankReversal:     newBankReversalPattern(bankNames + banks),
+	}
+	for _, name := range cfg.ExtraLenders {
+		if name = strings.TrimSpace(name); name != "" {
+			v.extraLenders = append(v.extraLenders, strings.ToUpper(name))
+		}
+	}
+	return v
+}
+
+// mentionsExtraLender reports whether text names a configured lender, ignoring case.
+func (v *vocabulary) mentionsExtraLender(log string) bool {
+	for _, name := range v.extraLenders {
+		if containsFold(log, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// alternation quotes names as regex alternatives, each prefixed with "|" so
+// the result appends to a built-in list. Blank names are skipped.
+func alternation(names []string) string {
+	var b strings.Builder
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			b.WriteString("|")
+			b.WriteString(regexp.QuoteMeta(name))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseLogs_ExtraProviders(t *testing.T) {
+	ctx := context.Background()
+	cfg := ParserConfig{
+		ExtraLenders:  []string{"Zanifu", " "},
+		ExtraGamblers: []string{"Shabiki"},
+		ExtraBanks:    []string{"Sidian"},
+	}
+
+	tests := []struct {
+		name       string
+		log        string
+		wantType   TransactionType
+		wantAmount float64
+		wantParty  string
+	}{
+		{
+			name:       "lender disbursement",
+			log:        "You have received Ksh3,000.00 from Zanifu. Repay by 20/2/26.",
+			wantType:   TxnDigitalLoan,
+			wantAmount: 3000,
+			wantParty:  "Zanifu",
+		},
+		{
+			name:       "lender repayment",
+			log:        "Ksh1,000.00 paid to ZANIFU for your loan",
+			wantType:   TxnDigitalRepay,
+			wantAmount: 1000,
+			wantParty:  "ZANIFU",
+		},
+		{
+			name:       "betting platform",
+			log:        "Shabiki: Your bet of Ksh150.00 has been placed",
+			wantType:   TxnGamblingStake,
+			wantAmount: 150,
+			wantParty:  "Shabiki",
+		},
+		{
+			name:       "bank deposit",
+			log:        "You have transferred Ksh2,000.00 to Sidian account 1234",
+			wantType:   TxnBankDeposit,
+			wantAmount: 2000,
+			wantParty:  "Sidian",
+		},
+	}
+
+	custom := NewParserWithConfig(cfg)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txns, err := custom.ParseLogs(ctx, []string{tt.log})
+			if err != nil || len(txns) != 1 {
+				t.Fatalf("ParseLogs() = %d txns, error %v", len(txns), err)
+			}
+			txn := txns[0]
+			if txn.Type != tt.wantType || txn.Amount != tt.wantAmount {
+				t.Errorf("got %v %v, want %v %v", txn.Type, txn.Amount, tt.wantType, tt.wantAmount)
+			}
+			if party := txn.Lender + txn.Recipient; party != tt.wantParty {
+				t.Errorf("lender/recipient = %q, want %q", party, tt.wantParty)
+			}
+
+			// The built-in lists do not know these providers
+			txns, _ = NewParser().ParseLogs(ctx, []string{tt.log})
+			if len(txns) == 1 && txns[0].Type == tt.wantType {
+				t.Errorf("NewParser() also parsed %v; the test provider should be unknown to it", tt.wantType)
+			}
+		})
+	}
+}
+
+func TestNewVocabulary_DefaultWithoutExtras(t *testing.T) {
+	if v := newVocabulary(ParserConfig{ExtraLenders: []string{""}}); v != defaultVocabulary {
+		t.Error("newVocabulary() compiled new patterns for blank names only")
+	}
+}